@@ -14,6 +14,7 @@ var (
 	daemonMode      string
 	kubeConfig      string
 	openstackConfig string
+	metricsAddr     string
 
 	neutronNet    string
 	neutronSubnet string
@@ -28,12 +29,13 @@ func main() {
 	fs.StringVar(&openstackConfig, "openstack-config", "", "Path to openstack config file.")
 	fs.StringVar(&neutronNet, "neutron-network", "share_net", "network name or id")
 	fs.StringVar(&neutronSubnet, "neutron-subnet", "share_net__subnet", "subnet name or id")
+	fs.StringVar(&metricsAddr, "metrics-addr", ":9190", "address to serve prometheus metrics, empty to disable.")
 	err := fs.Parse(os.Args[1:])
 	if err != nil {
 		panic(err)
 	}
 
-	if err = daemon.Run(utils.DefaultSocketPath, kubeConfig, openstackConfig, neutronNet, neutronSubnet); err != nil {
+	if err = daemon.Run(utils.DefaultSocketPath, metricsAddr, kubeConfig, openstackConfig, neutronNet, neutronSubnet); err != nil {
 		log.DefaultLogger.Fatal(err)
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"net"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/rubble/pkg/log"
 
@@ -21,9 +22,10 @@ import (
 	"github.com/containernetworking/cni/pkg/version"
 )
 
+// rollbackTimeout is time to release ip of a failed ADD
+const rollbackTimeout = 5 * time.Second
+
 var cniLog = log.DefaultLogger.WithField("component:", "rubble cni plugin")
-var ipVlan = plugin.NewIPVlanDriver()
-var ptp = plugin.NewPTPDriver()
 
 func init() {
 	// this ensures that main runs only on main thread (thread group leader).
@@ -38,7 +40,7 @@ func main() {
 
 func cmdAdd(args *skel.CmdArgs) error {
 	log.SetLogOutput(utils.DefaultCNILogPath)
-	cniLog.Debugf("*********** rubble cni do Add ******")
+	cniLog.Debugf("rubble cni do add")
 
 	addArgs, err := getCmdArgs(args)
 	if err != nil {
@@ -64,21 +66,19 @@ func cmdAdd(args *skel.CmdArgs) error {
 }
 
 func cmdDel(args *skel.CmdArgs) error {
-	cniLog.Debugf("*********** rubble cni do Del ******")
+	cniLog.Debugf("rubble cni do del")
 
 	//1. call plugins to teardown all resources
 	delArgs, err := getCmdArgs(args)
 	if err != nil {
 		return err
 	}
-	err = ptp.TearDown(&delArgs)
+	driver, err := plugin.NewDriver(delArgs.NetConf)
 	if err != nil {
-		return fmt.Errorf("failed to teardown ptp veth with error: %w", err)
+		return err
 	}
-
-	err = ipVlan.TearDown(&delArgs)
-	if err != nil {
-		return fmt.Errorf("failed to teardown ipvlan device with error: %w", err)
+	if err = driver.TearDown(&delArgs); err != nil {
+		return err
 	}
 
 	//2. call rubble-daemon to release ip
@@ -94,6 +94,7 @@ func cmdDel(args *skel.CmdArgs) error {
 		K8SPodName:             delArgs.K8sPodName,
 		K8SPodNamespace:        delArgs.K8sPodNameSpace,
 		K8SPodInfraContainerId: delArgs.K8sInfraContainerID,
+		Reason:                 utils.ReleaseReasonDelete,
 	})
 	if err != nil {
 		err = fmt.Errorf("cmdDel: error release ip %w", err)
@@ -106,6 +107,42 @@ func cmdDel(args *skel.CmdArgs) error {
 }
 
 func cmdCheck(args *skel.CmdArgs) error {
+	cniLog.Debugf("rubble cni do check")
+
+	checkArgs, err := getCmdArgs(args)
+	if err != nil {
+		return err
+	}
+	driver, err := plugin.NewDriver(checkArgs.NetConf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), utils.DefaultCniTimeout)
+	defer cancel()
+	client, conn, err := getRubbleClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error create grpc client, %w", err)
+	}
+	defer conn.Close()
+
+	//1. get net config recorded for pod from rubble-daemon
+	info, err := client.GetIPInfo(ctx, &rpc.GetInfoRequest{
+		K8SPodName:             checkArgs.K8sPodName,
+		K8SPodNamespace:        checkArgs.K8sPodNameSpace,
+		K8SPodInfraContainerId: checkArgs.K8sInfraContainerID,
+	})
+	if err != nil {
+		return types.NewError(types.ErrUnknownContainer, "cmdCheck: error get ip info", err.Error())
+	}
+	if !info.Success {
+		return types.NewError(types.ErrUnknownContainer, "cmdCheck: get ip info return not success", "")
+	}
+
+	//2. verify devices and routes in container and on host
+	if err = driver.Check(cniLog, info.NetConfs, &checkArgs); err != nil {
+		return types.NewError(types.ErrInternal, "cmdCheck: device check failed", err.Error())
+	}
 	return nil
 }
 
@@ -147,11 +184,26 @@ func getRubbleClient(ctx context.Context) (rpc.RubbleBackendClient, *grpc.Client
 	return client, conn, nil
 }
 
-func doCmdAdd(ctx context.Context, client rpc.RubbleBackendClient, cmdArgs *utils.CniCmdArgs) (*current.Result, error) {
+// doCmdAdd allocate ip and set up interfaces for pod, steps applied are undone in reverse order if a later
+// step fails, so failed ADD leaves no interface in container and no port in use
+func doCmdAdd(ctx context.Context, client rpc.RubbleBackendClient, cmdArgs *utils.CniCmdArgs) (result *current.Result, err error) {
 	cniLog.Infof("Do add nic for pod: %s/%s.", cmdArgs.K8sPodNameSpace, cmdArgs.K8sPodName)
 	cniLog.Infof("netConf is: %+v", cmdArgs.NetConf)
 	cniLog.Infof("stdin from args is: %s", string(cmdArgs.RawArgs.StdinData))
 
+	driver, err := plugin.NewDriver(cmdArgs.NetConf)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := plugin.NewRollback()
+	defer func() {
+		if err != nil {
+			cniLog.Infof("roll back add of pod %s/%s: %v", cmdArgs.K8sPodNameSpace, cmdArgs.K8sPodName, err)
+			rb.Run(cniLog)
+		}
+	}()
+
 	// 1. ipam with neutron
 	allocResult, err := client.AllocateIP(ctx, &rpc.AllocateIPRequest{
 		Netns:                  cmdArgs.NetNS,
@@ -165,27 +217,47 @@ func doCmdAdd(ctx context.Context, client rpc.RubbleBackendClient, cmdArgs *util
 		return nil, err
 	}
 	if !allocResult.Success {
-		err = fmt.Errorf("cmdAdd: allocate ip return not success")
+		return nil, fmt.Errorf("cmdAdd: allocate ip return not success")
 	}
+	rb.Add("ip allocation", func() error {
+		return releaseAllocation(client, cmdArgs)
+	})
 
 	cniLog.Infof("Allocate reply is %+v", allocResult)
 
-	// 2.setup ipVlan interface eth0 in container
-	// (TODO) convert allocResult to cni result
-	tmpResult, err := ipVlan.Setup(cniLog, allocResult, cmdArgs)
-	if err != nil {
-		err = fmt.Errorf("failed to setup ipvlan device with error: %w", err)
-		return nil, err
+	// 2. remove interfaces left in container by a previous failed ADD of the sandbox, the daemon returns
+	// the same port for it
+	if err = driver.TearDown(cmdArgs); err != nil {
+		return nil, fmt.Errorf("failed to clean up interfaces of previous attempt with error: %w", err)
 	}
 
-	// 3.setup ptp veth in container because the ipVlan limitation: https://www.cni.dev/plugins/current/main/ipvlan/#notes
-	result, err := ptp.Setup(cniLog, tmpResult, cmdArgs)
-	if err != nil {
-		err = fmt.Errorf("failed to setup ptp veth pair with error: %w", err)
+	// pod interfaces use mtu of neutron network unless mtu is set in net conf
+	if cmdArgs.MTU, err = plugin.PodMTU(cmdArgs, allocResult.NetConfs); err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	// 3.setup interface eth0 of datapath in container, then ptp veth to host because neither ipvlan,
+	// macvlan nor interface of port can reach host: https://www.cni.dev/plugins/current/main/ipvlan/#notes
+	return driver.Setup(cniLog, allocResult, cmdArgs, rb)
+}
+
+// releaseAllocation give ip allocated by a failed ADD back to daemon, ctx of ADD may be done already
+func releaseAllocation(client rpc.RubbleBackendClient, cmdArgs *utils.CniCmdArgs) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	reply, err := client.ReleaseIP(ctx, &rpc.ReleaseIPRequest{
+		K8SPodName:             cmdArgs.K8sPodName,
+		K8SPodNamespace:        cmdArgs.K8sPodNameSpace,
+		K8SPodInfraContainerId: cmdArgs.K8sInfraContainerID,
+		Reason:                 utils.ReleaseReasonRollback,
+	})
+	if err != nil {
+		return fmt.Errorf("error release ip %w", err)
+	}
+	if !reply.Success {
+		return fmt.Errorf("release ip return not success")
+	}
+	return nil
 }
 
 func loadNetConf(bytes []byte) (*utils.NetConf, error) {
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rubble/pkg/controller"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/log"
+	"github.com/rubble/pkg/neutron"
+)
+
+var (
+	logLevel            string
+	kubeConfig          string
+	syncPeriod          time.Duration
+	enableNetworkPolicy bool
+	enableQuota         bool
+	quotaReserve        int
+)
+
+func main() {
+	fs := flag.NewFlagSet("rubble-controller", flag.ExitOnError)
+
+	fs.StringVar(&logLevel, "log-level", "info", "rubble log level.")
+	fs.StringVar(&kubeConfig, "kube-config", "", "Path to kube-config file.")
+	fs.DurationVar(&syncPeriod, "sync-period", controller.DefaultSyncPeriod, "period to sync ip pools and network policies.")
+	fs.BoolVar(&enableNetworkPolicy, "enable-network-policy", true, "enforce network policies by neutron security groups.")
+	fs.BoolVar(&enableQuota, "enable-quota", false, "grant capacity budgets to nodes from free addresses of subnets.")
+	fs.IntVar(&quotaReserve, "quota-reserve", 0, "addresses of subnets kept out of node budgets.")
+	err := fs.Parse(os.Args[1:])
+	if err != nil {
+		panic(err)
+	}
+
+	k8sClient, err := k8s.NewK8s(kubeConfig, "")
+	if err != nil {
+		log.DefaultLogger.Fatal(err)
+	}
+	neutronClient, err := neutron.NewClient()
+	if err != nil {
+		log.DefaultLogger.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-sigs
+		log.DefaultLogger.Infof("got system signal: %v, exiting", sig)
+		close(stop)
+	}()
+
+	log.DefaultLogger.Infof("Starting rubble controller...")
+	if enableNetworkPolicy {
+		go controller.NewNetworkPolicyController(k8sClient, neutronClient, syncPeriod).Run(stop)
+	}
+	if enableQuota {
+		go controller.NewQuotaController(k8sClient, neutronClient, syncPeriod, quotaReserve).Run(stop)
+	}
+	controller.NewIPPoolController(k8sClient, neutronClient, syncPeriod).Run(stop)
+}
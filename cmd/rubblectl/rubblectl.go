@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"google.golang.org/grpc"
+)
+
+const usage = `rubblectl inspect and manipulate port pools of rubble daemon on this node
+
+Usage:
+  rubblectl [--socket path] [--timeout duration] <command> [flags]
+
+Commands:
+  ports    [--pool name] [--state inuse|idle|reserved]  list ports with ips, reserve times and pods
+  release  [--force] <port-id>                         delete port in use, even if its pod exists when force
+  drain    [--pool name] [--force]                     dispose idle ports, reserved ones too if force
+  resync                                               run garbage collection with neutron now
+  dump                                                 dump pod and port records in daemon db
+`
+
+var (
+	socketPath string
+	timeout    time.Duration
+)
+
+func main() {
+	fs := flag.NewFlagSet("rubblectl", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	fs.StringVar(&socketPath, "socket", utils.DefaultSocketPath, "socket of rubble daemon.")
+	fs.DurationVar(&timeout, "timeout", 2*time.Minute, "timeout of command.")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		panic(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client, conn, err := getAdminClient(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	defer conn.Close()
+
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "ports":
+		err = listPorts(ctx, client, args)
+	case "release":
+		err = releasePort(ctx, client, args)
+	case "drain":
+		err = drainPool(ctx, client, args)
+	case "resync":
+		err = resync(ctx, client)
+	case "dump":
+		err = dumpPodPorts(ctx, client)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(1)
+}
+
+func getAdminClient(ctx context.Context) (rpc.RubbleAdminClient, *grpc.ClientConn, error) {
+	conn, err := grpc.DialContext(ctx, socketPath, grpc.WithInsecure(), grpc.WithContextDialer(
+		func(ctx context.Context, s string) (net.Conn, error) {
+			unixAddr, err := net.ResolveUnixAddr("unix", socketPath)
+			if err != nil {
+				return nil, fmt.Errorf("error resolve addr, %w", err)
+			}
+			d := net.Dialer{}
+			return d.DialContext(ctx, "unix", unixAddr.String())
+		}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error dial to rubble server %s, with error: %w", socketPath, err)
+	}
+	return rpc.NewRubbleAdminClient(conn), conn, nil
+}
+
+func listPorts(ctx context.Context, client rpc.RubbleAdminClient, args []string) error {
+	fs := flag.NewFlagSet("ports", flag.ExitOnError)
+	poolName := fs.String("pool", "", "list ports of pool only, e.g. default or subnet:<id>.")
+	state := fs.String("state", "", "list ports in state only: inuse, idle or reserved.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	reply, err := client.ListPorts(ctx, &rpc.ListPortsRequest{Pool: *poolName, State: *state})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tPOOL\tSTATE\tIPV4\tIPV6\tSUBNET\tRESERVED UNTIL\tPOD")
+	for _, p := range reply.Ports {
+		reserved := "-"
+		if p.ReservedUntil > 0 {
+			reserved = time.Unix(p.ReservedUntil, 0).Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.Pool, p.State, orNone(p.IP.GetIPv4()),
+			orNone(p.IP.GetIPv6()), p.SubnetID, reserved, orNone(p.Pod))
+	}
+	return w.Flush()
+}
+
+func orNone(s string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	return s
+}
+
+func releasePort(ctx context.Context, client rpc.RubbleAdminClient, args []string) error {
+	fs := flag.NewFlagSet("release", flag.ExitOnError)
+	force := fs.Bool("force", false, "release port even if pod using it still exists.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("release requires exactly one port id")
+	}
+	portID := fs.Arg(0)
+	reply, err := client.ReleasePort(ctx, &rpc.ReleasePortRequest{PortID: portID, Force: *force})
+	if err != nil {
+		return err
+	}
+	if len(reply.Pod) > 0 {
+		fmt.Printf("port %s released from pod %s\n", portID, reply.Pod)
+	} else {
+		fmt.Printf("port %s released\n", portID)
+	}
+	return nil
+}
+
+func drainPool(ctx context.Context, client rpc.RubbleAdminClient, args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	poolName := fs.String("pool", "", "drain pool with the name only, all pools if empty.")
+	force := fs.Bool("force", false, "dispose ports reserved for pods too.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	reply, err := client.DrainPool(ctx, &rpc.DrainPoolRequest{Pool: *poolName, Force: *force})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d ports disposed\n", reply.Disposed)
+	return nil
+}
+
+func resync(ctx context.Context, client rpc.RubbleAdminClient) error {
+	reply, err := client.Resync(ctx, &rpc.ResyncRequest{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("resync done, %d ports managed by node\n", reply.Ports)
+	return nil
+}
+
+func dumpPodPorts(ctx context.Context, client rpc.RubbleAdminClient) error {
+	reply, err := client.DumpPodPorts(ctx, &rpc.DumpPodPortsRequest{})
+	if err != nil {
+		return err
+	}
+	for _, e := range reply.Entries {
+		fmt.Printf("%s\t%s\n", e.Key, e.Value)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/log"
+	"github.com/rubble/pkg/neutron"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const DefaultSyncPeriod = 30 * time.Second
+
+var logger = log.DefaultLogger.WithField("component:", "rubble controller")
+
+// IPPoolController pre-create neutron ports for addresses of RubbleIPPools, and release
+// addresses of pods which will not come back
+type IPPoolController struct {
+	k8s    *k8s.K8s
+	client *neutron.Client
+	period time.Duration
+}
+
+func NewIPPoolController(k8sClient *k8s.K8s, client *neutron.Client, period time.Duration) *IPPoolController {
+	if period <= 0 {
+		period = DefaultSyncPeriod
+	}
+	return &IPPoolController{
+		k8s:    k8sClient,
+		client: client,
+		period: period,
+	}
+}
+
+// Run sync ip pools periodically until stop closed
+func (c *IPPoolController) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for {
+		if err := c.Sync(); err != nil {
+			logger.Errorf("error sync ip pools: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *IPPoolController) Sync() error {
+	pools, err := c.k8s.ListIPPools()
+	if err != nil {
+		return fmt.Errorf("failed to list ip pools with error: %w", err)
+	}
+	for _, p := range pools {
+		if err = c.syncPool(p); err != nil {
+			logger.Errorf("failed to sync ip pool %s with error: %s", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *IPPoolController) syncPool(ipPool *k8s.IPPool) error {
+	addrs, err := ipPool.ListAddresses()
+	if err != nil {
+		return err
+	}
+	subnetID, err := c.client.GetSubnetworkID(ipPool.Spec.Subnet)
+	if err != nil {
+		return fmt.Errorf("failed to get subnet with: %s, error is: %w", ipPool.Spec.Subnet, err)
+	}
+	if len(subnetID) == 0 {
+		return fmt.Errorf("subnet %s not found", ipPool.Spec.Subnet)
+	}
+	sb, err := c.client.GetSubnet(subnetID)
+	if err != nil {
+		return fmt.Errorf("failed to get subnet with error: %w", err)
+	}
+	_, cidr, err := net.ParseCIDR(sb.CIDR)
+	if err != nil {
+		return err
+	}
+
+	ports, err := c.client.ListPortWithFilter(neutron.ListFilter{
+		NetworkID: sb.NetworkID,
+		Tags:      ipam.IPPoolTag(ipPool.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list ports of ip pool %s with error: %w", ipPool.Name, err)
+	}
+	portOfIP := make(map[string]string)
+	for _, p := range ports {
+		for _, fip := range p.FixedIPs {
+			if fip.SubnetID == sb.ID {
+				portOfIP[net.ParseIP(fip.IPAddress).String()] = p.ID
+			}
+		}
+	}
+
+	changed := false
+	wanted := make(map[string]bool)
+	allocs := ipPool.Status.Allocations
+	for _, ip := range addrs {
+		wanted[ip] = true
+		alloc := allocs[ip]
+
+		if id, ok := portOfIP[ip]; ok {
+			if alloc.PortID != id {
+				alloc.PortID = id
+				changed = true
+			}
+		} else if cidr.Contains(net.ParseIP(ip)) {
+			id, err := c.createPort(ipPool.Name, sb.NetworkID, sb.ID, ip)
+			if err != nil {
+				logger.Errorf("failed to create port for address %s of ip pool %s with error: %s", ip, ipPool.Name, err)
+			} else {
+				alloc.PortID = id
+				changed = true
+			}
+		} else {
+			logger.Warnf("address %s of ip pool %s is not in subnet %s", ip, ipPool.Name, sb.CIDR)
+		}
+
+		if len(alloc.Pod) > 0 && len(alloc.Node) == 0 && c.allocationStale(alloc) {
+			logger.Infof("pod %s of address %s in ip pool %s is gone, free the address", alloc.Pod, ip, ipPool.Name)
+			alloc.Pod = ""
+			alloc.Owner = ""
+			changed = true
+		}
+		allocs[ip] = alloc
+	}
+
+	// addresses removed from spec are deleted once not used by pod
+	for ip, alloc := range allocs {
+		if wanted[ip] || len(alloc.Node) > 0 {
+			continue
+		}
+		if len(alloc.PortID) > 0 {
+			if err = c.client.DeletePort(alloc.PortID); err != nil {
+				logger.Errorf("failed to delete port %s of ip pool %s with error: %s", alloc.PortID, ipPool.Name, err)
+				continue
+			}
+		}
+		delete(allocs, ip)
+		changed = true
+	}
+	for ip, id := range portOfIP {
+		if _, ok := allocs[ip]; ok {
+			continue
+		}
+		logger.Infof("delete port %s of address %s not in ip pool %s", id, ip, ipPool.Name)
+		if err = c.client.DeletePort(id); err != nil {
+			logger.Errorf("failed to delete port %s of ip pool %s with error: %s", id, ipPool.Name, err)
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	_, err = c.k8s.UpdateIPPoolStatus(ipPool)
+	if apierrors.IsConflict(err) {
+		// pool is modified by daemon, sync again in next period
+		logger.Infof("ip pool %s is modified, sync later", ipPool.Name)
+		return nil
+	}
+	return err
+}
+
+func (c *IPPoolController) createPort(pool, networkID, subnetID, ip string) (string, error) {
+	port, err := c.client.CreatePort(&neutron.CreateOpts{
+		Name:        fmt.Sprintf("rubble-ippool-%s-%s", pool, ip),
+		NetworkID:   networkID,
+		SubnetID:    subnetID,
+		IPAddress:   ip,
+		DeviceOwner: ipam.DeviceOwner,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = c.client.AddTag("ports", port.ID, ipam.IPPoolTag(pool)); err != nil {
+		_ = c.client.DeletePort(port.ID)
+		return "", fmt.Errorf("failed to add tag to port:%s with error %w", port.ID, err)
+	}
+	logger.Infof("created port %s for address %s of ip pool %s", port.ID, ip, pool)
+	return port.ID, nil
+}
+
+// allocationStale check whether pod of allocation is deleted and will not be recreated,
+// pods of an existing statefulset keep their addresses
+func (c *IPPoolController) allocationStale(alloc k8s.IPAllocation) bool {
+	parts := strings.SplitN(alloc.Pod, "/", 2)
+	if len(parts) != 2 {
+		return true
+	}
+	exists, err := c.k8s.PodExists(parts[0], parts[1])
+	if err != nil || exists {
+		return false
+	}
+
+	owner := strings.SplitN(alloc.Owner, "/", 2)
+	if len(owner) == 2 && owner[0] == "StatefulSet" {
+		exists, err = c.k8s.StatefulSetExists(parts[0], owner[1])
+		return err == nil && !exists
+	}
+	return true
+}
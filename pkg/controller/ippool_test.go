@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/neutron/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+// poolAddresses return addresses of ports tagged with ip pool
+func poolAddresses(server *fake.Server, name string) []string {
+	var ret []string
+	for _, p := range server.Ports() {
+		for _, tag := range p.Tags {
+			if tag == ipam.IPPoolTag(name) {
+				ret = append(ret, p.FixedIPs[0].IPAddress)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestIPPoolSync(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	netID := server.AddNetwork("net", 1450)
+	subnetID, err := server.AddSubnet(netID, "sub", "10.0.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// port of an address not in pool any more
+	if _, err = server.AddPort(fake.Port{NetworkID: netID, FixedIPs: []fake.FixedIP{{SubnetID: subnetID, IPAddress: "10.0.0.30"}},
+		Tags: []string{ipam.IPPoolTag("web")}}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := neutron.NewClientWithAuthOptions(server.AuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipPool := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": k8s.IPPoolGVR.GroupVersion().String(),
+		"kind":       k8s.IPPoolKind,
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"subnet":    "sub",
+			"addresses": []interface{}{"10.0.0.10-10.0.0.12", "10.0.1.5"},
+		},
+		"status": map[string]interface{}{"allocations": map[string]interface{}{
+			// pod is deleted and not owned by statefulset
+			"10.0.0.11": map[string]interface{}{"pod": "default/gone"},
+			// pod of an existing statefulset comes back
+			"10.0.0.12": map[string]interface{}{"pod": "default/web-0", "owner": "StatefulSet/web"},
+			// removed from spec, unused and used on node
+			"10.0.0.20": map[string]interface{}{},
+			"10.0.0.21": map[string]interface{}{"pod": "default/web-1", "node": "n1"},
+		}},
+	}}
+	kubeClient := k8sfake.NewSimpleClientset(
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.IPPoolGVR: k8s.IPPoolKind + "List"}, ipPool)
+	kc := k8s.NewK8sWithClient(kubeClient, dynamicClient, "")
+	c := NewIPPoolController(kc, client, 0)
+
+	if err = c.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	// addresses out of subnet get no port, port of address not in pool is deleted
+	want := []string{"10.0.0.10", "10.0.0.11", "10.0.0.12"}
+	if got := poolAddresses(server, "web"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ports of pool = %v, want %v", got, want)
+	}
+
+	p, err := kc.GetIPPool("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := p.Status.Allocations
+	for _, ip := range want {
+		if len(allocs[ip].PortID) == 0 {
+			t.Errorf("address %s has no port in status", ip)
+		}
+	}
+	if _, ok := allocs["10.0.1.5"]; ok && len(allocs["10.0.1.5"].PortID) > 0 {
+		t.Errorf("address out of subnet got port %s", allocs["10.0.1.5"].PortID)
+	}
+	if a := allocs["10.0.0.11"]; len(a.Pod) > 0 {
+		t.Errorf("address of deleted pod is still allocated to %s", a.Pod)
+	}
+	if a := allocs["10.0.0.12"]; a.Pod != "default/web-0" {
+		t.Errorf("address of statefulset pod allocated to %q, want default/web-0", a.Pod)
+	}
+	if _, ok := allocs["10.0.0.20"]; ok {
+		t.Errorf("unused address removed from spec is kept")
+	}
+	if a, ok := allocs["10.0.0.21"]; !ok || a.Node != "n1" {
+		t.Errorf("address removed from spec but used on node is dropped: %+v", a)
+	}
+
+	// ports are created once
+	creates := server.RequestCount(http.MethodPost, "/v2.0/ports")
+	if err = c.Sync(); err != nil {
+		t.Fatalf("sync again: %v", err)
+	}
+	if n := server.RequestCount(http.MethodPost, "/v2.0/ports"); n != creates {
+		t.Errorf("ports created by second sync = %d, want 0", n-creates)
+	}
+}
@@ -0,0 +1,648 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	listersnetworkingv1 "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// PolicySecurityGroupPrefix is name prefix of security groups created for network policies,
+	// the uid of policy follows the prefix
+	PolicySecurityGroupPrefix = ipam.PolicySecurityGroupPrefix
+
+	ingress = "ingress"
+	egress  = "egress"
+
+	// policySyncDelay debounce events, policies changed in the delay are synced together
+	policySyncDelay = time.Second
+)
+
+// NetworkPolicyController translate network policies into neutron security groups, pod traffic goes to
+// vpc directly by ipvlan so that policies have to be enforced on pod ports. security groups of policies
+// selecting a pod are set in NetworkPolicyAnnotation of the pod and applied to its port by daemon
+type NetworkPolicyController struct {
+	k8s    *k8s.K8s
+	client *neutron.Client
+	period time.Duration
+
+	factory      informers.SharedInformerFactory
+	policyLister listersnetworkingv1.NetworkPolicyLister
+	podLister    listerscorev1.PodLister
+	nsLister     listerscorev1.NamespaceLister
+	synced       []cache.InformerSynced
+	trigger      chan struct{}
+
+	// lock guard dirty and full, dirty are uids of policies changed or matching changed pods,
+	// full is set by periodic and namespace resync of all policies
+	lock  sync.Mutex
+	dirty map[types.UID]bool
+	full  bool
+}
+
+// sgRule is a neutron security group rule, empty protocol and zero ports match all,
+// empty remoteIPPrefix match any address
+type sgRule struct {
+	direction      string
+	etherType      string
+	protocol       string
+	portMin        int
+	portMax        int
+	remoteIPPrefix string
+}
+
+type portRange struct {
+	protocol string
+	min, max int
+}
+
+func NewNetworkPolicyController(k8sClient *k8s.K8s, client *neutron.Client, period time.Duration) *NetworkPolicyController {
+	if period <= 0 {
+		period = DefaultSyncPeriod
+	}
+	factory := informers.NewSharedInformerFactory(k8sClient.Client(), 0)
+	c := &NetworkPolicyController{
+		k8s:          k8sClient,
+		client:       client,
+		period:       period,
+		factory:      factory,
+		policyLister: factory.Networking().V1().NetworkPolicies().Lister(),
+		podLister:    factory.Core().V1().Pods().Lister(),
+		nsLister:     factory.Core().V1().Namespaces().Lister(),
+		trigger:      make(chan struct{}, 1),
+		dirty:        make(map[types.UID]bool),
+		full:         true,
+	}
+
+	policyHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueuePolicy(obj) },
+		UpdateFunc: func(old, cur interface{}) { c.enqueuePolicy(cur) },
+		DeleteFunc: func(obj interface{}) { c.enqueuePolicy(obj) },
+	}
+	// namespace labels select peers of any policy
+	nsHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueAll() },
+		UpdateFunc: func(old, cur interface{}) { c.enqueueAll() },
+		DeleteFunc: func(obj interface{}) { c.enqueueAll() },
+	}
+	podHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueuePods(obj) },
+		UpdateFunc: func(old, cur interface{}) {
+			// pods are updated frequently, sync only when they are matched or addressed differently
+			if podChanged(old.(*corev1.Pod), cur.(*corev1.Pod)) {
+				c.enqueuePods(old, cur)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.enqueuePods(obj) },
+	}
+
+	policyInformer := factory.Networking().V1().NetworkPolicies().Informer()
+	policyInformer.AddEventHandler(policyHandler)
+	nsInformer := factory.Core().V1().Namespaces().Informer()
+	nsInformer.AddEventHandler(nsHandler)
+	podInformer := factory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(podHandler)
+	c.synced = append(c.synced, policyInformer.HasSynced, nsInformer.HasSynced, podInformer.HasSynced)
+	return c
+}
+
+// Run sync network policies when policies, pods or namespaces changed, and periodically until stop closed
+func (c *NetworkPolicyController) Run(stop <-chan struct{}) {
+	c.factory.Start(stop)
+	if !cache.WaitForCacheSync(stop, c.synced...) {
+		logger.Errorf("failed to wait for caches of network policy controller to sync")
+		return
+	}
+
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for {
+		if err := c.Sync(); err != nil {
+			logger.Errorf("error sync network policies: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.enqueueAll()
+		case <-c.trigger:
+			// wait for more events, pods of a deployment are usually changed together
+			select {
+			case <-stop:
+				return
+			case <-time.After(policySyncDelay):
+			}
+		}
+	}
+}
+
+func (c *NetworkPolicyController) enqueue() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueAll sync all policies
+func (c *NetworkPolicyController) enqueueAll() {
+	c.lock.Lock()
+	c.full = true
+	c.lock.Unlock()
+	c.enqueue()
+}
+
+// enqueuePolicy sync the changed policy, its security group is deleted if policy is deleted
+func (c *NetworkPolicyController) enqueuePolicy(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	policy, ok := obj.(*networkingv1.NetworkPolicy)
+	if !ok {
+		return
+	}
+	c.lock.Lock()
+	c.dirty[policy.UID] = true
+	c.lock.Unlock()
+	c.enqueue()
+}
+
+// enqueuePods sync policies selecting the pods or their peers, both old and current pods are given
+// on update so that policies no longer matching the pod are synced too
+func (c *NetworkPolicyController) enqueuePods(objs ...interface{}) {
+	var pods []*corev1.Pod
+	for _, obj := range objs {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	policies, err := c.policyLister.List(labels.Everything())
+	if err != nil {
+		logger.Errorf("failed to list network policies with error: %s", err)
+		c.enqueueAll()
+		return
+	}
+	namespaces, err := c.nsLister.List(labels.Everything())
+	if err != nil {
+		logger.Errorf("failed to list namespaces with error: %s", err)
+		c.enqueueAll()
+		return
+	}
+	nsLabels := make(map[string]labels.Set)
+	for _, ns := range namespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+
+	matched := matchingPolicies(policies, pods, nsLabels)
+	if len(matched) == 0 {
+		return
+	}
+	c.lock.Lock()
+	for _, uid := range matched {
+		c.dirty[uid] = true
+	}
+	c.lock.Unlock()
+	c.enqueue()
+}
+
+// matchingPolicies return uids of policies selecting any of pods, or selecting any of pods as peer
+func matchingPolicies(policies []*networkingv1.NetworkPolicy, pods []*corev1.Pod, nsLabels map[string]labels.Set) []types.UID {
+	var ret []types.UID
+	for _, policy := range policies {
+		if len(selectPods(policy, pods)) > 0 {
+			ret = append(ret, policy.UID)
+			continue
+		}
+		var peers []networkingv1.NetworkPolicyPeer
+		for _, rule := range policy.Spec.Ingress {
+			peers = append(peers, rule.From...)
+		}
+		for _, rule := range policy.Spec.Egress {
+			peers = append(peers, rule.To...)
+		}
+		for _, peer := range peers {
+			if peer.IPBlock == nil && len(peerPods(policy, peer, pods, nsLabels)) > 0 {
+				ret = append(ret, policy.UID)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+func podChanged(old, cur *corev1.Pod) bool {
+	if old.Status.PodIP != cur.Status.PodIP || old.Status.Phase != cur.Status.Phase || old.Spec.NodeName != cur.Spec.NodeName {
+		return true
+	}
+	return !labels.Equals(old.Labels, cur.Labels)
+}
+
+// Sync sync security groups of dirty policies, or all policies if full sync is requested,
+// and annotations of pods selected by policies
+func (c *NetworkPolicyController) Sync() error {
+	c.lock.Lock()
+	dirty, full := c.dirty, c.full
+	c.dirty, c.full = make(map[types.UID]bool), false
+	c.lock.Unlock()
+
+	if err := c.sync(dirty, full); err != nil {
+		// retry all policies in next period as it is unknown which ones are synced
+		c.lock.Lock()
+		c.full = true
+		c.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *NetworkPolicyController) sync(dirty map[types.UID]bool, full bool) error {
+	policies, err := c.policyLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list network policies with error: %w", err)
+	}
+	pods, err := c.podLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list pods with error: %w", err)
+	}
+	namespaces, err := c.nsLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces with error: %w", err)
+	}
+	nsLabels := make(map[string]labels.Set)
+	for _, ns := range namespaces {
+		nsLabels[ns.Name] = ns.Labels
+	}
+
+	existing, err := c.client.ListSecurityGroups(PolicySecurityGroupPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list security groups with error: %w", err)
+	}
+	sgOfName := make(map[string]groups.SecGroup)
+	for _, sg := range existing {
+		sgOfName[sg.Name] = sg
+	}
+
+	// security groups of pods keyed by namespace/name
+	podGroups := make(map[string][]string)
+	failed := make(map[string]bool)
+	wanted := make(map[string]bool)
+	for _, policy := range policies {
+		name := PolicySecurityGroupPrefix + string(policy.UID)
+		wanted[name] = true
+		selected := selectPods(policy, pods)
+
+		// security groups of policies not changed are up to date
+		sg, exists := sgOfName[name]
+		sgID := sg.ID
+		if full || dirty[policy.UID] || !exists {
+			if sgID, err = c.syncPolicy(policy, name, sgOfName, pods, nsLabels); err != nil {
+				logger.Errorf("failed to sync network policy %s/%s with error: %s", policy.Namespace, policy.Name, err)
+				// keep security groups of selected pods unchanged until policy synced
+				for _, pod := range selected {
+					failed[podKey(pod)] = true
+				}
+				c.lock.Lock()
+				c.dirty[policy.UID] = true
+				c.lock.Unlock()
+				continue
+			}
+		}
+		for _, pod := range selected {
+			podGroups[podKey(pod)] = append(podGroups[podKey(pod)], sgID)
+		}
+	}
+
+	for _, pod := range pods {
+		key := podKey(pod)
+		if failed[key] || !managedPod(pod) {
+			continue
+		}
+		sgs := podGroups[key]
+		sort.Strings(sgs)
+		value := strings.Join(sgs, ",")
+		if pod.Annotations[ipam.NetworkPolicyAnnotation] == value {
+			continue
+		}
+		logger.Infof("set security groups of network policies of pod %s to %q", key, value)
+		if err = c.k8s.SetPodAnnotation(pod.Namespace, pod.Name, ipam.NetworkPolicyAnnotation, value); err != nil {
+			logger.Errorf("failed to set annotation of pod %s with error: %s", key, err)
+		}
+	}
+
+	// security groups of deleted policies can be deleted after daemons removed them from ports
+	for name, sg := range sgOfName {
+		if wanted[name] {
+			continue
+		}
+		if err = c.client.DeleteSecurityGroup(sg.ID); err != nil {
+			logger.Infof("security group %s of deleted network policy is not deleted, retry later: %s", sg.ID, err)
+			continue
+		}
+		logger.Infof("deleted security group %s of network policy %s", sg.ID, sg.Description)
+	}
+	return nil
+}
+
+// syncPolicy create security group of policy if not exists and update its rules, id of the group is returned
+func (c *NetworkPolicyController) syncPolicy(policy *networkingv1.NetworkPolicy, name string, sgOfName map[string]groups.SecGroup,
+	pods []*corev1.Pod, nsLabels map[string]labels.Set) (string, error) {
+	sg, ok := sgOfName[name]
+	if !ok {
+		created, err := c.client.CreateSecurityGroup(name, fmt.Sprintf("%s/%s", policy.Namespace, policy.Name))
+		if err != nil {
+			return "", fmt.Errorf("failed to create security group with error: %w", err)
+		}
+		logger.Infof("created security group %s for network policy %s/%s", created.ID, policy.Namespace, policy.Name)
+		sg = *created
+	}
+
+	existing, err := c.client.ListSecurityGroupRules(sg.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list rules of security group %s with error: %w", sg.ID, err)
+	}
+	desired := make(map[sgRule]bool)
+	for _, r := range policyRules(policy, pods, nsLabels) {
+		desired[r] = true
+	}
+
+	for _, r := range existing {
+		rule := convertRule(r)
+		if desired[rule] {
+			delete(desired, rule)
+			continue
+		}
+		if err = c.client.DeleteSecurityGroupRule(r.ID); err != nil {
+			return "", fmt.Errorf("failed to delete rule %s of security group %s with error: %w", r.ID, sg.ID, err)
+		}
+	}
+	for rule := range desired {
+		_, err = c.client.CreateSecurityGroupRule(rules.CreateOpts{
+			Direction:      rules.RuleDirection(rule.direction),
+			EtherType:      rules.RuleEtherType(rule.etherType),
+			SecGroupID:     sg.ID,
+			Protocol:       rules.RuleProtocol(rule.protocol),
+			PortRangeMin:   rule.portMin,
+			PortRangeMax:   rule.portMax,
+			RemoteIPPrefix: rule.remoteIPPrefix,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create rule %+v of security group %s with error: %w", rule, sg.ID, err)
+		}
+	}
+	return sg.ID, nil
+}
+
+// policyRules translate policy into security group rules, traffic of a direction not in policy types
+// is allowed as security groups of all policies selecting a pod are unioned
+func policyRules(policy *networkingv1.NetworkPolicy, pods []*corev1.Pod, nsLabels map[string]labels.Set) []sgRule {
+	hasIngress, hasEgress := policyTypes(policy)
+
+	var ret []sgRule
+	if hasIngress {
+		for _, r := range policy.Spec.Ingress {
+			ret = append(ret, peerRules(ingress, policy, r.From, r.Ports, pods, nsLabels)...)
+		}
+	} else {
+		ret = append(ret, allowAll(ingress)...)
+	}
+	if hasEgress {
+		for _, r := range policy.Spec.Egress {
+			ret = append(ret, peerRules(egress, policy, r.To, r.Ports, pods, nsLabels)...)
+		}
+	} else {
+		ret = append(ret, allowAll(egress)...)
+	}
+	return ret
+}
+
+func policyTypes(policy *networkingv1.NetworkPolicy) (hasIngress, hasEgress bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	for _, t := range policy.Spec.PolicyTypes {
+		switch t {
+		case networkingv1.PolicyTypeIngress:
+			hasIngress = true
+		case networkingv1.PolicyTypeEgress:
+			hasEgress = true
+		}
+	}
+	return
+}
+
+func allowAll(direction string) []sgRule {
+	return []sgRule{
+		{direction: direction, etherType: "IPv4"},
+		{direction: direction, etherType: "IPv6"},
+	}
+}
+
+// peerRules return rules allowing traffic from or to peers on ports, empty peers match all addresses
+// and empty ports match all ports
+func peerRules(direction string, policy *networkingv1.NetworkPolicy, peers []networkingv1.NetworkPolicyPeer,
+	ports []networkingv1.NetworkPolicyPort, pods []*corev1.Pod, nsLabels map[string]labels.Set) []sgRule {
+	var prefixes []string
+	if len(peers) == 0 {
+		prefixes = []string{"0.0.0.0/0", "::/0"}
+	}
+	for _, peer := range peers {
+		prefixes = append(prefixes, peerPrefixes(policy, peer, pods, nsLabels)...)
+	}
+
+	ranges := portRanges(policy, ports)
+	var ret []sgRule
+	for _, prefix := range prefixes {
+		ip, cidr, err := net.ParseCIDR(prefix)
+		if err != nil {
+			continue
+		}
+		etherType, remote := "IPv4", cidr.String()
+		if ip.To4() == nil {
+			etherType = "IPv6"
+		}
+		if ones, _ := cidr.Mask.Size(); ones == 0 {
+			remote = ""
+		}
+		for _, pr := range ranges {
+			ret = append(ret, sgRule{
+				direction:      direction,
+				etherType:      etherType,
+				protocol:       pr.protocol,
+				portMin:        pr.min,
+				portMax:        pr.max,
+				remoteIPPrefix: remote,
+			})
+		}
+	}
+	return ret
+}
+
+// peerPods return pods selected by pod and namespace selectors of peer
+func peerPods(policy *networkingv1.NetworkPolicy, peer networkingv1.NetworkPolicyPeer, pods []*corev1.Pod,
+	nsLabels map[string]labels.Set) []*corev1.Pod {
+	var nsSelector, podSelector labels.Selector
+	var err error
+	if peer.NamespaceSelector != nil {
+		if nsSelector, err = v1.LabelSelectorAsSelector(peer.NamespaceSelector); err != nil {
+			logger.Errorf("invalid namespace selector in network policy %s/%s: %s", policy.Namespace, policy.Name, err)
+			return nil
+		}
+	}
+	podSelector = labels.Everything()
+	if peer.PodSelector != nil {
+		if podSelector, err = v1.LabelSelectorAsSelector(peer.PodSelector); err != nil {
+			logger.Errorf("invalid pod selector in network policy %s/%s: %s", policy.Namespace, policy.Name, err)
+			return nil
+		}
+	}
+
+	var ret []*corev1.Pod
+	for _, pod := range pods {
+		if nsSelector == nil && pod.Namespace != policy.Namespace {
+			continue
+		}
+		if nsSelector != nil && !nsSelector.Matches(nsLabels[pod.Namespace]) {
+			continue
+		}
+		if podSelector.Matches(labels.Set(pod.Labels)) {
+			ret = append(ret, pod)
+		}
+	}
+	return ret
+}
+
+// peerPrefixes return cidrs of ip block or addresses of pods selected by peer
+func peerPrefixes(policy *networkingv1.NetworkPolicy, peer networkingv1.NetworkPolicyPeer, pods []*corev1.Pod,
+	nsLabels map[string]labels.Set) []string {
+	if peer.IPBlock != nil {
+		if len(peer.IPBlock.Except) > 0 {
+			logger.Warnf("except of ip block %s in network policy %s/%s is not supported by security group",
+				peer.IPBlock.CIDR, policy.Namespace, policy.Name)
+		}
+		return []string{peer.IPBlock.CIDR}
+	}
+
+	var ret []string
+	for _, pod := range peerPods(policy, peer, pods, nsLabels) {
+		if terminated(pod) {
+			continue
+		}
+		for _, ip := range podIPs(pod) {
+			if ip.To4() != nil {
+				ret = append(ret, ip.String()+"/32")
+			} else {
+				ret = append(ret, ip.String()+"/128")
+			}
+		}
+	}
+	return ret
+}
+
+// portRanges convert ports of policy, named ports are not supported as they differ between pods
+func portRanges(policy *networkingv1.NetworkPolicy, ports []networkingv1.NetworkPolicyPort) []portRange {
+	if len(ports) == 0 {
+		return []portRange{{}}
+	}
+	var ret []portRange
+	for _, p := range ports {
+		protocol := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			protocol = *p.Protocol
+		}
+		pr := portRange{protocol: strings.ToLower(string(protocol))}
+		if p.Port != nil {
+			if p.Port.IntValue() == 0 {
+				logger.Warnf("named port %s in network policy %s/%s is not supported", p.Port.String(), policy.Namespace, policy.Name)
+				continue
+			}
+			pr.min, pr.max = p.Port.IntValue(), p.Port.IntValue()
+			if p.EndPort != nil && int(*p.EndPort) > pr.min {
+				pr.max = int(*p.EndPort)
+			}
+		}
+		ret = append(ret, pr)
+	}
+	return ret
+}
+
+// selectPods return pods managed by rubble selected by policy
+func selectPods(policy *networkingv1.NetworkPolicy, pods []*corev1.Pod) []*corev1.Pod {
+	selector, err := v1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		logger.Errorf("invalid pod selector in network policy %s/%s: %s", policy.Namespace, policy.Name, err)
+		return nil
+	}
+	var ret []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Namespace == policy.Namespace && managedPod(pod) && selector.Matches(labels.Set(pod.Labels)) {
+			ret = append(ret, pod)
+		}
+	}
+	return ret
+}
+
+func convertRule(r rules.SecGroupRule) sgRule {
+	rule := sgRule{
+		direction:      r.Direction,
+		etherType:      r.EtherType,
+		protocol:       r.Protocol,
+		portMin:        r.PortRangeMin,
+		portMax:        r.PortRangeMax,
+		remoteIPPrefix: r.RemoteIPPrefix,
+	}
+	if _, cidr, err := net.ParseCIDR(r.RemoteIPPrefix); err == nil {
+		rule.remoteIPPrefix = cidr.String()
+		if ones, _ := cidr.Mask.Size(); ones == 0 {
+			rule.remoteIPPrefix = ""
+		}
+	}
+	return rule
+}
+
+// managedPod check whether pod uses port created by rubble
+func managedPod(pod *corev1.Pod) bool {
+	return !pod.Spec.HostNetwork && len(pod.Spec.NodeName) > 0 && !terminated(pod)
+}
+
+func terminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+func podIPs(pod *corev1.Pod) []net.IP {
+	var ret []net.IP
+	for _, podIP := range pod.Status.PodIPs {
+		if ip := net.ParseIP(podIP.IP); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	if len(ret) == 0 {
+		if ip := net.ParseIP(pod.Status.PodIP); ip != nil {
+			ret = append(ret, ip)
+		}
+	}
+	return ret
+}
+
+func podKey(pod *corev1.Pod) string {
+	return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMatchingPolicies(t *testing.T) {
+	policy := func(uid, namespace string, selector map[string]string, from ...networkingv1.NetworkPolicyPeer) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: namespace, UID: types.UID(uid)},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: selector},
+				Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: from}},
+			},
+		}
+	}
+	pod := func(namespace string, podLabels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: namespace, Labels: podLabels},
+			Spec:       corev1.PodSpec{NodeName: "node"},
+		}
+	}
+	policies := []*networkingv1.NetworkPolicy{
+		policy("web", "default", map[string]string{"app": "web"}),
+		policy("db", "default", map[string]string{"app": "db"},
+			networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}),
+		policy("monitor", "default", map[string]string{"app": "db"},
+			networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}}),
+		policy("block", "default", map[string]string{"app": "cache"},
+			networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}),
+	}
+	nsLabels := map[string]labels.Set{"default": {}, "ops": {"team": "ops"}}
+
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		want []types.UID
+	}{
+		{
+			name: "selected and peer",
+			pods: []*corev1.Pod{pod("default", map[string]string{"app": "web"})},
+			want: []types.UID{"web", "db"},
+		},
+		{
+			name: "selected",
+			pods: []*corev1.Pod{pod("default", map[string]string{"app": "db"})},
+			want: []types.UID{"db", "monitor"},
+		},
+		{
+			name: "peer by namespace",
+			pods: []*corev1.Pod{pod("ops", map[string]string{"app": "prometheus"})},
+			want: []types.UID{"monitor"},
+		},
+		{
+			name: "other namespace",
+			pods: []*corev1.Pod{pod("ops", map[string]string{"app": "web"})},
+			want: []types.UID{"monitor"},
+		},
+		{
+			name: "ip block does not select pods",
+			pods: []*corev1.Pod{pod("default", map[string]string{"app": "other"})},
+		},
+		{
+			name: "old and current labels",
+			pods: []*corev1.Pod{pod("default", map[string]string{"app": "cache"}), pod("default", map[string]string{"app": "other"})},
+			want: []types.UID{"block"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchingPolicies(policies, tt.pods, nsLabels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchingPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
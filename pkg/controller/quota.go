@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+)
+
+// QuotaController grant capacity budgets to nodes from free addresses of subnets they share, so a few
+// busy nodes can not exhaust a subnet and starve others. daemons report usage in leases of nodes and
+// apply budgets written to quota config map
+type QuotaController struct {
+	k8s    *k8s.K8s
+	client *neutron.Client
+	period time.Duration
+	// reserve is addresses of every subnet group kept out of budgets, e.g. for ports not created by rubble
+	reserve int
+
+	last map[string]k8s.NodeQuota
+}
+
+func NewQuotaController(k8sClient *k8s.K8s, client *neutron.Client, period time.Duration, reserve int) *QuotaController {
+	if period <= 0 {
+		period = DefaultSyncPeriod
+	}
+	return &QuotaController{
+		k8s:     k8sClient,
+		client:  client,
+		period:  period,
+		reserve: reserve,
+	}
+}
+
+// Run sync budgets periodically until stop closed
+func (c *QuotaController) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for {
+		if err := c.Sync(); err != nil {
+			logger.Errorf("error sync quota: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync grant budgets to nodes reporting usage, nodes using the same subnets share free addresses of them.
+// free addresses of a subnet used by groups of nodes with different subnets are split among the groups
+func (c *QuotaController) Sync() error {
+	usages, err := c.k8s.ListNodeUsages(time.Now())
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]k8s.NodeUsage)
+	for _, u := range usages {
+		subnetIDs := append([]string{}, u.Subnets...)
+		sort.Strings(subnetIDs)
+		key := strings.Join(subnetIDs, ",")
+		groups[key] = append(groups[key], u)
+	}
+
+	var subnetIDs []string
+	for key := range groups {
+		subnetIDs = append(subnetIDs, strings.Split(key, ",")...)
+	}
+	free, failed := c.freeAddresses(subnetIDs)
+	shares := shareFree(groups, free)
+
+	quotas := make(map[string]k8s.NodeQuota)
+	for key, nodes := range groups {
+		if ids := failedSubnets(key, failed); len(ids) > 0 {
+			logger.Errorf("skip quota of subnets %s as free addresses of %v are unknown", key, ids)
+			continue
+		}
+		budget, limited := shares[key]
+		if limited {
+			budget -= c.reserve
+		} else {
+			// only ipv6 subnets which are not exhausted
+			budget = math.MaxInt32
+		}
+		for _, u := range nodes {
+			// ports of nodes are counted as used in subnets
+			budget += u.InUse + u.Idle
+		}
+		for node, capacity := range fairShare(nodes, budget) {
+			quotas[node] = k8s.NodeQuota{Capacity: capacity}
+		}
+	}
+
+	if reflect.DeepEqual(quotas, c.last) {
+		return nil
+	}
+	if err = c.k8s.UpdateNodeQuotas(quotas); err != nil {
+		return fmt.Errorf("failed to update quotas with error: %w", err)
+	}
+	logger.Infof("granted quotas %v", quotas)
+	c.last = quotas
+	return nil
+}
+
+func failedSubnets(key string, failed map[string]bool) []string {
+	var ret []string
+	for _, id := range strings.Split(key, ",") {
+		if failed[id] {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// freeAddresses return addresses of ipv4 subnets not used by any port, ipv6 subnets are not included
+// as they are not exhausted. subnets failed to get are returned in failed
+func (c *QuotaController) freeAddresses(subnetIDs []string) (map[string]int, map[string]bool) {
+	free := make(map[string]int)
+	failed := make(map[string]bool)
+	// addresses used in subnets of every network listed
+	used := make(map[string]map[string]int)
+	for _, id := range subnetIDs {
+		if _, ok := free[id]; ok || failed[id] {
+			continue
+		}
+		sb, err := c.client.GetSubnet(id)
+		if err != nil {
+			logger.Errorf("failed to get subnet %s with error: %v", id, err)
+			failed[id] = true
+			continue
+		}
+		if sb.IPVersion == 6 {
+			continue
+		}
+		if _, ok := used[sb.NetworkID]; !ok {
+			ports, err := c.client.ListPortWithNetworkID(sb.NetworkID)
+			if err != nil {
+				logger.Errorf("failed to list ports of network %s with error: %v", sb.NetworkID, err)
+				failed[id] = true
+				continue
+			}
+			counts := make(map[string]int)
+			for _, p := range ports {
+				for _, fip := range p.FixedIPs {
+					counts[fip.SubnetID]++
+				}
+			}
+			used[sb.NetworkID] = counts
+		}
+		free[id] = 0
+		if left := subnetSize(sb) - used[sb.NetworkID][sb.ID]; left > 0 {
+			free[id] = left
+		}
+	}
+	return free, failed
+}
+
+// shareFree split free addresses of every subnet among groups using it in proportion to their nodes,
+// so that a subnet shared by groups is not granted to each of them in full. groups without ipv4 subnet
+// are not in the result
+func shareFree(groups map[string][]k8s.NodeUsage, free map[string]int) map[string]int {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	shares := make(map[string]int)
+	for id, left := range free {
+		var users []string
+		nodes := 0
+		for _, key := range keys {
+			for _, sid := range strings.Split(key, ",") {
+				if sid == id {
+					users = append(users, key)
+					nodes += len(groups[key])
+					break
+				}
+			}
+		}
+		granted := 0
+		for _, key := range users {
+			share := left * len(groups[key]) / nodes
+			shares[key] += share
+			granted += share
+		}
+		// remainder of division goes to groups in order
+		for i := 0; granted < left; i++ {
+			shares[users[i%len(users)]]++
+			granted++
+		}
+	}
+	return shares
+}
+
+// subnetSize return addresses in allocation pools of ipv4 subnet
+func subnetSize(sb *subnets.Subnet) int {
+	size := 0
+	for _, pool := range sb.AllocationPools {
+		start, end := net.ParseIP(pool.Start).To4(), net.ParseIP(pool.End).To4()
+		if start == nil || end == nil {
+			continue
+		}
+		if n := int(binary.BigEndian.Uint32(end)) - int(binary.BigEndian.Uint32(start)) + 1; n > 0 {
+			size += n
+		}
+	}
+	return size
+}
+
+// fairShare grant budget to nodes: every node keeps ports in use, the rest is shared by max-min
+// fairness first up to demand of nodes, then up to their max capacity
+func fairShare(nodes []k8s.NodeUsage, budget int) map[string]int {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+	grants := make(map[string]int, len(nodes))
+	left := budget
+	for _, u := range nodes {
+		grants[u.Node] = u.InUse
+		left -= u.InUse
+	}
+	left = waterFill(nodes, grants, left, func(u k8s.NodeUsage) int {
+		if u.Demand < u.MaxCapacity {
+			return u.Demand
+		}
+		return u.MaxCapacity
+	})
+	waterFill(nodes, grants, left, func(u k8s.NodeUsage) int {
+		return u.MaxCapacity
+	})
+	return grants
+}
+
+// waterFill raise grants of nodes towards limit evenly with left addresses, addresses left over are returned
+func waterFill(nodes []k8s.NodeUsage, grants map[string]int, left int, limit func(k8s.NodeUsage) int) int {
+	for left > 0 {
+		var hungry []k8s.NodeUsage
+		for _, u := range nodes {
+			if grants[u.Node] < limit(u) {
+				hungry = append(hungry, u)
+			}
+		}
+		if len(hungry) == 0 {
+			break
+		}
+		share := left / len(hungry)
+		if share == 0 {
+			share = 1
+		}
+		for _, u := range hungry {
+			add := limit(u) - grants[u.Node]
+			if add > share {
+				add = share
+			}
+			if add > left {
+				add = left
+			}
+			grants[u.Node] += add
+			left -= add
+		}
+	}
+	return left
+}
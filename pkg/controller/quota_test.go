@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/neutron/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaterFill(t *testing.T) {
+	nodes := []k8s.NodeUsage{{Node: "a", MaxCapacity: 2}, {Node: "b", MaxCapacity: 5}, {Node: "c", MaxCapacity: 10}}
+	limit := func(u k8s.NodeUsage) int { return u.MaxCapacity }
+	tests := []struct {
+		name     string
+		grants   map[string]int
+		left     int
+		want     map[string]int
+		wantLeft int
+	}{
+		{name: "even", left: 6, want: map[string]int{"a": 2, "b": 2, "c": 2}},
+		{name: "capped node gives way", left: 12, want: map[string]int{"a": 2, "b": 5, "c": 5}},
+		{name: "remainder", left: 4, want: map[string]int{"a": 2, "b": 1, "c": 1}},
+		{name: "all full", left: 20, want: map[string]int{"a": 2, "b": 5, "c": 10}, wantLeft: 3},
+		{name: "from existing grants", grants: map[string]int{"a": 2, "b": 1}, left: 3, want: map[string]int{"a": 2, "b": 3, "c": 1}},
+		{name: "nothing left", left: 0, want: map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grants := make(map[string]int)
+			for node, n := range tt.grants {
+				grants[node] = n
+			}
+			left := waterFill(nodes, grants, tt.left, limit)
+			for node, n := range grants {
+				if n == 0 {
+					delete(grants, node)
+				}
+			}
+			if !reflect.DeepEqual(grants, tt.want) || left != tt.wantLeft {
+				t.Errorf("waterFill() = %v left %d, want %v left %d", grants, left, tt.want, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	tests := []struct {
+		name   string
+		nodes  []k8s.NodeUsage
+		budget int
+		want   map[string]int
+	}{
+		{
+			name:   "demand first",
+			nodes:  []k8s.NodeUsage{{Node: "a", InUse: 2, Demand: 4, MaxCapacity: 10}, {Node: "b", InUse: 1, Demand: 8, MaxCapacity: 10}},
+			budget: 10,
+			want:   map[string]int{"a": 4, "b": 6},
+		},
+		{
+			name:   "rest up to max capacity",
+			nodes:  []k8s.NodeUsage{{Node: "a", InUse: 2, Demand: 4, MaxCapacity: 6}, {Node: "b", InUse: 1, Demand: 2, MaxCapacity: 10}},
+			budget: 14,
+			want:   map[string]int{"a": 6, "b": 8},
+		},
+		{
+			name:   "ports in use are kept over budget",
+			nodes:  []k8s.NodeUsage{{Node: "a", InUse: 5, Demand: 6, MaxCapacity: 10}, {Node: "b", InUse: 4, Demand: 6, MaxCapacity: 10}},
+			budget: 6,
+			want:   map[string]int{"a": 5, "b": 4},
+		},
+		{
+			name:   "demand above max capacity",
+			nodes:  []k8s.NodeUsage{{Node: "a", Demand: 20, MaxCapacity: 3}, {Node: "b", Demand: 20, MaxCapacity: 10}},
+			budget: 100,
+			want:   map[string]int{"a": 3, "b": 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fairShare(tt.nodes, tt.budget); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fairShare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareFree(t *testing.T) {
+	nodes := func(names ...string) []k8s.NodeUsage {
+		var ret []k8s.NodeUsage
+		for _, n := range names {
+			ret = append(ret, k8s.NodeUsage{Node: n})
+		}
+		return ret
+	}
+	tests := []struct {
+		name   string
+		groups map[string][]k8s.NodeUsage
+		free   map[string]int
+		want   map[string]int
+	}{
+		{
+			name:   "separate subnets",
+			groups: map[string][]k8s.NodeUsage{"a": nodes("n1"), "b": nodes("n2")},
+			free:   map[string]int{"a": 10, "b": 5},
+			want:   map[string]int{"a": 10, "b": 5},
+		},
+		{
+			name:   "shared subnet is split by nodes",
+			groups: map[string][]k8s.NodeUsage{"a": nodes("n1", "n2", "n3"), "a,b": nodes("n4")},
+			free:   map[string]int{"a": 10, "b": 5},
+			want:   map[string]int{"a": 8, "a,b": 7},
+		},
+		{
+			name:   "ipv6 subnet is not limited",
+			groups: map[string][]k8s.NodeUsage{"a": nodes("n1"), "v6": nodes("n2")},
+			free:   map[string]int{"a": 10},
+			want:   map[string]int{"a": 10},
+		},
+		{
+			name:   "exhausted",
+			groups: map[string][]k8s.NodeUsage{"a": nodes("n1"), "a,b": nodes("n2")},
+			free:   map[string]int{"a": 0, "b": 0},
+			want:   map[string]int{"a": 0, "a,b": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shareFree(tt.groups, tt.free); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shareFree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaSync(t *testing.T) {
+	server := fake.NewServer()
+	defer server.Close()
+	netID := server.AddNetwork("net", 1450)
+	large, err := server.AddSubnet(netID, "large", "10.0.0.0/28")
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := server.AddSubnet(netID, "small", "10.0.1.0/29")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v6, err := server.AddSubnet(netID, "v6", "fd00::/64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := neutron.NewClientWithAuthOptions(server.AuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := k8s.NewK8sWithClient(k8sfake.NewSimpleClientset(), nil, "")
+
+	usages := []k8s.NodeUsage{
+		{Node: "n1", Subnets: []string{large}, Demand: 100, MaxCapacity: 100},
+		{Node: "n2", Subnets: []string{large, small}, Demand: 100, MaxCapacity: 100},
+		{Node: "n3", Subnets: []string{large, v6}, Demand: 100, MaxCapacity: 100},
+		{Node: "n4", Subnets: []string{v6}, Demand: 100, MaxCapacity: 100},
+	}
+	for _, u := range usages {
+		if err = kc.ReportNodeUsage(u, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = NewQuotaController(kc, client, time.Minute, 0).Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	quotas := make(map[string]int)
+	for _, u := range usages {
+		q, ok, err := kc.GetNodeQuota(u.Node)
+		if err != nil || !ok {
+			t.Fatalf("quota of %s: %v %v", u.Node, ok, err)
+		}
+		quotas[u.Node] = q.Capacity
+	}
+	// 10.0.0.0/28 has 13 addresses and 10.0.1.0/29 has 5 besides network, gateway and broadcast
+	if total := quotas["n1"] + quotas["n2"] + quotas["n3"]; total != 13+5 {
+		t.Errorf("quotas %v grant %d addresses of ipv4 subnets, want %d", quotas, total, 13+5)
+	}
+	if quotas["n2"] < 5 {
+		t.Errorf("quota of n2 = %d, want the small subnet granted to it", quotas["n2"])
+	}
+	if quotas["n4"] != 100 {
+		t.Errorf("quota of node with only ipv6 subnet = %d, want max capacity", quotas["n4"])
+	}
+}
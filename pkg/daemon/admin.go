@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/pool"
+	"github.com/rubble/pkg/rpc"
+)
+
+// adminServer serve rubblectl to inspect and manipulate pools of node
+type adminServer struct {
+	daemon *daemonServer
+
+	rpc.UnimplementedRubbleAdminServer
+}
+
+func newAdminServer(daemon *daemonServer) rpc.RubbleAdminServer {
+	return &adminServer{daemon: daemon}
+}
+
+// podsOfPorts return pods using or reserving ports in db, keyed by port id
+func (a *adminServer) podsOfPorts() (map[string]string, error) {
+	objs, err := a.daemon.resourceDB.List()
+	if err != nil {
+		return nil, fmt.Errorf("error list resource relation db with error: %w", err)
+	}
+	pods := make(map[string]string)
+	for _, obj := range objs {
+		res := obj.(ipam.PodResources)
+		if res.PodInfo == nil {
+			continue
+		}
+		for _, item := range res.Resources {
+			pods[item.ID] = res.PodInfo.PodInfoKey()
+		}
+	}
+	return pods, nil
+}
+
+func (a *adminServer) ListPorts(ctx context.Context, r *rpc.ListPortsRequest) (*rpc.ListPortsReply, error) {
+	switch r.State {
+	case "", ipam.PortStateInUse, ipam.PortStateIdle, ipam.PortStateReserved:
+	default:
+		return nil, fmt.Errorf("unknown port state %q", r.State)
+	}
+	pods, err := a.podsOfPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &rpc.ListPortsReply{}
+	for _, p := range a.daemon.portManager.Ports() {
+		if (len(r.Pool) > 0 && p.Pool != r.Pool) || (len(r.State) > 0 && p.State != r.State) {
+			continue
+		}
+		info := &rpc.PortInfo{
+			ID:       p.ID,
+			Pool:     p.Pool,
+			State:    p.State,
+			IP:       &rpc.IPSet{IPv4: p.IPv4, IPv6: p.IPv6},
+			SubnetID: p.SubnetID,
+			IPPool:   p.IPPool,
+		}
+		if !p.ReservedUntil.IsZero() {
+			info.ReservedUntil = p.ReservedUntil.Unix()
+		}
+		// idle ports may still be recorded for pods until gc cleans records with stick time expired
+		if p.State != ipam.PortStateIdle {
+			info.Pod = pods[p.ID]
+		}
+		reply.Ports = append(reply.Ports, info)
+	}
+	return reply, nil
+}
+
+// ReleasePort dispose port in use and remove it from record of the pod in db. it is refused while the pod
+// still exists unless force, the port is deleted rather than put back to pool since pod may still use it
+func (a *adminServer) ReleasePort(ctx context.Context, r *rpc.ReleasePortRequest) (*rpc.ReleasePortReply, error) {
+	logger.Infof("admin: release port %s, force %v", r.PortID, r.Force)
+	s := a.daemon
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	objs, err := s.resourceDB.List()
+	if err != nil {
+		return nil, fmt.Errorf("error list resource relation db with error: %w", err)
+	}
+	var owners []ipam.PodResources
+	for _, obj := range objs {
+		res := obj.(ipam.PodResources)
+		for _, item := range res.Resources {
+			if item.ID == r.PortID {
+				owners = append(owners, res)
+				break
+			}
+		}
+	}
+	if !r.Force {
+		for _, res := range owners {
+			if res.PodInfo == nil {
+				continue
+			}
+			exists, err := s.k8s.PodExists(res.PodInfo.Namespace, res.PodInfo.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check pod %s with error: %w", res.PodInfo.PodInfoKey(), err)
+			}
+			if exists {
+				return nil, fmt.Errorf("port %s is used by pod %s which still exists, use --force to release it anyway",
+					r.PortID, res.PodInfo.PodInfoKey())
+			}
+		}
+	}
+
+	err = s.portManager.DisposePort(r.PortID)
+	if errors.Is(err, pool.ErrNotFound) {
+		return nil, fmt.Errorf("port %s is not managed by this node", r.PortID)
+	}
+	if errors.Is(err, pool.ErrInvalidState) {
+		return nil, fmt.Errorf("port %s is not in use by pod: %w", r.PortID, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to release port %s with error: %w", r.PortID, err)
+	}
+
+	reply := &rpc.ReleasePortReply{}
+	for _, res := range owners {
+		var left []ipam.ResourceItem
+		for _, item := range res.Resources {
+			if item.ID != r.PortID {
+				left = append(left, item)
+			}
+		}
+		if res.PodInfo == nil {
+			logger.Warnf("admin: port %s is recorded without pod info, the record is kept in db", r.PortID)
+			continue
+		}
+		key := res.PodInfo.PodInfoKey()
+		reply.Pod = key
+		if len(left) == 0 {
+			err = s.resourceDB.Delete(key)
+		} else {
+			res.Resources = left
+			err = s.resourceDB.Put(key, res)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update resource of pod %s in db with error: %w", key, err)
+		}
+	}
+	return reply, nil
+}
+
+func (a *adminServer) DrainPool(ctx context.Context, r *rpc.DrainPoolRequest) (*rpc.DrainPoolReply, error) {
+	logger.Infof("admin: drain pool %q, force %v", r.Pool, r.Force)
+	disposed, err := a.daemon.portManager.DrainPool(r.Pool, r.Force)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.DrainPoolReply{Disposed: int32(disposed)}, nil
+}
+
+// Resync run garbage collection and security group sync at once instead of waiting for gc period
+func (a *adminServer) Resync(ctx context.Context, r *rpc.ResyncRequest) (*rpc.ResyncReply, error) {
+	logger.Infof("admin: resync with neutron")
+	if err := a.daemon.gc(); err != nil {
+		return nil, err
+	}
+	if err := a.daemon.syncSecurityGroups(); err != nil {
+		return nil, err
+	}
+	return &rpc.ResyncReply{Ports: int32(len(a.daemon.portManager.Ports()))}, nil
+}
+
+func (a *adminServer) DumpPodPorts(ctx context.Context, r *rpc.DumpPodPortsRequest) (*rpc.DumpPodPortsReply, error) {
+	objs, err := a.daemon.resourceDB.List()
+	if err != nil {
+		return nil, fmt.Errorf("error list resource relation db with error: %w", err)
+	}
+	reply := &rpc.DumpPodPortsReply{}
+	for _, obj := range objs {
+		res := obj.(ipam.PodResources)
+		value, err := json.Marshal(res)
+		if err != nil {
+			return nil, err
+		}
+		// records without pod info are skipped by gc, dump them with empty key for inspection
+		key := ""
+		if res.PodInfo != nil {
+			key = res.PodInfo.PodInfoKey()
+		}
+		reply.Entries = append(reply.Entries, &rpc.PodPortsEntry{Key: key, Value: string(value)})
+	}
+	sort.Slice(reply.Entries, func(i, j int) bool {
+		return reply.Entries[i].Key < reply.Entries[j].Key
+	})
+	return reply, nil
+}
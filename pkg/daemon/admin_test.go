@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/rpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func inUsePort(t *testing.T, h *Harness, pod string) string {
+	t.Helper()
+	reply, err := h.Admin.ListPorts(context.Background(), &rpc.ListPortsRequest{State: ipam.PortStateInUse})
+	if err != nil {
+		t.Fatalf("list ports: %v", err)
+	}
+	for _, p := range reply.Ports {
+		if p.Pod == pod {
+			return p.ID
+		}
+	}
+	t.Fatalf("no port in use by %s", pod)
+	return ""
+}
+
+func portExists(h *Harness, id string) bool {
+	for _, p := range h.Neutron.Ports() {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReleasePort(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	for _, pod := range []string{"p0", "p1"} {
+		if _, err := allocate(ctx, h, pod, "c-"+pod); err != nil {
+			t.Fatalf("allocate %s: %v", pod, err)
+		}
+	}
+	p0 := inUsePort(t, h, "default/p0")
+	p1 := inUsePort(t, h, "default/p1")
+
+	if _, err := h.Admin.ReleasePort(ctx, &rpc.ReleasePortRequest{PortID: p0}); err == nil {
+		t.Fatalf("release port of running pod without force succeeded")
+	}
+	if !portExists(h, p0) || portsInState(t, h, ipam.PortStateInUse) != 2 {
+		t.Fatalf("port %s is changed by refused release", p0)
+	}
+
+	if err := h.KubeClient.CoreV1().Pods("default").Delete(ctx, "p0", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := h.Admin.ReleasePort(ctx, &rpc.ReleasePortRequest{PortID: p0})
+	if err != nil {
+		t.Fatalf("release port of deleted pod: %v", err)
+	}
+	if reply.Pod != "default/p0" {
+		t.Errorf("released from pod %q, want default/p0", reply.Pod)
+	}
+
+	reply, err = h.Admin.ReleasePort(ctx, &rpc.ReleasePortRequest{PortID: p1, Force: true})
+	if err != nil {
+		t.Fatalf("force release: %v", err)
+	}
+	if reply.Pod != "default/p1" {
+		t.Errorf("released from pod %q, want default/p1", reply.Pod)
+	}
+
+	// released ports are deleted instead of going back to pool where other pods could take them
+	ports, err := h.Admin.ListPorts(ctx, &rpc.ListPortsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ports.Ports {
+		if p.ID == p0 || p.ID == p1 {
+			t.Errorf("released port %s is still in pool as %s", p.ID, p.State)
+		}
+	}
+	for _, id := range []string{p0, p1} {
+		if portExists(h, id) {
+			t.Errorf("released port %s is not deleted", id)
+		}
+	}
+	dump, err := h.Admin.DumpPodPorts(ctx, &rpc.DumpPodPortsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Entries) != 0 {
+		t.Errorf("records left in db: %v", dump.Entries)
+	}
+}
+
+func TestAdminRecordWithoutPodInfo(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(1)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	if _, err := allocate(ctx, h, "p0", "c0"); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	id := inUsePort(t, h, "default/p0")
+	s := h.Server.(*daemonServer)
+	orphan := ipam.PodResources{Resources: []ipam.ResourceItem{{Type: "port", ID: id}}}
+	if err := s.resourceDB.Put("orphan", orphan); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Admin.ListPorts(ctx, &rpc.ListPortsRequest{}); err != nil {
+		t.Errorf("list ports: %v", err)
+	}
+	dump, err := h.Admin.DumpPodPorts(ctx, &rpc.DumpPodPortsRequest{})
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	if len(dump.Entries) != 2 {
+		t.Errorf("dump entries = %d, want 2", len(dump.Entries))
+	}
+	if _, err := h.Admin.ReleasePort(ctx, &rpc.ReleasePortRequest{PortID: id, Force: true}); err != nil {
+		t.Errorf("release: %v", err)
+	}
+}
@@ -3,14 +3,20 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/rubble/pkg/utils"
 	"io/ioutil"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rubble/pkg/ipam"
 	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/metrics"
 	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/pool"
 	"github.com/rubble/pkg/rpc"
 	"github.com/rubble/pkg/storage"
 )
@@ -27,7 +33,23 @@ type daemonServer struct {
 	neutronClient *neutron.Client
 
 	resourceDB  storage.Storage
+	journal     *pool.Journal
 	portManager ipam.ResourceManager
+	// ipType tell cni how port is attached to pod, TypeVPCENI if ports are attached to node as interfaces,
+	// enableTrunking if ports are subports of trunk of node
+	ipType         rpc.IPType
+	enableTrunking bool
+
+	gcPeriod time.Duration
+	// stopCh stop background loops of daemon, loops wait for them to exit
+	stopCh chan struct{}
+	loops  sync.WaitGroup
+	// inflight are AllocateIP and ReleaseIP being handled, they are waited on shutdown
+	inflight sync.WaitGroup
+	// gcLock guard records of pods in db against gc, allocating count AllocateIP between reading and
+	// writing record, gc keeps ports in use without record while it is not zero
+	gcLock     sync.RWMutex
+	allocating int32
 
 	rpc.UnimplementedRubbleBackendServer
 }
@@ -45,6 +67,38 @@ func (s *daemonServer) getPodResource(key string) (ipam.PodResources, error) {
 	return ipam.PodResources{}, err
 }
 
+// allocatedPort return port recorded for pod if it is still in use
+func (s *daemonServer) allocatedPort(res *ipam.PodResources) (*ipam.PortResource, bool) {
+	if !res.Active() {
+		return nil, false
+	}
+	items := res.GetResourceItemByType(utils.ResourceTypeMultipleIP)
+	if len(items) != 1 {
+		return nil, false
+	}
+	port, err := s.portManager.Get(items[0].ID)
+	if err != nil {
+		return nil, false
+	}
+	return port.(*ipam.PortResource), true
+}
+
+func (s *daemonServer) allocateReply(port *ipam.PortResource) (*rpc.AllocateIPReply, error) {
+	conf, err := ipam.NetConfFromPort(port)
+	if err != nil {
+		logger.Errorf("failed to generate net config with error: %s", err)
+		return nil, err
+	}
+	return &rpc.AllocateIPReply{
+		Success:        true,
+		IPType:         s.ipType,
+		IPv4:           len(conf[0].BasicInfo.PodIP.IPv4) > 0,
+		IPv6:           len(conf[0].BasicInfo.PodIP.IPv6) > 0,
+		NetConfs:       conf,
+		EnableTrunking: s.enableTrunking,
+	}, nil
+}
+
 func (s *daemonServer) allocatePortIP(ctx *ipam.ResourceContext, old *ipam.PodResources) (*ipam.PortResource, error) {
 	oldRes := old.GetResourceItemByType(utils.ResourceTypeMultipleIP)
 	logger.Infof("@@@@@@@@@@@@@@@@ what is old resource for %v", oldRes)
@@ -66,8 +120,41 @@ func (s *daemonServer) allocatePortIP(ctx *ipam.ResourceContext, old *ipam.PodRe
 	return res.(*ipam.PortResource), nil
 }
 
-func (s *daemonServer) AllocateIP(ctx context.Context, r *rpc.AllocateIPRequest) (*rpc.AllocateIPReply, error) {
+// allocatedSandbox return port still in use by pod for ADD retried for the same sandbox or for a new sandbox
+// of pod, e.g. after node reboot, record of the new sandbox is updated. otherwise nil port is returned with
+// the record and allocating is counted, caller decrements it after recording the port allocated
+func (s *daemonServer) allocatedSandbox(podInfo *k8s.PodInfo, r *rpc.AllocateIPRequest) (*ipam.PortResource, ipam.PodResources, error) {
+	s.gcLock.RLock()
+	defer s.gcLock.RUnlock()
+
+	oldRes, err := s.getPodResource(podInfo.PodInfoKey())
+	if err != nil {
+		return nil, oldRes, fmt.Errorf("failed to get pod resources from db for pod %s with error: %w", podInfo.PodInfoKey(), err)
+	}
+	port, ok := s.allocatedPort(&oldRes)
+	if !ok {
+		// neutron is requested without the lock, so slow requests do not block gc and admin
+		atomic.AddInt32(&s.allocating, 1)
+		return nil, oldRes, nil
+	}
+	if oldRes.ContainerID == r.K8SPodInfraContainerId {
+		logger.Infof("repeated allocation of sandbox %s of pod %s, return port %s", r.K8SPodInfraContainerId, podInfo.PodInfoKey(), port.GetResourceId())
+		return port, oldRes, nil
+	}
+	logger.Infof("sandbox %s of pod %s replaces sandbox %s, keep port %s", r.K8SPodInfraContainerId, podInfo.PodInfoKey(), oldRes.ContainerID, port.GetResourceId())
+	oldRes.ContainerID, oldRes.NetNS, oldRes.IfName = r.K8SPodInfraContainerId, r.Netns, r.IfName
+	if err = s.resourceDB.Put(podInfo.PodInfoKey(), oldRes); err != nil {
+		return nil, oldRes, fmt.Errorf("error put resource into store with error: %w", err)
+	}
+	return port, oldRes, nil
+}
+
+func (s *daemonServer) AllocateIP(ctx context.Context, r *rpc.AllocateIPRequest) (reply *rpc.AllocateIPReply, err error) {
 	logger.Infof("********Do Allocate IP with request %+v ********", r)
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+	start := time.Now()
+	defer func() { observeRPC("AllocateIP", start, err) }()
 
 	podName := fmt.Sprintf("%s/%s", r.K8SPodNamespace, r.K8SPodName)
 	logger.WithFields(map[string]interface{}{
@@ -80,24 +167,34 @@ func (s *daemonServer) AllocateIP(ctx context.Context, r *rpc.AllocateIPRequest)
 	// 1. get pod Info
 	podInfo, pod, err := s.k8s.GetPod(r.K8SPodNamespace, r.K8SPodName)
 	if err != nil {
-		return nil, fmt.Errorf("error get pod info for: %+v", err)
+		return nil, fmt.Errorf("error get pod info for: %w", err)
 	}
 	logger.Infof("********Pod is %s ******", podInfo)
 
-	// 2. Find old resource info
-	oldRes, err := s.getPodResource(podInfo.PodInfoKey())
+	// 2. Find old resource info, port still in use by pod is returned for ADD retried
+	port, oldRes, err := s.allocatedSandbox(podInfo, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pod resources from db for pod %s with error: %w", podInfo.PodInfoKey(), err)
+		return nil, err
+	}
+	if port != nil {
+		return s.allocateReply(port)
+	}
+	defer atomic.AddInt32(&s.allocating, -1)
+
+	ns, err := s.k8s.GetNamespace(r.K8SPodNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("error get namespace %s with error: %w", r.K8SPodNamespace, err)
 	}
 
 	// 3. Allocate network resource for pod
 	resContext := &ipam.ResourceContext{
-		Context: ctx,
-		PodInfo: podInfo,
-		Pod:     pod,
+		Context:   ctx,
+		PodInfo:   podInfo,
+		Pod:       pod,
+		Namespace: ns,
 	}
 
-	port, err := s.allocatePortIP(resContext, &oldRes)
+	port, err = s.allocatePortIP(resContext, &oldRes)
 	if err != nil {
 		return nil, fmt.Errorf("error get allocated port for: %+v, result: %w", podInfo, err)
 	}
@@ -109,24 +206,23 @@ func (s *daemonServer) AllocateIP(ctx context.Context, r *rpc.AllocateIPRequest)
 				Type: port.GetType(),
 			},
 		},
+		SecurityGroups: port.SecurityGroups(),
+		ContainerID:    r.K8SPodInfraContainerId,
+		NetNS:          r.Netns,
+		IfName:         r.IfName,
 	}
 	logger.Infof("$$$$$$$$$$ PUT DB  %+v, %+v", newRes, newRes.PodInfo)
+	s.gcLock.RLock()
 	err = s.resourceDB.Put(podInfo.PodInfoKey(), newRes)
+	s.gcLock.RUnlock()
 	if err != nil {
 		return nil, fmt.Errorf("error put resource into store with error: %w", err)
 	}
 
-	conf, err := ipam.NetConfFromPort(port)
+	allocIPReply, err := s.allocateReply(port)
 	if err != nil {
-		logger.Errorf("failed to generate net config with error: %s", err)
 		return nil, err
 	}
-	allocIPReply := &rpc.AllocateIPReply{
-		Success:  true,
-		IPType:   rpc.IPType_TypeENIMultiIP,
-		IPv4:     true,
-		NetConfs: conf,
-	}
 
 	// 4. grpc connection
 	if ctx.Err() != nil {
@@ -137,13 +233,17 @@ func (s *daemonServer) AllocateIP(ctx context.Context, r *rpc.AllocateIPRequest)
 	return allocIPReply, err
 }
 
-func (s *daemonServer) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (*rpc.ReleaseIPReply, error) {
+func (s *daemonServer) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (reply *rpc.ReleaseIPReply, err error) {
 	logger.Infof("********Do Release IP with request %+v ********", r)
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+	start := time.Now()
+	defer func() { observeRPC("ReleaseIP", start, err) }()
 
 	// 1. get pod Info
 	podInfo, pod, err := s.k8s.GetPod(r.K8SPodNamespace, r.K8SPodName)
 	if err != nil {
-		return nil, fmt.Errorf("error get pod info for: %+v", err)
+		return nil, fmt.Errorf("error get pod info for: %w", err)
 	}
 	logger.Infof("********Pod is %s ******", podInfo)
 
@@ -154,14 +254,27 @@ func (s *daemonServer) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (
 		Pod:     pod,
 	}
 
+	s.gcLock.RLock()
+	defer s.gcLock.RUnlock()
+
 	// 3. Find old resource
 	oldRes, err := s.getPodResource(podInfo.PodInfoKey())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod resources from db for pod %s with error: %w", podInfo.PodInfoKey(), err)
 	}
+	// kubelet may delete an old sandbox after the new one of pod is set up, keep port of the new one
+	if oldRes.Active() && staleSandbox(oldRes.ContainerID, r.K8SPodInfraContainerId) {
+		logger.Infof("release of sandbox %s of pod %s is ignored, pod uses sandbox %s", r.K8SPodInfraContainerId, podInfo.PodInfoKey(), oldRes.ContainerID)
+		return &rpc.ReleaseIPReply{Success: true}, nil
+	}
 
 	for _, res := range oldRes.Resources {
 		err = s.portManager.Release(resContext, res.ID)
+		if errors.Is(err, pool.ErrNotFound) {
+			// port is taken over by other node
+			logger.Infof("port %s of pod %s is not managed by this node any more", res.ID, podInfo.PodInfoKey())
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -171,9 +284,19 @@ func (s *daemonServer) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (
 		if err != nil {
 			return nil, fmt.Errorf("failed to delete key %s with error: %w", podInfo.PodInfoKey(), err)
 		}
+	} else if oldRes.PodInfo != nil {
+		// keep the record for ip stick, gc will delete it after stick time
+		oldRes.ReleasedAt = time.Now()
+		err = s.resourceDB.Put(podInfo.PodInfoKey(), oldRes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update key %s with error: %w", podInfo.PodInfoKey(), err)
+		}
 	}
 
-	reply := &rpc.ReleaseIPReply{
+	logger.Infof("released resources of pod %s, reason: %q", podInfo.PodInfoKey(), r.Reason)
+	metrics.Releases.WithLabelValues(releaseReason(r.Reason)).Inc()
+
+	reply = &rpc.ReleaseIPReply{
 		Success: true,
 	}
 
@@ -186,7 +309,67 @@ func (s *daemonServer) ReleaseIP(ctx context.Context, r *rpc.ReleaseIPRequest) (
 }
 
 func (s *daemonServer) GetIPInfo(ctx context.Context, r *rpc.GetInfoRequest) (*rpc.GetInfoReply, error) {
-	return nil, nil
+	logger.Debugf("get ip info of pod %s/%s, sandbox %s", r.K8SPodNamespace, r.K8SPodName, r.K8SPodInfraContainerId)
+
+	podInfo := &k8s.PodInfo{
+		Name:      r.K8SPodName,
+		Namespace: r.K8SPodNamespace,
+	}
+
+	// 1. Find resource allocated to pod
+	res, err := s.getPodResource(podInfo.PodInfoKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod resources from db for pod %s with error: %w", podInfo.PodInfoKey(), err)
+	}
+	if !res.Active() {
+		return nil, fmt.Errorf("no resource allocated for pod %s", podInfo.PodInfoKey())
+	}
+	if staleSandbox(res.ContainerID, r.K8SPodInfraContainerId) {
+		return nil, fmt.Errorf("sandbox %s is not the one pod %s uses", r.K8SPodInfraContainerId, podInfo.PodInfoKey())
+	}
+	items := res.GetResourceItemByType(utils.ResourceTypeMultipleIP)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no port allocated for pod %s", podInfo.PodInfoKey())
+	}
+
+	// 2. Get port in use by pod
+	port, err := s.portManager.Get(items[0].ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get port %s of pod %s with error: %w", items[0].ID, podInfo.PodInfoKey(), err)
+	}
+
+	conf, err := ipam.NetConfFromPort(port.(*ipam.PortResource))
+	if err != nil {
+		logger.Errorf("failed to generate net config with error: %s", err)
+		return nil, err
+	}
+
+	return &rpc.GetInfoReply{
+		Success:        true,
+		IPType:         s.ipType,
+		IPv4:           len(conf[0].BasicInfo.PodIP.IPv4) > 0,
+		IPv6:           len(conf[0].BasicInfo.PodIP.IPv6) > 0,
+		NetConfs:       conf,
+		EnableTrunking: s.enableTrunking,
+	}, nil
+}
+
+// releaseReason return reason label of metrics, reasons unknown to daemon are counted as other
+func releaseReason(reason string) string {
+	switch reason {
+	case utils.ReleaseReasonDelete, utils.ReleaseReasonRollback:
+		return reason
+	case "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
+
+// staleSandbox check whether request is for a sandbox other than the one recorded, records created
+// before sandboxes are recorded match any sandbox
+func staleSandbox(recorded, requested string) bool {
+	return len(recorded) > 0 && len(requested) > 0 && recorded != requested
 }
 
 func newDaemonServer(kubeConfig, openstackConfig, net, subnet string) (rpc.RubbleBackendServer, error) {
@@ -204,6 +387,7 @@ func newDaemonServer(kubeConfig, openstackConfig, net, subnet string) (rpc.Rubbl
 	if err != nil {
 		return nil, fmt.Errorf("failed read config file with error: %w", err)
 	}
+	neutronService.SetRateLimit(daemonConfig.RateLimit)
 	nodeInfo, err := getNodeInfo(neutronService)
 	if err != nil {
 		return nil, fmt.Errorf("failed get node info with error: %w", err)
@@ -223,7 +407,31 @@ func newDaemonServer(kubeConfig, openstackConfig, net, subnet string) (rpc.Rubbl
 	if err != nil {
 		return nil, fmt.Errorf("error init resource manager storage: %w", err)
 	}
+	journal, err := newPoolJournal(resourceDB)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &daemonServer{
+		kubeConfig:      kubeConfig,
+		openstackConfig: openstackConfig,
+		cniBinPath:      cniBinPath,
+		neutronNet:      net,
+		neutronSubNet:   subnet,
+		k8s:             k8sService,
+		neutronClient:   neutronService,
+		resourceDB:      resourceDB,
+		journal:         journal,
+	}
+	if err = service.init(daemonConfig); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
 
+// init restore port manager from db and neutron ports, then start gc
+func (s *daemonServer) init(daemonConfig *utils.DaemonConfigure) error {
 	filter := &k8s.Filter{
 		Annotations: map[string]string{
 			utils.PodNetworks: "rubble",
@@ -232,41 +440,105 @@ func newDaemonServer(kubeConfig, openstackConfig, net, subnet string) (rpc.Rubbl
 			"vpc-cni": "true",
 		},
 	}
-	pods, err := k8sService.ListLocalPods(filter)
+	pods, err := s.k8s.ListLocalPods(filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list local pods with error: %w", err)
+		return fmt.Errorf("failed to list local pods with error: %w", err)
 	}
 	logger.Infof("Local pods is %+v", pods)
-	podsUsage := getPodsWithoutPort(pods, resourceDB)
+	podsUsage := getPodsWithoutPort(pods, s.resourceDB)
 
-	portsMapping, err := getPortsMapping(podsUsage, resourceDB)
+	portsMapping, err := getPortsMapping(podsUsage, s.resourceDB)
 	if err != nil {
-		return nil, fmt.Errorf("error get ports usage in db storage: %w", err)
+		return fmt.Errorf("error get ports usage in db storage: %w", err)
 	}
 
-	portManager, err := ipam.NewPortResourceManager(daemonConfig, neutronService, portsMapping)
+	switch utils.GetDatapath(daemonConfig.Datapath) {
+	case utils.DatapathIPVlan, utils.DatapathMacvlan:
+		s.ipType = rpc.IPType_TypeENIMultiIP
+	case utils.DatapathENI:
+		s.ipType = rpc.IPType_TypeVPCENI
+	case utils.DatapathTrunk:
+		s.ipType = rpc.IPType_TypeENIMultiIP
+		s.enableTrunking = true
+	default:
+		return fmt.Errorf("unknown datapath %q", daemonConfig.Datapath)
+	}
+
+	portManager, err := ipam.NewPortResourceManager(daemonConfig, s.neutronClient, s.k8s, portsMapping, s.journal)
 	if err != nil {
-		return nil, fmt.Errorf("error init port resource manager: %w", err)
+		return fmt.Errorf("error init port resource manager: %w", err)
+	}
+	s.portManager = portManager
+
+	s.gcPeriod = defaultGCPeriod
+	if daemonConfig.Period > 0 {
+		s.gcPeriod = time.Duration(daemonConfig.Period) * time.Second
 	}
 
-	//(TODO) start gc
-	// service.startGarbageCollectionLoop()
+	s.stopCh = make(chan struct{})
+	warmer := newPoolWarmer(s, daemonConfig.Node.Name)
+	s.loops.Add(2)
 	// gc 处理 daemon boltdb 中记录的 pod 和 port对应关系 不匹配问题
+	go func() {
+		defer s.loops.Done()
+		s.startGarbageCollectionLoop()
+	}()
+	go func() {
+		defer s.loops.Done()
+		warmer.Run(s.stopCh)
+	}()
+	if daemonConfig.ClusterQuota {
+		reporter := &quotaReporter{server: s, nodeName: daemonConfig.Node.Name}
+		s.loops.Add(1)
+		go func() {
+			defer s.loops.Done()
+			reporter.Run(s.stopCh)
+		}()
+	}
+
+	return nil
+}
 
-	service := &daemonServer{
-		kubeConfig:      kubeConfig,
-		openstackConfig: openstackConfig,
-		cniBinPath:      cniBinPath,
-		neutronNet:      net,
-		neutronSubNet:   subnet,
-		k8s:             k8sService,
-		neutronClient:   neutronService,
+// shutdown stop gc and warm-up, wait for rpc in flight, stop pools and close db. rpc server must be
+// stopped before so no new rpc comes in. resources in pools are restored on next start
+func (s *daemonServer) shutdown(ctx context.Context) error {
+	close(s.stopCh)
+	if err := waitGroup(ctx, &s.inflight); err != nil {
+		return fmt.Errorf("rpc still in flight: %w", err)
+	}
+	if err := waitGroup(ctx, &s.loops); err != nil {
+		return fmt.Errorf("gc or warm-up still running: %w", err)
+	}
+	if err := s.portManager.Close(ctx); err != nil {
+		return err
+	}
 
-		resourceDB:  resourceDB,
-		portManager: portManager,
+	pods, err := s.resourceDB.List()
+	if err != nil {
+		return err
 	}
+	logger.Infof("shutdown with %d pods recorded in db", len(pods))
+	if db, ok := s.resourceDB.(interface{ Close() error }); ok {
+		if err = db.Close(); err != nil {
+			return fmt.Errorf("failed to close db with error: %w", err)
+		}
+	}
+	return nil
+}
 
-	return service, nil
+// waitGroup wait for wg until ctx is done
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func getNodeInfo(client *neutron.Client) (*utils.NodeInfo, error) {
@@ -313,6 +585,19 @@ func getConfigFromPath(path string) (*utils.DaemonConfigure, error) {
 	return config, nil
 }
 
+// newPoolJournal create journal of pools in bucket next to pod resources in daemon db
+func newPoolJournal(resourceDB storage.Storage) (*pool.Journal, error) {
+	db, ok := resourceDB.(*storage.DiskStorage)
+	if !ok {
+		return nil, fmt.Errorf("pool journal requires disk storage")
+	}
+	store, err := db.Bucket(utils.PoolJournalName, json.Marshal, pool.DecodeJournalEntry)
+	if err != nil {
+		return nil, fmt.Errorf("error init pool journal storage: %w", err)
+	}
+	return pool.NewJournal(store), nil
+}
+
 func jsonDeserializer(bytes []byte) (interface{}, error) {
 	resourceRel := &ipam.PodResources{}
 	err := json.Unmarshal(bytes, resourceRel)
@@ -340,7 +625,7 @@ func getPortsMapping(podsUsage map[string]*k8s.PodInfo, db storage.Storage) (map
 
 		_, ok := podsUsage[mapping.PodInfo.PodInfoKey()]
 		if !ok {
-			logger.Infof("!!!!!!!!!!!! GC REQUIRED!!!!! pod %s is not running on nodes, but using port %s in db", mapping.PodInfo.PodInfoKey(), mapping.Resources[0].ID)
+			logger.Infof("pod %s is not running on nodes, but using port %s in db, gc will release it", mapping.PodInfo.PodInfoKey(), mapping.Resources[0].ID)
 		}
 
 		for _, port := range mapping.Resources {
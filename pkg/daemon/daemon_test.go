@@ -0,0 +1,564 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/metrics"
+	"github.com/rubble/pkg/neutron/fake"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func runningPods(n int) []runtime.Object {
+	var objs []runtime.Object
+	for i := 0; i < n; i++ {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("p%d", i), Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: HarnessNodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	return objs
+}
+
+func allocate(ctx context.Context, h *Harness, pod, sandbox string) (*rpc.AllocateIPReply, error) {
+	return h.Server.AllocateIP(ctx, &rpc.AllocateIPRequest{
+		K8SPodName:             pod,
+		K8SPodNamespace:        "default",
+		K8SPodInfraContainerId: sandbox,
+	})
+}
+
+func release(ctx context.Context, h *Harness, pod, sandbox string) (*rpc.ReleaseIPReply, error) {
+	return h.Server.ReleaseIP(ctx, &rpc.ReleaseIPRequest{
+		K8SPodName:             pod,
+		K8SPodNamespace:        "default",
+		K8SPodInfraContainerId: sandbox,
+		Reason:                 utils.ReleaseReasonDelete,
+	})
+}
+
+func podIPv4(reply *rpc.AllocateIPReply) string {
+	return reply.NetConfs[0].BasicInfo.PodIP.IPv4
+}
+
+func portsInState(t *testing.T, h *Harness, state string) int {
+	t.Helper()
+	reply, err := h.Admin.ListPorts(context.Background(), &rpc.ListPortsRequest{State: state})
+	if err != nil {
+		t.Fatalf("list ports: %v", err)
+	}
+	return len(reply.Ports)
+}
+
+// rpcCount return count of rpc observed with outcome
+func rpcCount(t *testing.T, method, outcome string) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := metrics.RPCDuration.WithLabelValues(method, outcome).(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestAllocateRelease(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	reply, err := allocate(ctx, h, "p0", "c0")
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	_, cidr, _ := net.ParseCIDR(HarnessSubnetCIDR)
+	if ip := net.ParseIP(podIPv4(reply)); ip == nil || !cidr.Contains(ip) {
+		t.Fatalf("pod ip %q not in subnet %s", podIPv4(reply), HarnessSubnetCIDR)
+	}
+	if reply.NetConfs[0].MTU != HarnessNetworkMTU {
+		t.Errorf("mtu = %d, want %d", reply.NetConfs[0].MTU, HarnessNetworkMTU)
+	}
+
+	again, err := allocate(ctx, h, "p0", "c0")
+	if err != nil {
+		t.Fatalf("allocate again: %v", err)
+	}
+	if podIPv4(again) != podIPv4(reply) {
+		t.Errorf("repeated ADD of sandbox got %s, want %s", podIPv4(again), podIPv4(reply))
+	}
+	other, err := allocate(ctx, h, "p1", "c1")
+	if err != nil {
+		t.Fatalf("allocate p1: %v", err)
+	}
+	if podIPv4(other) == podIPv4(reply) {
+		t.Errorf("pods got the same ip %s", podIPv4(other))
+	}
+
+	info, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: "p0", K8SPodNamespace: "default", K8SPodInfraContainerId: "c0"})
+	if err != nil {
+		t.Fatalf("get ip info: %v", err)
+	}
+	if got := info.NetConfs[0].BasicInfo.PodIP.IPv4; got != podIPv4(reply) {
+		t.Errorf("ip info = %s, want %s", got, podIPv4(reply))
+	}
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 2 {
+		t.Errorf("ports in use = %d, want 2", n)
+	}
+
+	if r, err := release(ctx, h, "p0", "c0"); err != nil || !r.Success {
+		t.Fatalf("release: %v %v", r, err)
+	}
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 1 {
+		t.Errorf("ports in use after release = %d, want 1", n)
+	}
+	// DEL is repeated by kubelet, the second one is not an error
+	if r, err := release(ctx, h, "p0", "c0"); err != nil || !r.Success {
+		t.Fatalf("release again: %v %v", r, err)
+	}
+}
+
+func TestGetIPInfo(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	reply, err := allocate(ctx, h, "p0", "c0")
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	tests := []struct {
+		name    string
+		pod     string
+		sandbox string
+		wantErr bool
+	}{
+		{name: "known sandbox", pod: "p0", sandbox: "c0"},
+		{name: "sandbox not given", pod: "p0"},
+		{name: "unknown sandbox", pod: "p1", sandbox: "c1", wantErr: true},
+		{name: "stale sandbox", pod: "p0", sandbox: "c-old", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: tt.pod, K8SPodNamespace: "default", K8SPodInfraContainerId: tt.sandbox})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got ip info %v, want error", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get ip info: %v", err)
+			}
+			if got := info.NetConfs[0].BasicInfo.PodIP.IPv4; !info.Success || !info.IPv4 || got != podIPv4(reply) {
+				t.Errorf("ip info = %s success %v, want %s", got, info.Success, podIPv4(reply))
+			}
+		})
+	}
+
+	if _, err := release(ctx, h, "p0", "c0"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: "p0", K8SPodNamespace: "default", K8SPodInfraContainerId: "c0"}); err == nil {
+		t.Errorf("get ip info of released pod succeeded")
+	}
+}
+
+func TestAllocateSandboxRetry(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(1)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	if _, err := allocate(ctx, h, "p0", "c0"); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	port := inUsePort(t, h, "default/p0")
+	creates := h.Neutron.RequestCount(http.MethodPost, "/v2.0/ports")
+
+	// ADD retried for the same sandbox and ADD of a new sandbox of the pod get the same port
+	for _, sandbox := range []string{"c0", "c1"} {
+		if _, err := allocate(ctx, h, "p0", sandbox); err != nil {
+			t.Fatalf("allocate sandbox %s: %v", sandbox, err)
+		}
+		if got := inUsePort(t, h, "default/p0"); got != port {
+			t.Errorf("sandbox %s got port %s, want %s", sandbox, got, port)
+		}
+		if n := portsInState(t, h, ipam.PortStateInUse); n != 1 {
+			t.Errorf("ports in use after sandbox %s = %d, want 1", sandbox, n)
+		}
+	}
+	if n := h.Neutron.RequestCount(http.MethodPost, "/v2.0/ports"); n != creates {
+		t.Errorf("ports created by retries = %d, want 0", n-creates)
+	}
+	// record follows the new sandbox, the old one is stale
+	if _, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: "p0", K8SPodNamespace: "default", K8SPodInfraContainerId: "c1"}); err != nil {
+		t.Errorf("ip info of new sandbox: %v", err)
+	}
+	if _, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: "p0", K8SPodNamespace: "default", K8SPodInfraContainerId: "c0"}); err == nil {
+		t.Errorf("ip info of replaced sandbox succeeded")
+	}
+}
+
+func TestSlowAllocationNotBlockGC(t *testing.T) {
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:           5,
+		MaxIdleSize:           2,
+		MinIdleSize:           1,
+		AllowedSecurityGroups: []string{HarnessSecurityGroupName},
+	}
+	h, err := NewHarness(cfg, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p0", Namespace: "default",
+			Annotations: map[string]string{ipam.SecurityGroupsAnnotation: HarnessSecurityGroupName}},
+		Spec:   corev1.PodSpec{NodeName: HarnessNodeName},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	// security group of port is updated after the port is acquired from pool
+	h.Neutron.AddFault(fake.Fault{Method: http.MethodPut, Path: "/v2.0/ports/", Latency: time.Second, Times: 1})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := allocate(context.Background(), h, "p0", "c0")
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for portsInState(t, h, ipam.PortStateInUse) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := h.Server.(*daemonServer).gc(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("gc waited %s for allocation", elapsed)
+	}
+	// port acquired but not recorded yet is kept by gc
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 1 {
+		t.Errorf("ports in use after gc = %d, want 1", n)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	inUsePort(t, h, "default/p0")
+}
+
+func TestAllocateWithNeutronFaults(t *testing.T) {
+	h, err := NewHarness(&utils.DaemonConfigure{MaxPoolSize: 5, MaxIdleSize: 1, MinIdleSize: 0}, runningPods(3)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	tests := []struct {
+		name    string
+		fault   fake.Fault
+		wantErr bool
+	}{
+		{
+			name:  "slow neutron",
+			fault: fake.Fault{Method: http.MethodPost, Path: "/v2.0/ports", Latency: 200 * time.Millisecond, Times: 1},
+		},
+		{
+			name:    "neutron down",
+			fault:   fake.Fault{Method: http.MethodPost, Path: "/v2.0/ports", StatusCode: http.StatusInternalServerError},
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := h.Admin.DrainPool(context.Background(), &rpc.DrainPoolRequest{Force: true}); err != nil {
+				t.Fatalf("drain: %v", err)
+			}
+			h.Neutron.AddFault(tt.fault)
+			defer h.Neutron.ClearFaults()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			pod := fmt.Sprintf("p%d", i)
+			_, err := allocate(ctx, h, pod, "c")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("allocate error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				h.Neutron.ClearFaults()
+				if _, err = allocate(context.Background(), h, pod, "c"); err != nil {
+					t.Fatalf("allocate after neutron recovered: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocateTimeoutKeepsPort(t *testing.T) {
+	h, err := NewHarness(&utils.DaemonConfigure{MaxPoolSize: 5, MaxIdleSize: 1, MinIdleSize: 0}, runningPods(1)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.Neutron.AddFault(fake.Fault{Method: http.MethodPost, Path: "/v2.0/ports", Latency: 300 * time.Millisecond, Times: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err = allocate(ctx, h, "p0", "c"); err == nil {
+		t.Fatal("allocate succeeded before port is created")
+	}
+	// port created by neutron after cni gave up is kept in pool instead of orphaned
+	deadline := time.Now().Add(5 * time.Second)
+	for portsInState(t, h, ipam.PortStateIdle) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	pooled := portsInState(t, h, ipam.PortStateIdle) + portsInState(t, h, ipam.PortStateInUse)
+	if n := len(h.Neutron.Ports()); n == 0 || n != pooled {
+		t.Errorf("ports in neutron %d, in pool %d, want the created port in pool", n, pooled)
+	}
+}
+
+func TestSubnetFailover(t *testing.T) {
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:  12,
+		MaxIdleSize:  2,
+		MinIdleSize:  0,
+		SubnetID:     HarnessSmallSubnetName,
+		Subnets:      []string{HarnessSubnetName},
+		SubnetPolicy: "ordered",
+	}
+	h, err := NewHarness(cfg, runningPods(9)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	_, small, _ := net.ParseCIDR(HarnessSmallSubnetCIDR)
+	_, large, _ := net.ParseCIDR(HarnessSubnetCIDR)
+	seen := make(map[string]bool)
+	inSmall, inLarge := 0, 0
+	for i := 0; i < 9; i++ {
+		reply, err := allocate(ctx, h, fmt.Sprintf("p%d", i), "c")
+		if err != nil {
+			t.Fatalf("allocate p%d: %v", i, err)
+		}
+		ip := podIPv4(reply)
+		if seen[ip] {
+			t.Fatalf("ip %s allocated twice", ip)
+		}
+		seen[ip] = true
+		switch {
+		case small.Contains(net.ParseIP(ip)):
+			inSmall++
+		case large.Contains(net.ParseIP(ip)):
+			inLarge++
+		default:
+			t.Fatalf("ip %s in no subnet", ip)
+		}
+	}
+	if inSmall == 0 || inLarge == 0 {
+		t.Errorf("ips in small subnet %d, in large subnet %d, want both used", inSmall, inLarge)
+	}
+}
+
+func TestBatchSubnetFailover(t *testing.T) {
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:  12,
+		MaxIdleSize:  8,
+		MinIdleSize:  8,
+		SubnetID:     HarnessSmallSubnetName,
+		Subnets:      []string{HarnessSubnetName},
+		SubnetPolicy: "ordered",
+	}
+	h, err := NewHarness(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	_, small, _ := net.ParseCIDR(HarnessSmallSubnetCIDR)
+	inSmall, inLarge := 0, 0
+	for _, port := range h.Neutron.Ports() {
+		if small.Contains(net.ParseIP(port.FixedIPs[0].IPAddress)) {
+			inSmall++
+		} else {
+			inLarge++
+		}
+	}
+	// the small subnet is filled before ports are created from the large one
+	if inSmall+inLarge != 8 || inSmall < 5 || inLarge == 0 {
+		t.Errorf("ports in small subnet %d, in large subnet %d, want small subnet filled and 8 in total", inSmall, inLarge)
+	}
+	// ports are created in bulks instead of one by one after the small subnet is exhausted
+	if n := h.Neutron.RequestCount(http.MethodPost, "/v2.0/ports"); n >= 8 {
+		t.Errorf("create requests = %d, want less than 8", n)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	ips := make(map[string]string)
+	for _, pod := range []string{"p0", "p1"} {
+		reply, err := allocate(ctx, h, pod, "c")
+		if err != nil {
+			t.Fatalf("allocate %s: %v", pod, err)
+		}
+		ips[pod] = podIPv4(reply)
+	}
+	ports := len(h.Neutron.Ports())
+
+	if err = h.Restart(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	for pod, ip := range ips {
+		info, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: pod, K8SPodNamespace: "default", K8SPodInfraContainerId: "c"})
+		if err != nil {
+			t.Fatalf("get ip info of %s after restart: %v", pod, err)
+		}
+		if got := info.NetConfs[0].BasicInfo.PodIP.IPv4; got != ip {
+			t.Errorf("ip of %s after restart = %s, want %s", pod, got, ip)
+		}
+		reply, err := allocate(ctx, h, pod, "c")
+		if err != nil {
+			t.Fatalf("allocate %s after restart: %v", pod, err)
+		}
+		if podIPv4(reply) != ip {
+			t.Errorf("ADD of %s after restart got %s, want %s", pod, podIPv4(reply), ip)
+		}
+	}
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 2 {
+		t.Errorf("ports in use after restart = %d, want 2", n)
+	}
+	if got := len(h.Neutron.Ports()); got < ports {
+		t.Errorf("ports after restart = %d, want at least %d", got, ports)
+	}
+}
+
+func TestSubnetAnnotation(t *testing.T) {
+	pod := func(name, subnet string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{ipam.SubnetAnnotation: subnet}},
+			Spec:       corev1.PodSpec{NodeName: HarnessNodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:       10,
+		MaxIdleSize:       5,
+		MinIdleSize:       2,
+		AnnotationSubnets: []string{HarnessSmallSubnetName},
+		SubnetPoolSize:    2,
+	}
+	h, err := NewHarness(cfg, pod("a0", HarnessSmallSubnetName), pod("a1", HarnessSmallSubnetName),
+		pod("a2", HarnessSmallSubnetName), pod("b0", HarnessSubnetName), pod("c0", "not-allowed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, small, _ := net.ParseCIDR(HarnessSmallSubnetCIDR)
+	_, large, _ := net.ParseCIDR(HarnessSubnetCIDR)
+	tests := []struct {
+		pod     string
+		cidr    *net.IPNet
+		wantErr bool
+	}{
+		{pod: "a0", cidr: small},
+		{pod: "a1", cidr: small},
+		// pool of annotation subnet holds at most subnet_pool_size ports
+		{pod: "a2", wantErr: true},
+		// subnet of node is allocated from default pool
+		{pod: "b0", cidr: large},
+		{pod: "c0", wantErr: true},
+	}
+	for _, tt := range tests {
+		reply, err := allocate(ctx, h, tt.pod, "c")
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("allocate %s error = %v, want error %v", tt.pod, err, tt.wantErr)
+		}
+		if err == nil && !tt.cidr.Contains(net.ParseIP(podIPv4(reply))) {
+			t.Errorf("ip of %s = %s, want in %s", tt.pod, podIPv4(reply), tt.cidr)
+		}
+	}
+}
+
+func TestRPCMetrics(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(4)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	// metrics are global, changes made by this test are checked
+	allocated := rpcCount(t, "AllocateIP", metrics.OutcomeSuccess)
+	allocateFailed := rpcCount(t, "AllocateIP", metrics.OutcomeError)
+	released := rpcCount(t, "ReleaseIP", metrics.OutcomeSuccess)
+	notFound := testutil.ToFloat64(metrics.RPCErrors.WithLabelValues("AllocateIP", "kubernetes"))
+	reasons := []string{utils.ReleaseReasonDelete, utils.ReleaseReasonRollback, "unknown", "other"}
+	releases := make(map[string]float64)
+	for _, reason := range reasons {
+		releases[reason] = testutil.ToFloat64(metrics.Releases.WithLabelValues(reason))
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := allocate(ctx, h, fmt.Sprintf("p%d", i), fmt.Sprintf("c%d", i)); err != nil {
+			t.Fatalf("allocate p%d: %v", i, err)
+		}
+	}
+	if _, err := allocate(ctx, h, "missing", "c"); err == nil {
+		t.Fatalf("allocate for pod not found succeeded")
+	}
+	for i, reason := range []string{utils.ReleaseReasonDelete, utils.ReleaseReasonRollback, "", "unexpected"} {
+		if _, err := h.Server.ReleaseIP(ctx, &rpc.ReleaseIPRequest{K8SPodName: fmt.Sprintf("p%d", i),
+			K8SPodNamespace: "default", K8SPodInfraContainerId: fmt.Sprintf("c%d", i), Reason: reason}); err != nil {
+			t.Fatalf("release p%d: %v", i, err)
+		}
+	}
+
+	if n := rpcCount(t, "AllocateIP", metrics.OutcomeSuccess) - allocated; n != 4 {
+		t.Errorf("successful AllocateIP observed %d, want 4", n)
+	}
+	if n := rpcCount(t, "AllocateIP", metrics.OutcomeError) - allocateFailed; n != 1 {
+		t.Errorf("failed AllocateIP observed %d, want 1", n)
+	}
+	if n := testutil.ToFloat64(metrics.RPCErrors.WithLabelValues("AllocateIP", "kubernetes")) - notFound; n != 1 {
+		t.Errorf("AllocateIP errors caused by kubernetes = %v, want 1", n)
+	}
+	if n := rpcCount(t, "ReleaseIP", metrics.OutcomeSuccess) - released; n != 4 {
+		t.Errorf("successful ReleaseIP observed %d, want 4", n)
+	}
+	for _, reason := range reasons {
+		if n := testutil.ToFloat64(metrics.Releases.WithLabelValues(reason)) - releases[reason]; n != 1 {
+			t.Errorf("releases with reason %s = %v, want 1", reason, n)
+		}
+	}
+}
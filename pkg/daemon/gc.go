@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const defaultGCPeriod = 60 * time.Second
+
+func (s *daemonServer) startGarbageCollectionLoop() {
+	ticker := time.NewTicker(s.gcPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+		if err := s.gc(); err != nil {
+			logger.Errorf("error garbage collection: %v", err)
+		}
+		if err := s.syncSecurityGroups(); err != nil {
+			logger.Errorf("error sync security groups: %v", err)
+		}
+	}
+}
+
+// gc reconcile pod and port relationship recorded in db with local pods,
+// ports used by pods not running on this node are released back to pool
+func (s *daemonServer) gc() error {
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	// list pods after taking the lock, so every pod recorded in db is in the list if it is running.
+	// all pods on this node are listed, pods without vpc-cni label may also use rubble
+	pods, err := s.k8s.ListLocalPods(&k8s.Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to list local pods with error: %w", err)
+	}
+	localPods := make(map[string]*k8s.PodInfo)
+	for _, p := range pods {
+		localPods[p.PodInfoKey()] = p
+	}
+
+	resObjList, err := s.resourceDB.List()
+	if err != nil {
+		return fmt.Errorf("error list resource relation db with error: %w", err)
+	}
+
+	inUseSet := make(map[string]interface{})
+	var deadRes []ipam.PodResources
+	for _, obj := range resObjList {
+		res := obj.(ipam.PodResources)
+		if res.PodInfo == nil {
+			logger.Warnf("gc: skip resource record without pod info: %+v", res.Resources)
+			continue
+		}
+		if _, ok := localPods[res.PodInfo.PodInfoKey()]; ok {
+			for _, item := range res.Resources {
+				inUseSet[item.ID] = res.PodInfo
+			}
+			continue
+		}
+		deadRes = append(deadRes, res)
+	}
+
+	expireSet := make(map[string]interface{})
+	for _, res := range deadRes {
+		key := res.PodInfo.PodInfoKey()
+		if !res.ReleasedAt.IsZero() {
+			// resources already released, wait for stick time to clean the record
+			if time.Now().After(res.ReleasedAt.Add(res.PodInfo.IpStickTime)) {
+				logger.Infof("gc: ip stick time of pod %s expired, delete it from db", key)
+				if err = s.resourceDB.Delete(key); err != nil {
+					return fmt.Errorf("failed to delete key %s with error: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		for _, item := range res.Resources {
+			// port may be reused by another running pod
+			if _, ok := inUseSet[item.ID]; ok {
+				continue
+			}
+			logger.Infof("gc: pod %s is not running on node, release port %s", key, item.ID)
+			expireSet[item.ID] = res.PodInfo
+		}
+
+		if res.PodInfo.IpStickTime == 0 {
+			err = s.resourceDB.Delete(key)
+		} else {
+			res.ReleasedAt = time.Now()
+			err = s.resourceDB.Put(key, res)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update resource of pod %s in db with error: %w", key, err)
+		}
+	}
+
+	// ports acquired by allocations in flight are not recorded yet, they are checked by next gc
+	if atomic.LoadInt32(&s.allocating) > 0 {
+		for _, p := range s.portManager.Ports() {
+			if p.State != ipam.PortStateInUse {
+				continue
+			}
+			if _, ok := inUseSet[p.ID]; ok {
+				continue
+			}
+			if _, ok := expireSet[p.ID]; !ok {
+				inUseSet[p.ID] = nil
+			}
+		}
+	}
+
+	return s.portManager.GarbageCollection(inUseSet, expireSet)
+}
+
+// syncSecurityGroups update security groups of ports used by local pods, network policy controller
+// and users change them by annotations of pods and namespaces after pods are created
+func (s *daemonServer) syncSecurityGroups() error {
+	pods, err := s.k8s.ListLocalPodObjects(&k8s.Filter{})
+	if err != nil {
+		return fmt.Errorf("failed to list local pods with error: %w", err)
+	}
+
+	// snapshot records under the lock, namespaces and neutron are requested without blocking allocations
+	type podRecord struct {
+		key string
+		pod *corev1.Pod
+		res ipam.PodResources
+	}
+	var records []podRecord
+	s.gcLock.RLock()
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		obj, err := s.resourceDB.Get(key)
+		if err != nil {
+			continue
+		}
+		res := obj.(ipam.PodResources)
+		if res.PodInfo == nil || !res.ReleasedAt.IsZero() {
+			continue
+		}
+		records = append(records, podRecord{key: key, pod: pod, res: res})
+	}
+	s.gcLock.RUnlock()
+
+	namespaces := make(map[string]*corev1.Namespace)
+	for _, r := range records {
+		ns, ok := namespaces[r.pod.Namespace]
+		if !ok {
+			if ns, err = s.k8s.GetNamespace(r.pod.Namespace); err != nil {
+				logger.Errorf("failed to get namespace %s with error: %s", r.pod.Namespace, err)
+				continue
+			}
+			namespaces[r.pod.Namespace] = ns
+		}
+		ctx := &ipam.ResourceContext{
+			Context:   context.Background(),
+			PodInfo:   r.res.PodInfo,
+			Pod:       r.pod,
+			Namespace: ns,
+		}
+
+		var sgs []string
+		for _, item := range r.res.Resources {
+			if sgs, err = s.portManager.UpdateSecurityGroups(ctx, item.ID); err != nil {
+				logger.Errorf("failed to update security groups of port %s of pod %s with error: %s", item.ID, r.key, err)
+			}
+		}
+		if err != nil || equalStrings(sgs, r.res.SecurityGroups) {
+			continue
+		}
+		if err = s.updateRecordSecurityGroups(r.key, r.res, sgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateRecordSecurityGroups record security groups of pod if its ports are not changed since snapshot,
+// a port released and reused meanwhile gets security groups of its new pod in allocation or next sync
+func (s *daemonServer) updateRecordSecurityGroups(key string, snapshot ipam.PodResources, sgs []string) error {
+	s.gcLock.Lock()
+	defer s.gcLock.Unlock()
+
+	obj, err := s.resourceDB.Get(key)
+	if err != nil {
+		return nil
+	}
+	res := obj.(ipam.PodResources)
+	if !res.ReleasedAt.IsZero() || !sameResources(res.Resources, snapshot.Resources) {
+		return nil
+	}
+	res.SecurityGroups = sgs
+	if err = s.resourceDB.Put(key, res); err != nil {
+		return fmt.Errorf("failed to update resource of pod %s in db with error: %w", key, err)
+	}
+	return nil
+}
+
+func sameResources(a, b []ipam.ResourceItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubble/pkg/ipam"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGarbageCollection(t *testing.T) {
+	h, err := NewHarness(nil, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+	for _, pod := range []string{"p0", "p1"} {
+		if _, err = allocate(ctx, h, pod, "c"); err != nil {
+			t.Fatalf("allocate %s: %v", pod, err)
+		}
+	}
+	s := h.Server.(*daemonServer)
+	// record without pod info written by an old version must not break gc
+	if err = s.resourceDB.Put("default/broken", ipam.PodResources{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = h.KubeClient.CoreV1().Pods("default").Delete(ctx, "p0", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.gc(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 1 {
+		t.Errorf("ports in use after gc = %d, want 1", n)
+	}
+	if _, err = s.resourceDB.Get("default/p1"); err != nil {
+		t.Errorf("record of running pod p1 removed by gc: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/neutron/fake"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/storage"
+	"github.com/rubble/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	HarnessNodeName   = "harness-node"
+	HarnessVMUUID     = "0b8e1b7e-6c2f-4d3a-9b1e-3f7a2c4d5e6f"
+	HarnessNetName    = "harness_net"
+	HarnessSubnetName = "harness_net__subnet"
+	HarnessSubnetCIDR = "192.168.100.0/24"
+	// HarnessSmallSubnetName is a subnet with few addresses, set it as DaemonConfigure.SubnetID
+	// and HarnessSubnetName in DaemonConfigure.Subnets to run subnet failover
+	HarnessSmallSubnetName = "harness_net__subnet_small"
+	HarnessSmallSubnetCIDR = "192.168.101.0/29"
+	// set DaemonConfigure.IPv6SubnetID to HarnessIPv6SubnetName to run dual stack
+	HarnessIPv6SubnetName = "harness_net__subnet_v6"
+	HarnessIPv6SubnetCIDR = "fd00:100::/64"
+	HarnessNetworkMTU     = 1450
+	// set HarnessSecurityGroupName in DaemonConfigure.SecurityGroups to create ports with security group
+	HarnessSecurityGroupName = "harness_sg"
+	// HarnessTrunkPortName is port of vm and parent of trunk created for trunk datapath
+	HarnessTrunkPortName = "harness_vm_port"
+)
+
+// Harness runs a daemon server against the fake neutron server and a fake kubernetes clientset,
+// so AllocateIP/ReleaseIP scenarios can run without an OpenStack cloud
+type Harness struct {
+	Server     rpc.RubbleBackendServer
+	Admin      rpc.RubbleAdminServer
+	Neutron    *fake.Server
+	KubeClient kubernetes.Interface
+	// DynamicClient serves rubble crds, e.g. RubbleIPPool
+	DynamicClient dynamic.Interface
+	Config        *utils.DaemonConfigure
+	NetworkID     string
+	SubnetID      string
+	// TrunkID is trunk of vm for trunk datapath
+	TrunkID string
+	// SecurityGroupID is id of HarnessSecurityGroupName
+	SecurityGroupID string
+
+	dir string
+}
+
+// NewHarness create the fake neutron network and subnet, prepare pods and unstructured rubble crds in fake kubernetes
+// and start daemon server. config is optional, pool sizes and subnets in it are kept and other fields are filled by harness
+func NewHarness(config *utils.DaemonConfigure, objects ...runtime.Object) (*Harness, error) {
+	neutronServer := fake.NewServer()
+	h := &Harness{
+		Neutron: neutronServer,
+	}
+	h.NetworkID = neutronServer.AddNetwork(HarnessNetName, HarnessNetworkMTU)
+	subnetID, err := neutronServer.AddSubnet(h.NetworkID, HarnessSubnetName, HarnessSubnetCIDR)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.SubnetID = subnetID
+	if _, err = neutronServer.AddSubnet(h.NetworkID, HarnessIPv6SubnetName, HarnessIPv6SubnetCIDR); err != nil {
+		h.Close()
+		return nil, err
+	}
+	if _, err = neutronServer.AddSubnet(h.NetworkID, HarnessSmallSubnetName, HarnessSmallSubnetCIDR); err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	h.SecurityGroupID = neutronServer.AddSecurityGroup(HarnessSecurityGroupName)
+
+	if config == nil {
+		config = &utils.DaemonConfigure{
+			MaxPoolSize: 10,
+			MaxIdleSize: 5,
+			MinIdleSize: 2,
+		}
+	}
+	config.NetID = h.NetworkID
+	if len(config.SubnetID) == 0 {
+		config.SubnetID = h.SubnetID
+	}
+	config.NodeName = HarnessNodeName
+	config.Node = &utils.NodeInfo{
+		UUID:      HarnessVMUUID,
+		Name:      HarnessNodeName,
+		ProjectID: fake.ProjectID,
+	}
+	h.Config = config
+
+	if utils.GetDatapath(config.Datapath) == utils.DatapathTrunk {
+		parent, err := neutronServer.AddPort(fake.Port{
+			Name:        HarnessTrunkPortName,
+			NetworkID:   h.NetworkID,
+			FixedIPs:    []fake.FixedIP{{SubnetID: h.SubnetID}},
+			DeviceID:    HarnessVMUUID,
+			DeviceOwner: "compute:nova",
+		})
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		if h.TrunkID, err = neutronServer.AddTrunk("harness_trunk", parent.ID); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+
+	h.dir, err = ioutil.TempDir("", "rubble-harness")
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	if err = h.start(objects...); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) start(objects ...runtime.Object) error {
+	var kubeObjects, crdObjects []runtime.Object
+	for _, obj := range objects {
+		if _, ok := obj.(*unstructured.Unstructured); ok {
+			crdObjects = append(crdObjects, obj)
+		} else {
+			kubeObjects = append(kubeObjects, obj)
+		}
+	}
+	h.KubeClient = k8sfake.NewSimpleClientset(withNamespaces(kubeObjects)...)
+	h.DynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{k8s.IPPoolGVR: k8s.IPPoolKind + "List"}, crdObjects...)
+	return h.startServer()
+}
+
+// startServer start daemon server with db in harness dir, pods and ports of previous server are restored
+func (h *Harness) startServer() error {
+	neutronClient, err := neutron.NewClientWithAuthOptions(h.Neutron.AuthOptions())
+	if err != nil {
+		return fmt.Errorf("failed to create neutron client with error: %w", err)
+	}
+
+	resourceDB, err := storage.NewDiskStorage(utils.ResDBName, filepath.Join(h.dir, "daemon.db"), json.Marshal, jsonDeserializer)
+	if err != nil {
+		return fmt.Errorf("error init resource manager storage: %w", err)
+	}
+	journal, err := newPoolJournal(resourceDB)
+	if err != nil {
+		return err
+	}
+
+	service := &daemonServer{
+		cniBinPath:    utils.DefaultCNIPath,
+		neutronNet:    h.NetworkID,
+		neutronSubNet: h.SubnetID,
+		k8s:           k8s.NewK8sWithClient(h.KubeClient, h.DynamicClient, HarnessNodeName),
+		neutronClient: neutronClient,
+		resourceDB:    resourceDB,
+		journal:       journal,
+	}
+	if err = service.init(h.Config); err != nil {
+		return err
+	}
+	h.Server = service
+	h.Admin = newAdminServer(service)
+	return nil
+}
+
+// Restart shut down daemon server and start a new one with the same db, kubernetes and neutron
+func (h *Harness) Restart() error {
+	if err := h.shutdown(); err != nil {
+		return err
+	}
+	return h.startServer()
+}
+
+func (h *Harness) shutdown() error {
+	s, ok := h.Server.(*daemonServer)
+	if !ok {
+		return nil
+	}
+	h.Server = nil
+	h.Admin = nil
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return s.shutdown(ctx)
+}
+
+// Close shut down daemon server, stop the fake neutron server and remove the daemon db
+func (h *Harness) Close() {
+	if err := h.shutdown(); err != nil {
+		logger.Warnf("failed to shutdown harness daemon server: %v", err)
+	}
+	h.Neutron.Close()
+	if len(h.dir) > 0 {
+		_ = os.RemoveAll(h.dir)
+	}
+}
+
+// withNamespaces add namespaces of pods which are not in objects
+func withNamespaces(objects []runtime.Object) []runtime.Object {
+	namespaces := make(map[string]bool)
+	for _, obj := range objects {
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			namespaces[ns.Name] = true
+		}
+	}
+	ret := objects
+	for _, obj := range objects {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || namespaces[pod.Namespace] {
+			continue
+		}
+		namespaces[pod.Namespace] = true
+		ret = append(ret, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: pod.Namespace}})
+	}
+	return ret
+}
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rubble/pkg/controller"
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/rpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func ipPoolObjects(name string, addresses []interface{}, pods ...string) []runtime.Object {
+	objs := []runtime.Object{&unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": k8s.IPPoolGVR.GroupVersion().String(),
+		"kind":       k8s.IPPoolKind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"subnet": HarnessSubnetName, "addresses": addresses},
+	}}}
+	controllerRef := true
+	for _, pod := range pods {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: "default",
+				Annotations:     map[string]string{ipam.IpPoolAnnotation: name},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: name, Controller: &controllerRef}}},
+			Spec:   corev1.PodSpec{NodeName: HarnessNodeName},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	return objs
+}
+
+func poolAllocation(t *testing.T, h *Harness, name, ip string) k8s.IPAllocation {
+	t.Helper()
+	p, err := k8s.NewK8sWithClient(h.KubeClient, h.DynamicClient, "").GetIPPool(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Status.Allocations[ip]
+}
+
+func TestIPPoolAllocation(t *testing.T) {
+	addresses := []interface{}{"192.168.100.50-192.168.100.51"}
+	h, err := NewHarness(nil, ipPoolObjects("web", addresses, "web-0", "web-1", "web-2")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+
+	client, err := neutron.NewClientWithAuthOptions(h.Neutron.AuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := k8s.NewK8sWithClient(h.KubeClient, h.DynamicClient, "")
+	if err = controller.NewIPPoolController(kc, client, 0).Sync(); err != nil {
+		t.Fatalf("sync ip pool: %v", err)
+	}
+
+	ips := make(map[string]string)
+	for _, pod := range []string{"web-0", "web-1"} {
+		reply, err := allocate(ctx, h, pod, "c-"+pod)
+		if err != nil {
+			t.Fatalf("allocate %s: %v", pod, err)
+		}
+		ips[pod] = podIPv4(reply)
+		if a := poolAllocation(t, h, "web", ips[pod]); a.Pod != "default/"+pod || a.Node != HarnessNodeName {
+			t.Errorf("allocation of %s = %+v, want pod %s on %s", ips[pod], a, pod, HarnessNodeName)
+		}
+	}
+	if ips["web-0"] == ips["web-1"] || ips["web-0"] != "192.168.100.50" || ips["web-1"] != "192.168.100.51" {
+		t.Fatalf("pods got %v, want addresses of pool", ips)
+	}
+	// pool is exhausted
+	if _, err := allocate(ctx, h, "web-2", "c-web-2"); err == nil {
+		t.Fatalf("allocate web-2 from exhausted pool succeeded")
+	}
+
+	// released address goes back to pool and is kept for the pod with the same name
+	if _, err := release(ctx, h, "web-1", "c-web-1"); err != nil {
+		t.Fatalf("release web-1: %v", err)
+	}
+	if a := poolAllocation(t, h, "web", ips["web-1"]); a.Pod != "default/web-1" || len(a.Node) > 0 {
+		t.Errorf("allocation after release = %+v, want pod kept and node cleared", a)
+	}
+	if _, err := allocate(ctx, h, "web-2", "c-web-2"); err == nil {
+		t.Errorf("web-2 took address kept for web-1")
+	}
+	reply, err := allocate(ctx, h, "web-1", "c-web-1-new")
+	if err != nil {
+		t.Fatalf("allocate web-1 again: %v", err)
+	}
+	if podIPv4(reply) != ips["web-1"] {
+		t.Errorf("web-1 got %s again, want %s", podIPv4(reply), ips["web-1"])
+	}
+
+	// ports of ip pool are restored as in use after restart, no port is created for them
+	creates := h.Neutron.RequestCount(http.MethodPost, "/v2.0/ports")
+	if err = h.Restart(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	ports, err := h.Admin.ListPorts(ctx, &rpc.ListPortsRequest{Pool: ipam.IPPoolTag("web")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports.Ports) != 2 {
+		t.Errorf("ports of ip pool after restart = %d, want 2", len(ports.Ports))
+	}
+	for _, pod := range []string{"web-0", "web-1"} {
+		info, err := h.Server.GetIPInfo(ctx, &rpc.GetInfoRequest{K8SPodName: pod, K8SPodNamespace: "default"})
+		if err != nil {
+			t.Fatalf("ip info of %s after restart: %v", pod, err)
+		}
+		if got := info.NetConfs[0].BasicInfo.PodIP.IPv4; got != ips[pod] {
+			t.Errorf("%s has %s after restart, want %s", pod, got, ips[pod])
+		}
+	}
+	if n := h.Neutron.RequestCount(http.MethodPost, "/v2.0/ports"); n != creates {
+		t.Errorf("ports created for ip pool after restart: %d", n-creates)
+	}
+	if _, err := release(ctx, h, "web-0", "c-web-0"); err != nil {
+		t.Fatalf("release web-0 after restart: %v", err)
+	}
+	if a := poolAllocation(t, h, "web", ips["web-0"]); len(a.Node) > 0 {
+		t.Errorf("allocation of %s after release = %+v, want node cleared", ips["web-0"], a)
+	}
+	if n := portsInState(t, h, ipam.PortStateInUse); n != 1 {
+		t.Errorf("ports in use = %d, want 1", n)
+	}
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rubble/pkg/metrics"
+	"github.com/rubble/pkg/pool"
+	"github.com/rubble/pkg/storage"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// statusCoder is implemented by errors of unexpected openstack api responses
+type statusCoder interface {
+	GetStatusCode() int
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	metrics.RPCDuration.WithLabelValues(method, metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.RPCErrors.WithLabelValues(method, errorCause(err)).Inc()
+	}
+}
+
+// errorCause classify error of rpc for metrics
+func errorCause(err error) string {
+	var apiStatus apierrors.APIStatus
+	var sc statusCoder
+	switch {
+	case errors.Is(err, pool.ErrNoAvailableResource):
+		return "pool_exhausted"
+	case errors.Is(err, pool.ErrContextDone), errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.Is(err, pool.ErrNotFound), errors.Is(err, storage.ErrNotFound):
+		return "not_found"
+	case errors.As(err, &apiStatus):
+		return "kubernetes"
+	case errors.As(err, &sc):
+		return "neutron"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/neutron/fake"
+	"github.com/rubble/pkg/rpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const otherVM = "5d0c3a9e-8b7f-4e6d-a1c2-b3d4e5f6a7b8"
+
+func staticIPPod(name, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{ipam.IpAddressAnnotation: ip}},
+		Spec:       corev1.PodSpec{NodeName: HarnessNodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func portWithIP(t *testing.T, h *Harness, ip string) fake.Port {
+	t.Helper()
+	for _, p := range h.Neutron.Ports() {
+		if len(p.FixedIPs) > 0 && p.FixedIPs[0].IPAddress == ip {
+			return p
+		}
+	}
+	t.Fatalf("no port with ip %s", ip)
+	return fake.Port{}
+}
+
+func hasTagPrefix(tags []string, prefix string) bool {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTakeOverPort(t *testing.T) {
+	const ip = "192.168.100.77"
+	h, err := NewHarness(nil, staticIPPod("web-0", ip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	old, err := h.Neutron.AddPort(fake.Port{
+		NetworkID:   h.NetworkID,
+		DeviceOwner: ipam.DeviceOwner,
+		FixedIPs:    []fake.FixedIP{{SubnetID: h.SubnetID, IPAddress: ip}},
+		Tags:        []string{ipam.VMTag(otherVM)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// node of the port is asked to hand it over first
+	if _, err = allocate(ctx, h, "web-0", "c"); err == nil {
+		t.Fatal("port is taken over before its node hands it over")
+	}
+	p, _ := h.Neutron.GetPort(old.ID)
+	if !hasTagPrefix(p.Tags, ipam.TakeOverTagPrefix+":"+HarnessVMUUID) {
+		t.Fatalf("tags of port = %v, want take over request", p.Tags)
+	}
+	if _, err = allocate(ctx, h, "web-0", "c"); err == nil {
+		t.Fatal("port is taken over before its node hands it over")
+	}
+
+	// the node drops its tag
+	var tags []string
+	for _, tag := range p.Tags {
+		if tag != ipam.VMTag(otherVM) {
+			tags = append(tags, tag)
+		}
+	}
+	if err = h.Neutron.SetPortTags(old.ID, tags); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := allocate(ctx, h, "web-0", "c")
+	if err != nil {
+		t.Fatalf("allocate after port handed over: %v", err)
+	}
+	if podIPv4(reply) != ip {
+		t.Errorf("ip = %s, want %s", podIPv4(reply), ip)
+	}
+	p, _ = h.Neutron.GetPort(old.ID)
+	if hasTagPrefix(p.Tags, ipam.TakeOverTagPrefix) || !hasTagPrefix(p.Tags, ipam.VMTag(HarnessVMUUID)) {
+		t.Errorf("tags of port taken over = %v", p.Tags)
+	}
+}
+
+func TestTakeOverPortTimeout(t *testing.T) {
+	const ip = "192.168.100.78"
+	h, err := NewHarness(nil, staticIPPod("web-0", ip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	// node of port did not answer the request for long, e.g. it is gone
+	stale := ipam.TakeOverTag(HarnessVMUUID, time.Now().Add(-time.Hour))
+	if _, err = h.Neutron.AddPort(fake.Port{
+		NetworkID:   h.NetworkID,
+		DeviceOwner: ipam.DeviceOwner,
+		FixedIPs:    []fake.FixedIP{{SubnetID: h.SubnetID, IPAddress: ip}},
+		Tags:        []string{ipam.VMTag(otherVM), stale},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := allocate(context.Background(), h, "web-0", "c")
+	if err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	if podIPv4(reply) != ip {
+		t.Errorf("ip = %s, want %s", podIPv4(reply), ip)
+	}
+	p := portWithIP(t, h, ip)
+	if hasTagPrefix(p.Tags, ipam.VMTag(otherVM)) || hasTagPrefix(p.Tags, ipam.TakeOverTagPrefix) {
+		t.Errorf("tags of port taken over = %v", p.Tags)
+	}
+}
+
+func TestHandOverPort(t *testing.T) {
+	const ip = "192.168.100.79"
+	h, err := NewHarness(nil, staticIPPod("web-0", ip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+	if _, err = allocate(ctx, h, "web-0", "c"); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	p := portWithIP(t, h, ip)
+	request := ipam.TakeOverTag(otherVM, time.Now())
+	if err = h.Neutron.SetPortTags(p.ID, append(p.Tags, request)); err != nil {
+		t.Fatal(err)
+	}
+	s := h.Server.(*daemonServer)
+
+	// port in use is kept
+	if err = s.gc(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if p, _ = h.Neutron.GetPort(p.ID); !hasTagPrefix(p.Tags, ipam.VMTag(HarnessVMUUID)) {
+		t.Fatalf("port in use is handed over, tags %v", p.Tags)
+	}
+
+	if _, err = h.Server.ReleaseIP(ctx, &rpc.ReleaseIPRequest{K8SPodName: "web-0", K8SPodNamespace: "default", K8SPodInfraContainerId: "c"}); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err = s.gc(); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	p, ok := h.Neutron.GetPort(p.ID)
+	if !ok {
+		t.Fatal("port requested by other node is deleted")
+	}
+	if hasTagPrefix(p.Tags, ipam.VMTag(HarnessVMUUID)) {
+		t.Errorf("idle port is not handed over, tags %v", p.Tags)
+	}
+	if _, err = s.portManager.Get(p.ID); err == nil {
+		t.Error("port handed over is still in pool")
+	}
+}
@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/rubble/pkg/k8s"
+)
+
+const (
+	quotaInterval = 30 * time.Second
+	// quotaLeaseDuration is how long usage of node counts after daemon stopped reporting
+	quotaLeaseDuration = 3 * quotaInterval
+)
+
+// quotaReporter report usage of pools in lease of node and apply capacity granted by
+// controller in quota config map, pools keep their max sizes until a budget is granted
+type quotaReporter struct {
+	server   *daemonServer
+	nodeName string
+}
+
+// Run report usage and apply budget periodically until stop closed
+func (r *quotaReporter) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(quotaInterval)
+	defer ticker.Stop()
+	for {
+		r.sync()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *quotaReporter) sync() {
+	usage, subnets := r.server.portManager.PoolUsage()
+	demand := usage.InUse + usage.IdleTarget
+	if demand > usage.MaxCapacity {
+		demand = usage.MaxCapacity
+	}
+	err := r.server.k8s.ReportNodeUsage(k8s.NodeUsage{
+		Node:        r.nodeName,
+		Subnets:     subnets,
+		InUse:       usage.InUse,
+		Idle:        usage.Idle,
+		Demand:      demand,
+		MaxCapacity: usage.MaxCapacity,
+	}, quotaLeaseDuration)
+	if err != nil {
+		logger.Errorf("failed to report usage of node %s with error: %v", r.nodeName, err)
+	}
+
+	quota, ok, err := r.server.k8s.GetNodeQuota(r.nodeName)
+	if err != nil {
+		logger.Errorf("failed to get quota of node %s with error: %v", r.nodeName, err)
+		return
+	}
+	if ok {
+		r.server.portManager.SetPoolCapacity(quota.Capacity)
+	}
+}
@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQuotaCountsAnnotationPools(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "a0", Namespace: "default", Annotations: map[string]string{ipam.SubnetAnnotation: HarnessSmallSubnetName}},
+		Spec:       corev1.PodSpec{NodeName: HarnessNodeName},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:       10,
+		MaxIdleSize:       2,
+		MinIdleSize:       0,
+		AnnotationSubnets: []string{HarnessSmallSubnetName},
+		SubnetPoolSize:    3,
+	}
+	h, err := NewHarness(cfg, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if _, err = allocate(context.Background(), h, "a0", "c"); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	manager := h.Server.(*daemonServer).portManager
+
+	usage, subnets := manager.PoolUsage()
+	if usage.InUse != 1 || usage.MaxCapacity != 10+3 {
+		t.Errorf("usage = %+v, want port of annotation pool in use and its size in max capacity", usage)
+	}
+	if len(subnets) != 2 {
+		t.Errorf("subnets = %v, want subnets of node and annotation", subnets)
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+	}{
+		{name: "budget below max", capacity: 5},
+		// controller never grants less than ports in use
+		{name: "budget of ports in use", capacity: 1},
+		{name: "budget above max", capacity: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager.SetPoolCapacity(tt.capacity)
+			usage, _ := manager.PoolUsage()
+			want := tt.capacity
+			if want > usage.MaxCapacity {
+				want = usage.MaxCapacity
+			}
+			if usage.Capacity > want {
+				t.Errorf("capacity of pools = %d, want at most %d", usage.Capacity, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/neutron/fake"
+	"github.com/rubble/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecurityGroupAnnotation(t *testing.T) {
+	pod := func(name, key, value string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{key: value}},
+			Spec:       corev1.PodSpec{NodeName: HarnessNodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:           10,
+		MaxIdleSize:           5,
+		MinIdleSize:           0,
+		AllowedSecurityGroups: []string{HarnessSecurityGroupName},
+	}
+	h, err := NewHarness(cfg,
+		pod("allowed", ipam.SecurityGroupsAnnotation, HarnessSecurityGroupName),
+		pod("not-allowed", ipam.SecurityGroupsAnnotation, "other_sg"),
+		pod("policy", ipam.NetworkPolicyAnnotation, "rubble-np-uid"),
+		pod("not-policy", ipam.NetworkPolicyAnnotation, "other_sg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.Neutron.AddSecurityGroup("other_sg")
+	h.Neutron.AddSecurityGroup("rubble-np-uid")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		pod     string
+		wantErr bool
+	}{
+		{pod: "allowed"},
+		// only groups in security_groups and allowed_security_groups can be selected
+		{pod: "not-allowed", wantErr: true},
+		{pod: "policy"},
+		// network policy annotation only accepts groups of network policies
+		{pod: "not-policy", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := allocate(ctx, h, tt.pod, "c")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("allocate %s error = %v, want error %v", tt.pod, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSyncSecurityGroupsNotBlockAllocation(t *testing.T) {
+	cfg := &utils.DaemonConfigure{
+		MaxPoolSize:           10,
+		MaxIdleSize:           5,
+		MinIdleSize:           2,
+		AllowedSecurityGroups: []string{HarnessSecurityGroupName},
+	}
+	h, err := NewHarness(cfg, runningPods(2)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.Background()
+	if _, err = allocate(ctx, h, "p0", "c"); err != nil {
+		t.Fatalf("allocate p0: %v", err)
+	}
+
+	pod, err := h.KubeClient.CoreV1().Pods("default").Get(ctx, "p0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod.Annotations = map[string]string{ipam.SecurityGroupsAnnotation: HarnessSecurityGroupName}
+	if _, err = h.KubeClient.CoreV1().Pods("default").Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	h.Neutron.AddFault(fake.Fault{Method: http.MethodPut, Path: "/v2.0/ports", Latency: time.Second, Times: 1})
+
+	done := make(chan error, 1)
+	go func() { done <- h.Server.(*daemonServer).syncSecurityGroups() }()
+	time.Sleep(100 * time.Millisecond)
+
+	// allocation goes on while security groups of p0 are being updated
+	allocCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	if _, err = allocate(allocCtx, h, "p1", "c"); err != nil {
+		t.Fatalf("allocate p1 during security group sync: %v", err)
+	}
+	if err = <-done; err != nil {
+		t.Fatalf("sync security groups: %v", err)
+	}
+
+	updated := 0
+	for _, port := range h.Neutron.Ports() {
+		if len(port.SecurityGroups) == 1 && port.SecurityGroups[0] == h.SecurityGroupID {
+			updated++
+		}
+	}
+	if updated != 1 {
+		t.Errorf("ports with security group of annotation = %d, want 1", updated)
+	}
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"fmt"
 	"github.com/rubble/pkg/rpc"
 	"net"
@@ -8,14 +9,25 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/rubble/pkg/log"
+	"github.com/rubble/pkg/metrics"
 	"google.golang.org/grpc"
 )
 
 var logger = log.DefaultLogger.WithField("component:", "rubble cni-server")
 
-func Run(socketFilePath, kubeConfig, openstackConfig, neutronNet, neutronSubnet string) error {
+const (
+	// drainTimeout is time rpc in flight are waited on shutdown
+	drainTimeout = 15 * time.Second
+	// shutdownTimeout is time pools are waited to finish refill or dispose on shutdown, it fits default
+	// termination grace period of pod with drainTimeout
+	shutdownTimeout = 10 * time.Second
+)
+
+// Run start rpc server on socketFilePath, metrics are served on metricsAddr if it is not empty
+func Run(socketFilePath, metricsAddr, kubeConfig, openstackConfig, neutronNet, neutronSubnet string) error {
 
 	if err := os.MkdirAll(filepath.Dir(socketFilePath), 0700); err != nil {
 		return err
@@ -36,6 +48,7 @@ func Run(socketFilePath, kubeConfig, openstackConfig, neutronNet, neutronSubnet
 		return err
 	}
 	rpc.RegisterRubbleBackendServer(grpcServer, rubble)
+	rpc.RegisterRubbleAdminServer(grpcServer, newAdminServer(rubble.(*daemonServer)))
 
 	stop := make(chan struct{})
 
@@ -47,6 +60,14 @@ func Run(socketFilePath, kubeConfig, openstackConfig, neutronNet, neutronSubnet
 		stop <- struct{}{}
 	}()
 
+	if len(metricsAddr) > 0 {
+		go func() {
+			if err := metrics.Serve(metricsAddr); err != nil {
+				logger.Errorf("error serve metrics on %s: %v", metricsAddr, err)
+			}
+		}()
+	}
+
 	logger.Infof("Starting rubble cni-server...")
 	go func() {
 		err = grpcServer.Serve(l)
@@ -57,6 +78,24 @@ func Run(socketFilePath, kubeConfig, openstackConfig, neutronNet, neutronSubnet
 	}()
 
 	<-stop
-	grpcServer.GracefulStop()
+	// stop accepting rpc and drain rpc in flight, they are canceled after drain timeout
+	drained := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(drainTimeout):
+		logger.Warnf("rpc not finished in %s, stop rpc server", drainTimeout)
+		grpcServer.Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := rubble.(*daemonServer).shutdown(ctx); err != nil {
+		return fmt.Errorf("error shutdown rubble cni-server: %w", err)
+	}
+	logger.Infof("rubble cni-server stopped")
 	return nil
 }
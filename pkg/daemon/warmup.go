@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/rubble/pkg/ipam"
+	"github.com/rubble/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	warmUpResync = 5 * time.Minute
+	// warmUpDelay coalesce pods scheduled together, e.g. replicas of a deployment scaling out
+	warmUpDelay = 200 * time.Millisecond
+)
+
+// poolWarmer watch pods scheduled to this node and warm pools for pods not started yet,
+// so ports are created before kubelet calls cni ADD
+type poolWarmer struct {
+	server *daemonServer
+
+	factories []informers.SharedInformerFactory
+	podLister listerscorev1.PodLister
+	synced    []cache.InformerSynced
+	trigger   chan struct{}
+}
+
+func newPoolWarmer(s *daemonServer, nodeName string) *poolWarmer {
+	podFactory := informers.NewSharedInformerFactoryWithOptions(s.k8s.Client(), warmUpResync,
+		informers.WithTweakListOptions(func(options *v1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}))
+	w := &poolWarmer{
+		server:    s,
+		factories: []informers.SharedInformerFactory{podFactory},
+		podLister: podFactory.Core().V1().Pods().Lister(),
+		trigger:   make(chan struct{}, 1),
+	}
+
+	podInformer := podFactory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { w.enqueue() },
+		UpdateFunc: func(old, cur interface{}) { w.enqueue() },
+		DeleteFunc: func(obj interface{}) { w.enqueue() },
+	})
+	w.synced = []cache.InformerSynced{podInformer.HasSynced}
+	return w
+}
+
+// Run warm pools when pods on node changed until stop closed
+func (w *poolWarmer) Run(stop <-chan struct{}) {
+	for _, factory := range w.factories {
+		factory.Start(stop)
+	}
+	if !cache.WaitForCacheSync(stop, w.synced...) {
+		logger.Errorf("failed to wait for caches of pool warmer to sync")
+		return
+	}
+
+	for {
+		w.warm()
+		select {
+		case <-stop:
+			return
+		case <-w.trigger:
+			time.Sleep(warmUpDelay)
+		}
+	}
+}
+
+func (w *poolWarmer) enqueue() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// warm count pods not started and without port allocated into pools
+func (w *poolWarmer) warm() {
+	pods, err := w.podLister.List(labels.Everything())
+	if err != nil {
+		logger.Errorf("failed to list local pods with error: %v", err)
+		return
+	}
+
+	var pending []*ipam.ResourceContext
+	// namespaces are fetched only for pending pods instead of watched on every node
+	namespaces := make(map[string]*corev1.Namespace)
+	for _, pod := range pods {
+		if !w.isPending(pod) {
+			continue
+		}
+		ns, ok := namespaces[pod.Namespace]
+		if !ok {
+			if ns, err = w.server.k8s.GetNamespace(pod.Namespace); err != nil {
+				logger.Warnf("failed to get namespace %s with error: %v", pod.Namespace, err)
+				ns = nil
+			}
+			namespaces[pod.Namespace] = ns
+		}
+		pending = append(pending, &ipam.ResourceContext{Pod: pod, Namespace: ns})
+	}
+	w.server.portManager.WarmUp(pending)
+}
+
+// isPending check whether pod is going to acquire a port, host network pods and pods with port
+// allocated are excluded
+func (w *poolWarmer) isPending(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Spec.HostNetwork || pod.Status.Phase != corev1.PodPending ||
+		len(pod.Status.PodIP) > 0 {
+		return false
+	}
+	key := (&k8s.PodInfo{Namespace: pod.Namespace, Name: pod.Name}).PodInfoKey()
+	res, err := w.server.getPodResource(key)
+	if err != nil {
+		logger.Warnf("failed to get resource of pod %s with error: %v", key, err)
+		return false
+	}
+	return len(res.Resources) == 0 || !res.ReleasedAt.IsZero()
+}
@@ -0,0 +1,125 @@
+package ipam
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rubble/pkg/pool"
+)
+
+const (
+	// PortStateInUse is port allocated to pod
+	PortStateInUse = "inuse"
+	// PortStateIdle is port in pool free for any pod
+	PortStateIdle = "idle"
+	// PortStateReserved is idle port reserved for pod released it until stick time passes
+	PortStateReserved = "reserved"
+)
+
+// PortInfo is a port managed by this node, listed by admin rpc
+type PortInfo struct {
+	ID    string
+	Pool  string
+	State string
+	IPv4  string
+	IPv6  string
+	// SubnetID is ipv4 subnet of port, ipv6 subnet for ipv6 only port
+	SubnetID      string
+	ReservedUntil time.Time
+	// IPPool is name of ip pool for ports of ip pool attached to this node
+	IPPool string
+}
+
+// Ports return ports of all pools and ports of ip pools attached to this node, sorted by pool and id
+func (m *PortResourceManager) Ports() []PortInfo {
+	now := time.Now()
+	var ret []PortInfo
+	for _, pp := range m.pools() {
+		for _, res := range pp.pool.GetInUse() {
+			ret = append(ret, portInfo(pp.factory.poolName, PortStateInUse, res.(*PortResource)))
+		}
+		for _, item := range pp.pool.GetIdle() {
+			state := PortStateIdle
+			if item.ReservedUntil().After(now) {
+				state = PortStateReserved
+			}
+			info := portInfo(pp.factory.poolName, state, item.GetResource().(*PortResource))
+			if state == PortStateReserved {
+				info.ReservedUntil = item.ReservedUntil()
+			}
+			ret = append(ret, info)
+		}
+	}
+
+	m.reservedLock.Lock()
+	for _, rp := range m.reserved {
+		info := portInfo(IPPoolTag(rp.ipPool), PortStateInUse, rp.PortResource)
+		info.IPPool = rp.ipPool
+		ret = append(ret, info)
+	}
+	m.reservedLock.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Pool != ret[j].Pool {
+			return ret[i].Pool < ret[j].Pool
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret
+}
+
+func portInfo(poolName, state string, p *PortResource) PortInfo {
+	return PortInfo{
+		ID:       p.GetResourceId(),
+		Pool:     poolName,
+		State:    state,
+		IPv4:     p.port.IP,
+		IPv6:     p.port.IPv6,
+		SubnetID: p.subnetID(),
+	}
+}
+
+// DrainPool dispose idle ports of pool with the name, all pools if name is empty. ports reserved for
+// pods are kept unless force, count of ports disposed is returned
+func (m *PortResourceManager) DrainPool(name string, force bool) (int, error) {
+	found := false
+	disposed := 0
+	for _, pp := range m.pools() {
+		if len(name) > 0 && pp.factory.poolName != name {
+			continue
+		}
+		found = true
+		disposed += pp.pool.Drain(force)
+	}
+	if !found {
+		return 0, fmt.Errorf("pool %s not found", name)
+	}
+	return disposed, nil
+}
+
+// DisposePort remove port in use from its pool and delete it, the port is never handed to another pod
+// since pod may still use its address. ports of ip pools are owned by controller and not disposed
+func (m *PortResourceManager) DisposePort(resId string) error {
+	if _, ok := m.getReserved(resId); ok {
+		return fmt.Errorf("port %s belongs to ip pool: %w", resId, pool.ErrInvalidState)
+	}
+	pp, err := m.poolOf(resId)
+	if err != nil {
+		return err
+	}
+	res, ok := pp.pool.GetInUse()[resId]
+	if !ok {
+		return pool.ErrInvalidState
+	}
+	if err = m.detach(res); err != nil {
+		return err
+	}
+	if _, err = pp.pool.Remove(resId); err != nil {
+		return err
+	}
+	if err = pp.factory.Dispose(res); err != nil {
+		return fmt.Errorf("port %s is removed from pool but failed to delete with error: %w", resId, err)
+	}
+	return nil
+}
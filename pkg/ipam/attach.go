@@ -0,0 +1,64 @@
+package ipam
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rubble/pkg/neutron"
+	types "github.com/rubble/pkg/utils"
+)
+
+// eniDetachTimeout is time to wait for nova to detach port from node
+const eniDetachTimeout = time.Minute
+
+// eni return if ports are attached to node as interfaces of pods
+func (m *PortResourceManager) eni() bool {
+	return types.GetDatapath(m.config.Datapath) == types.DatapathENI
+}
+
+// attaching return if ports allocated to pods are attached to node, by nova for eni datapath or as
+// subports of trunk of node for trunk datapath
+func (m *PortResourceManager) attaching() bool {
+	return m.eni() || m.trunk != nil
+}
+
+// nodePortFilter match ports allocated by this node. nova and trunk change device owner of ports
+// attached to node, so they are matched by tag only
+func (m *PortResourceManager) nodePortFilter() neutron.ListFilter {
+	f := neutron.ListFilter{Tags: VMTag(m.config.Node.UUID)}
+	if !m.attaching() {
+		f.DeviceOwner = DeviceOwner
+	}
+	return f
+}
+
+// attach port allocated to pod to node, hot-plug it into node for eni datapath or add it into trunk
+// for trunk datapath, cni moves the interface into pod or creates vlan of it
+func (m *PortResourceManager) attach(client *neutron.Client, res types.NetworkResource) error {
+	switch {
+	case m.eni():
+		if err := client.AttachPort(m.config.Node.UUID, res.GetResourceId()); err != nil {
+			return fmt.Errorf("failed to attach port %s to node with error: %w", res.GetResourceId(), err)
+		}
+	case m.trunk != nil:
+		if err := m.trunk.addSubport(client, res.(*PortResource)); err != nil {
+			return fmt.Errorf("failed to add port %s into trunk %s with error: %w", res.GetResourceId(), m.trunk.id, err)
+		}
+	}
+	return nil
+}
+
+// detach port released by pod from node, so idle port is not left on node
+func (m *PortResourceManager) detach(res types.NetworkResource) error {
+	switch {
+	case m.eni():
+		if err := m.client.DetachPort(m.config.Node.UUID, res.GetResourceId(), eniDetachTimeout); err != nil {
+			return fmt.Errorf("failed to detach port %s from node with error: %w", res.GetResourceId(), err)
+		}
+	case m.trunk != nil:
+		if err := m.trunk.removeSubport(m.client, res.(*PortResource)); err != nil {
+			return fmt.Errorf("failed to remove port %s from trunk %s with error: %w", res.GetResourceId(), m.trunk.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/pool"
+	types "github.com/rubble/pkg/utils"
+)
+
+// disposeConcurrency limit concurrent delete requests of DisposeBatch, neutron has no bulk delete
+const disposeConcurrency = 5
+
+// CreateBatch create count ports in bulk requests. least-used policy spreads ports among subnets,
+// other policies create them from the first available subnet. ports left by an exhausted subnet
+// are created from the next one
+func (f *PortFactory) CreateBatch(ctx context.Context, count int) ([]types.NetworkResource, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	client := f.client.WithContext(ctx)
+	candidates := f.subnets.candidates()
+	shares := f.subnets.spread(candidates, count)
+
+	var ret []types.NetworkResource
+	var err error
+	left := 0
+	for i, sb := range candidates {
+		n := shares[i] + left
+		if n == 0 {
+			continue
+		}
+		var res []types.NetworkResource
+		res, err = f.createFrom(client, sb, n)
+		ret = append(ret, res...)
+		left = n - len(res)
+		if err == nil {
+			f.subnets.markAvailable(sb.ID)
+			continue
+		}
+		exhausted, retry := f.subnetExhaustion(err, sb)
+		if !retry {
+			logger.Errorf("failed to create %d ports with error: %s", n, err)
+			return ret, err
+		}
+		if len(exhausted) == 0 {
+			logger.Warnf("no address left for %d ports from subnet %s or ipv6 subnet %s, try next subnet", left, sb.ID, f.subnetIDv6)
+			continue
+		}
+		logger.Warnf("subnet %s is exhausted, create %d ports from next subnet", exhausted, left)
+		f.subnets.markExhausted(exhausted)
+	}
+	if left > 0 {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// createFrom create n ports from sb, the bulk is halved when neutron has not enough addresses for it
+// as neutron creates all ports of a bulk request or none, so that last addresses of sb are used
+func (f *PortFactory) createFrom(client *neutron.Client, sb *subnets.Subnet, n int) ([]types.NetworkResource, error) {
+	var ret []types.NetworkResource
+	size := n
+	for len(ret) < n {
+		if size > n-len(ret) {
+			size = n - len(ret)
+		}
+		res, err := f.createBulk(client, sb, size)
+		ret = append(ret, res...)
+		if err == nil {
+			continue
+		}
+		if _, retry := f.subnetExhaustion(err, sb); retry && size > 1 {
+			size /= 2
+			continue
+		}
+		return ret, err
+	}
+	return ret, nil
+}
+
+// createBulk create count ports from sb in one request
+func (f *PortFactory) createBulk(client *neutron.Client, sb *subnets.Subnet, count int) ([]types.NetworkResource, error) {
+	opts := make([]*neutron.CreateOpts, 0, count)
+	for i := 0; i < count; i++ {
+		opts = append(opts, f.createOpts(sb))
+	}
+
+	for _, opt := range opts {
+		f.journal.Record(f.poolName, opt.Name, pool.JournalCreating, time.Time{})
+	}
+	ports, err := client.CreatePorts(opts)
+	if !isContextDone(err) {
+		for _, opt := range opts {
+			f.journal.Forget(f.poolName, opt.Name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// ports are created, tag them even if ctx is done so that they are not orphaned
+	client = f.client
+	tag := VMTag(f.vmUUID)
+	var ret []types.NetworkResource
+	var lastErr error
+	for i := range ports {
+		port := &ports[i]
+		if !hasTag(port.Tags, tag) {
+			// neutron does not support tags in bulk request
+			if err = client.AddTag("ports", port.ID, tag); err != nil {
+				lastErr = fmt.Errorf("failed to add tag to port:%s with error %w", port.ID, err)
+				if err = client.DeletePort(port.ID); err != nil {
+					logger.Errorf("failed to delete port %s with error: %s", port.ID, err)
+				}
+				continue
+			}
+		}
+
+		p := &PortResource{port: port}
+		f.Lock()
+		f.ports = append(f.ports, p)
+		f.Unlock()
+		f.subnets.addUsage(p.subnetID(), 1)
+		ret = append(ret, p)
+	}
+	return ret, lastErr
+}
+
+// DisposeBatch delete ports concurrently, ports failed to delete are returned with the last error
+func (f *PortFactory) DisposeBatch(ctx context.Context, res []types.NetworkResource) ([]types.NetworkResource, error) {
+	var lock sync.Mutex
+	var failed []types.NetworkResource
+	var lastErr error
+
+	client := f.client.WithContext(ctx)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, disposeConcurrency)
+	for _, r := range res {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r types.NetworkResource) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := client.DeletePort(r.GetResourceId()); err != nil {
+				lock.Lock()
+				failed = append(failed, r)
+				lastErr = fmt.Errorf("failed to delete port %s with error: %w", r.GetResourceId(), err)
+				lock.Unlock()
+				return
+			}
+			if p, ok := r.(*PortResource); ok {
+				f.subnets.addUsage(p.subnetID(), -1)
+			}
+		}(r)
+	}
+	wg.Wait()
+	return failed, lastErr
+}
+
+// Recover find ports created before daemon restarted by names in journal. ports with tag of node are
+// restored from tag listing, ports created without tag are tagged and returned
+func (f *PortFactory) Recover(names []string) ([]types.NetworkResource, error) {
+	tag := VMTag(f.vmUUID)
+	var ret []types.NetworkResource
+	for _, name := range names {
+		nps, err := f.client.ListPortWithFilter(neutron.ListFilter{Name: name, NetworkID: f.netID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list port %s with error: %w", name, err)
+		}
+		for _, np := range nps {
+			if hasTag(np.Tags, tag) {
+				continue
+			}
+			if err = f.client.AddTag("ports", np.ID, tag); err != nil {
+				return nil, fmt.Errorf("failed to add tag to port:%s with error %w", np.ID, err)
+			}
+			port, err := f.convertPort(np)
+			if err != nil {
+				return nil, err
+			}
+			logger.Infof("recover port %s created before restart", np.ID)
+			p := &PortResource{port: port}
+			f.Lock()
+			f.ports = append(f.ports, p)
+			f.Unlock()
+			f.subnets.addUsage(p.subnetID(), 1)
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
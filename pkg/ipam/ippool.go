@@ -0,0 +1,201 @@
+package ipam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/pool"
+	types "github.com/rubble/pkg/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// IPPoolTagPrefix tag ports pre-created for addresses of RubbleIPPool, e.g. ip_pool:web
+	IPPoolTagPrefix = "ip_pool"
+
+	ipPoolUpdateRetries = 5
+)
+
+// reservedPort is a port of ip pool attached to this node, it is not managed by pool
+// and never given to other pods
+type reservedPort struct {
+	*PortResource
+	ipPool string
+}
+
+// IPPoolTag return tag of ports of ip pool
+func IPPoolTag(name string) string {
+	return fmt.Sprintf("%s:%s", IPPoolTagPrefix, name)
+}
+
+// ipPoolOfPort return name of ip pool port created for, empty if port is not an ip pool port
+func ipPoolOfPort(tags []string) string {
+	prefix := IPPoolTagPrefix + ":"
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return strings.TrimPrefix(tag, prefix)
+		}
+	}
+	return ""
+}
+
+// pickPoolAddress return address allocated to pod before, or a free address with port created
+func pickPoolAddress(ipPool *k8s.IPPool, podKey string) (string, bool) {
+	var free []string
+	for ip, alloc := range ipPool.Status.Allocations {
+		if len(alloc.PortID) == 0 {
+			continue
+		}
+		if alloc.Pod == podKey {
+			return ip, true
+		}
+		if len(alloc.Pod) == 0 {
+			free = append(free, ip)
+		}
+	}
+	if len(free) == 0 {
+		return "", false
+	}
+	sort.Strings(free)
+	return free[0], true
+}
+
+// acquirePoolAddress take address from ip pool for pod and attach its port to this node,
+// the address allocated to the pod before is preferred so pods of statefulset keep their addresses
+func (m *PortResourceManager) acquirePoolAddress(ctx *ResourceContext, name string) (types.NetworkResource, error) {
+	podKey := ctx.PodInfo.PodInfoKey()
+	for i := 0; i < ipPoolUpdateRetries; i++ {
+		ipPool, err := m.k8s.GetIPPool(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ip pool %s with error: %w", name, err)
+		}
+
+		ip, ok := pickPoolAddress(ipPool, podKey)
+		if !ok {
+			return nil, fmt.Errorf("no free address in ip pool %s", name)
+		}
+		alloc := ipPool.Status.Allocations[ip]
+		if len(alloc.Node) > 0 && alloc.Node != m.config.Node.Name {
+			// pod with same name is gone from old node, take over the address
+			if alloc.Pod != podKey {
+				return nil, fmt.Errorf("address %s of pod %s is still used on node %s", ip, podKey, alloc.Node)
+			}
+			logger.Infof("take over address %s of pod %s from node %s", ip, podKey, alloc.Node)
+		}
+		if res, ok := m.getReserved(alloc.PortID); ok {
+			return res, nil
+		}
+
+		alloc.Owner = k8s.PodOwner(ctx.Pod)
+		alloc.Pod = podKey
+		alloc.Node = m.config.Node.Name
+		ipPool.Status.Allocations[ip] = alloc
+		if _, err = m.k8s.UpdateIPPoolStatus(ipPool); err != nil {
+			if apierrors.IsConflict(err) {
+				logger.Infof("ip pool %s is modified, retry allocating address for pod %s", name, podKey)
+				continue
+			}
+			return nil, fmt.Errorf("failed to update status of ip pool %s with error: %w", name, err)
+		}
+		logger.Infof("address %s of ip pool %s is allocated to pod %s", ip, name, podKey)
+
+		res, err := m.attachPoolPort(m.clientFor(ctx), name, alloc.PortID)
+		if err != nil {
+			if uerr := m.updatePoolAllocation(name, alloc.PortID, func(a *k8s.IPAllocation) { a.Node = "" }); uerr != nil {
+				logger.Errorf("failed to give back address %s of ip pool %s with error: %s", ip, name, uerr)
+			}
+			return nil, err
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("failed to allocate address from ip pool %s after %d retries", name, ipPoolUpdateRetries)
+}
+
+// attachPoolPort tag port of ip pool with this node and keep it as reserved
+func (m *PortResourceManager) attachPoolPort(client *neutron.Client, name, portID string) (*PortResource, error) {
+	np, err := client.GetPort(portID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get port %s of ip pool %s with error: %w", portID, name, err)
+	}
+	port, err := m.factory.convertPort(*np)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.retagPort(client, portID, vmOfPort(np.Tags), ""); err != nil {
+		return nil, err
+	}
+
+	res := &PortResource{port: port}
+	m.reservedLock.Lock()
+	m.reserved[portID] = &reservedPort{PortResource: res, ipPool: name}
+	m.reservedLock.Unlock()
+	return res, nil
+}
+
+// releasePoolAddress detach port of ip pool from this node, pod and owner are kept in allocation
+func (m *PortResourceManager) releasePoolAddress(resId string) error {
+	m.reservedLock.Lock()
+	res, ok := m.reserved[resId]
+	delete(m.reserved, resId)
+	m.reservedLock.Unlock()
+	if !ok {
+		return pool.ErrNotFound
+	}
+
+	err := m.client.RemoveTag("ports", resId, VMTag(m.config.Node.UUID))
+	if err != nil {
+		logger.Errorf("failed to remove tag of port %s with error: %s", resId, err)
+	}
+	logger.Infof("release port %s of ip pool %s", resId, res.ipPool)
+	return m.updatePoolAllocation(res.ipPool, resId, func(a *k8s.IPAllocation) {
+		if a.Node == m.config.Node.Name {
+			a.Node = ""
+		}
+	})
+}
+
+// updatePoolAllocation update allocation of port in ip pool, retry when pool is modified
+func (m *PortResourceManager) updatePoolAllocation(name, portID string, update func(alloc *k8s.IPAllocation)) error {
+	for i := 0; i < ipPoolUpdateRetries; i++ {
+		ipPool, err := m.k8s.GetIPPool(name)
+		if err != nil {
+			return fmt.Errorf("failed to get ip pool %s with error: %w", name, err)
+		}
+		for ip, alloc := range ipPool.Status.Allocations {
+			if alloc.PortID != portID {
+				continue
+			}
+			update(&alloc)
+			ipPool.Status.Allocations[ip] = alloc
+			_, err = m.k8s.UpdateIPPoolStatus(ipPool)
+			break
+		}
+		if err == nil || !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to update ip pool %s after %d retries", name, ipPoolUpdateRetries)
+}
+
+func (m *PortResourceManager) getReserved(resId string) (*PortResource, bool) {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+	res, ok := m.reserved[resId]
+	if !ok {
+		return nil, false
+	}
+	return res.PortResource, true
+}
+
+func (m *PortResourceManager) reservedIDs() []string {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+	var ret []string
+	for id := range m.reserved {
+		ret = append(ret, id)
+	}
+	return ret
+}
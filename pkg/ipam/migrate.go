@@ -0,0 +1,256 @@
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/rubble/pkg/neutron"
+	types "github.com/rubble/pkg/utils"
+)
+
+const (
+	// TakeOverTagPrefix is prefix of tag on port requesting its node to hand it over to another node
+	TakeOverTagPrefix = "takeover"
+	// takeOverTimeout is time node of port has to answer take over request, port is claimed after it
+	takeOverTimeout = 10 * time.Minute
+)
+
+// VMTag return tag of ports attached to vm
+func VMTag(vmUUID string) string {
+	return fmt.Sprintf("%s:%s", VMTagPrefix, vmUUID)
+}
+
+// vmOfPort return uuid of vm port attached to, empty if port is not attached to any vm
+func vmOfPort(tags []string) string {
+	prefix := VMTagPrefix + ":"
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return strings.TrimPrefix(tag, prefix)
+		}
+	}
+	return ""
+}
+
+// podUsingIP return another pod still using ip, empty if ip is free
+func (m *PortResourceManager) podUsingIP(ctx *ResourceContext, ip string) (string, error) {
+	pods, err := m.k8s.ListPodsWithIP(ip)
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods {
+		key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		if key != ctx.PodInfo.PodInfoKey() {
+			return key, nil
+		}
+	}
+	return "", nil
+}
+
+// TakeOverTag return tag requesting node of the vm port is attached to to hand it over to vm, at is
+// time of the request
+func TakeOverTag(vmUUID string, at time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", TakeOverTagPrefix, vmUUID, at.Unix())
+}
+
+// takeOverOfPort return the tag requesting take over of port, the vm requesting and time of request
+func takeOverOfPort(tags []string) (string, string, time.Time) {
+	prefix := TakeOverTagPrefix + ":"
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+		value := strings.TrimPrefix(tag, prefix)
+		i := strings.LastIndex(value, ":")
+		if i < 0 {
+			continue
+		}
+		sec, err := strconv.ParseInt(value[i+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		return tag, value[:i], time.Unix(sec, 0)
+	}
+	return "", "", time.Time{}
+}
+
+// takeOverPort claim port of static ip from the node it attached to, after pod using the ip on
+// that node is gone. the node is requested to hand the port over by a tag first, it drops the port
+// from its pool and removes its tag in gc if the port is idle there. port is claimed once it has no
+// tag of other node, or the node does not answer in takeOverTimeout, e.g. the node is gone
+func (m *PortResourceManager) takeOverPort(ctx *ResourceContext, pp *portPool, np ports.Port, ip string) (types.NetworkResource, error) {
+	if len(ipPoolOfPort(np.Tags)) > 0 {
+		return nil, fmt.Errorf("IP address %s belongs to ip pool %s", ip, ipPoolOfPort(np.Tags))
+	}
+	oldVM := vmOfPort(np.Tags)
+	if oldVM == m.config.Node.UUID {
+		return nil, fmt.Errorf("IP address %s is occupied by port but not in pool idle queue", ip)
+	}
+	pod, err := m.podUsingIP(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	if len(pod) > 0 {
+		return nil, fmt.Errorf("IP address %s is still used by pod %s", ip, pod)
+	}
+
+	client := m.clientFor(ctx)
+	requestTag, requester, requestedAt := takeOverOfPort(np.Tags)
+	requested := requester == m.config.Node.UUID
+	if len(oldVM) > 0 && (!requested || time.Since(requestedAt) < takeOverTimeout) {
+		if !requested {
+			if len(requestTag) > 0 && time.Since(requestedAt) < takeOverTimeout {
+				return nil, fmt.Errorf("port %s of ip %s is being taken over by vm %s", np.ID, ip, requester)
+			}
+			if err = m.requestTakeOver(client, np.ID, requestTag); err != nil {
+				return nil, err
+			}
+		}
+		return nil, fmt.Errorf("port %s of ip %s is being handed over by vm %s, retry later", np.ID, ip, oldVM)
+	}
+	if len(oldVM) > 0 {
+		logger.Warnf("vm %s does not hand over port %s in %s, take it over", oldVM, np.ID, takeOverTimeout)
+	}
+
+	port, err := pp.factory.convertPort(np)
+	if err != nil {
+		return nil, err
+	}
+	res := &PortResource{port: port}
+	if err = pp.pool.Adopt(ctx.Context, res); err != nil {
+		return nil, err
+	}
+	logger.Infof("take over port %s of ip %s from vm %s", np.ID, ip, oldVM)
+	if err = m.retagPort(client, np.ID, oldVM, requestTag); err != nil {
+		if _, rerr := pp.pool.Remove(res.GetResourceId()); rerr != nil {
+			logger.Errorf("failed to remove port %s from pool with error: %s", res.GetResourceId(), rerr)
+		}
+		return nil, err
+	}
+	pp.factory.subnets.addUsage(res.subnetID(), 1)
+	return res, nil
+}
+
+// requestTakeOver tag port to request node it is attached to to hand it over, stale request is replaced
+func (m *PortResourceManager) requestTakeOver(client *neutron.Client, id, staleTag string) error {
+	if len(staleTag) > 0 {
+		if err := client.RemoveTag("ports", id, staleTag); err != nil {
+			return fmt.Errorf("failed to remove tag %s from port %s with error: %w", staleTag, id, err)
+		}
+	}
+	tag := TakeOverTag(m.config.Node.UUID, time.Now())
+	if err := client.AddTag("ports", id, tag); err != nil {
+		return fmt.Errorf("failed to add tag %s to port %s with error: %w", tag, id, err)
+	}
+	logger.Infof("request take over of port %s", id)
+	return nil
+}
+
+// retagPort move port from old vm to this node and remove the take over request
+func (m *PortResourceManager) retagPort(client *neutron.Client, id, oldVM, requestTag string) error {
+	if err := client.AddTag("ports", id, VMTag(m.config.Node.UUID)); err != nil {
+		return fmt.Errorf("failed to add tag to port:%s with error %w", id, err)
+	}
+	if len(oldVM) > 0 && oldVM != m.config.Node.UUID {
+		if err := client.RemoveTag("ports", id, VMTag(oldVM)); err != nil {
+			return fmt.Errorf("failed to remove tag of vm %s from port %s with error: %w", oldVM, id, err)
+		}
+	}
+	if len(requestTag) > 0 {
+		if err := client.RemoveTag("ports", id, requestTag); err != nil {
+			logger.Warnf("failed to remove tag %s from port %s with error: %s", requestTag, id, err)
+		}
+	}
+	return nil
+}
+
+// handOverPorts answer take over requests of other nodes in gc. idle ports are dropped from pools and
+// untagged, ports in use are kept until pods using them are gone
+func (m *PortResourceManager) handOverPorts(nodePorts []ports.Port) map[string]bool {
+	handled := make(map[string]bool)
+	for _, np := range nodePorts {
+		_, requester, _ := takeOverOfPort(np.Tags)
+		if len(requester) == 0 || requester == m.config.Node.UUID || len(ipPoolOfPort(np.Tags)) > 0 {
+			continue
+		}
+		handled[np.ID] = true
+		pp, err := m.poolOf(np.ID)
+		if err == nil {
+			if _, ok := pp.pool.GetInUse()[np.ID]; ok {
+				logger.Warnf("gc: port %s requested by vm %s is in use, keep it", np.ID, requester)
+				continue
+			}
+			res, err := pp.pool.Remove(np.ID)
+			if err != nil {
+				// acquired after checked
+				continue
+			}
+			if err = m.factory.client.RemoveTag("ports", np.ID, VMTag(m.config.Node.UUID)); err != nil {
+				logger.Errorf("gc: failed to hand over port %s with error: %s", np.ID, err)
+				if err = pp.pool.Adopt(context.Background(), res); err == nil {
+					_ = pp.pool.Release(np.ID)
+				}
+				continue
+			}
+			pp.factory.subnets.addUsage(res.(*PortResource).subnetID(), -1)
+			logger.Infof("gc: hand over port %s to vm %s", np.ID, requester)
+			continue
+		}
+		// port not in any pool, e.g. lost by a crash
+		if err = m.factory.client.RemoveTag("ports", np.ID, VMTag(m.config.Node.UUID)); err != nil {
+			logger.Errorf("gc: failed to hand over port %s with error: %s", np.ID, err)
+			continue
+		}
+		logger.Infof("gc: hand over port %s not in pool to vm %s", np.ID, requester)
+	}
+	return handled
+}
+
+// dropTakenOverPorts drop idle ports which were in pools before listing but not tagged with this node
+// any more, they are taken over by other nodes. ports in use are never dropped
+func (m *PortResourceManager) dropTakenOverPorts(pooled map[string]*portPool, reserved []string, tagged map[string]bool) {
+	for id, pp := range pooled {
+		if tagged[id] {
+			continue
+		}
+		if _, ok := pp.pool.GetInUse()[id]; ok {
+			logger.Warnf("gc: port %s in use is not tagged with this node, keep it", id)
+			continue
+		}
+		res, err := pp.pool.Remove(id)
+		if err != nil {
+			continue
+		}
+		logger.Infof("gc: port %s is taken over by other node, drop it from pool", id)
+		pp.factory.subnets.addUsage(res.(*PortResource).subnetID(), -1)
+	}
+
+	for _, id := range reserved {
+		if tagged[id] {
+			continue
+		}
+		m.reservedLock.Lock()
+		if _, ok := m.reserved[id]; ok {
+			logger.Infof("gc: port %s of ip pool is taken over by other node, drop it", id)
+			delete(m.reserved, id)
+		}
+		m.reservedLock.Unlock()
+	}
+}
+
+// pooledPorts return all ports in pools and the pool they belong to
+func (m *PortResourceManager) pooledPorts() map[string]*portPool {
+	ret := make(map[string]*portPool)
+	for _, pp := range m.pools() {
+		for id := range pp.pool.GetInUse() {
+			ret[id] = pp
+		}
+		for _, item := range pp.pool.GetIdle() {
+			ret[item.GetResource().GetResourceId()] = pp
+		}
+	}
+	return ret
+}
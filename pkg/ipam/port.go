@@ -1,9 +1,14 @@
 package ipam
 
 import (
+	"context"
 	"fmt"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/rubble/pkg/k8s"
 	"github.com/rubble/pkg/rpc"
 	"net"
+	"time"
 
 	"github.com/rubble/pkg/log"
 	"github.com/rubble/pkg/neutron"
@@ -18,22 +23,54 @@ const (
 
 	IpAddressAnnotation = "rubble.kubernetes.io/ip_address"
 	IpPoolAnnotation    = "rubble.kubernetes.io/ip_pool"
+	// SubnetAnnotation on pod or namespace select subnet which pod ip is allocated from, value is name
+	// or id of one of subnets of node or AnnotationSubnets in config
+	SubnetAnnotation = "rubble.kubernetes.io/subnet"
+	// SecurityGroupsAnnotation on pod or namespace override security groups in config, value is comma
+	// separated names or ids of security groups in SecurityGroups or AllowedSecurityGroups of config
+	SecurityGroupsAnnotation = "rubble.kubernetes.io/security_groups"
+	// NetworkPolicyAnnotation is set on pod by controller, value is comma separated ids of security groups
+	// translated from network policies selecting the pod, they replace security groups of pod port
+	NetworkPolicyAnnotation = "rubble.kubernetes.io/network_policy_security_groups"
+	// PolicySecurityGroupPrefix is name prefix of security groups created for network policies, only
+	// they are accepted in NetworkPolicyAnnotation
+	PolicySecurityGroupPrefix = "rubble-np-"
+
+	orphanPortGracePeriod = 5 * time.Minute
+	// defaultSubnetPoolSize is max ports of pool of subnet selected by annotation
+	defaultSubnetPoolSize = 10
 )
 
 var logger = log.DefaultLogger.WithField("component:", "port resource manager")
 
 type PortResource struct {
 	port *neutron.Port
+	// vid is vlan id of port in trunk of node for trunk datapath, 0 if port is not a subport
+	vid int
+	// lock guard security groups of port, which are updated by allocation and gc
+	lock sync.Mutex
 }
 
 type PortFactory struct {
+	// client is for background refill and dispose, podClient for ports created on pod start
 	client    *neutron.Client
+	podClient *neutron.Client
 	netID     string
-	subnetID  string
-	nodeName  string
-	vmUUID    string
-	projectID string
-	ports     []*PortResource
+	// subnets select subnet for new port, subnetIDv6 is added to every port for dual stack
+	subnets     *subnetSelector
+	subnetIDv6  string
+	subnetCache map[string]*subnets.Subnet
+	// mtuCache are mtu of networks, keyed by network id
+	mtuCache map[string]int
+	// securityGroups are ids of security groups of new ports
+	securityGroups []string
+	nodeName       string
+	vmUUID         string
+	projectID      string
+	ports          []*PortResource
+	// journal record names of ports being created in pool poolName
+	journal  *pool.Journal
+	poolName string
 	sync.RWMutex
 }
 
@@ -46,31 +83,122 @@ func (p *PortResource) GetType() string {
 }
 
 func (p *PortResource) GetIPAddress() string {
-	return p.port.IP
+	if len(p.port.IP) > 0 {
+		return p.port.IP
+	}
+	return p.port.IPv6
+}
+
+// SecurityGroups return ids of security groups of port
+func (p *PortResource) SecurityGroups() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string{}, p.port.Sgs...)
+}
+
+// subnetID return the subnet port allocated from, ipv6 subnet for ipv6 only port
+func (p *PortResource) subnetID() string {
+	if len(p.port.SubnetID) > 0 {
+		return p.port.SubnetID
+	}
+	return p.port.SubnetIDv6
+}
+
+// HasIPAddress check whether ip is one of ipv4 or ipv6 address of port
+func (p *PortResource) HasIPAddress(ip string) bool {
+	addr := net.ParseIP(ip)
+	return addr != nil && (addr.Equal(net.ParseIP(p.port.IP)) || addr.Equal(net.ParseIP(p.port.IPv6)))
 }
 
+// Create create port from subnets of node for pod, the next subnet is tried when a subnet is exhausted.
+// port with specified ip is created from the subnet contains the ip
 func (f *PortFactory) Create(ip string) (types.NetworkResource, error) {
-	opts := neutron.CreateOpts{
-		Name:        fmt.Sprintf("rubble-port-%s", types.RandomString(10)),
-		NetworkID:   f.netID,
-		SubnetID:    f.subnetID,
-		DeviceOwner: DeviceOwner,
+	return f.CreateContext(context.Background(), ip)
+}
+
+// CreateContext create port for pod like Create. requests are not aborted when ctx is done because neutron
+// may create the port after the request is aborted, the port is returned and put to idle by pool.
+// next subnet is not tried after ctx is done
+func (f *PortFactory) CreateContext(ctx context.Context, ip string) (types.NetworkResource, error) {
+	return f.createWith(ctx, f.podClient, ip)
+}
+
+func (f *PortFactory) createWith(ctx context.Context, client *neutron.Client, ip string) (types.NetworkResource, error) {
+	candidates := f.subnets.candidates()
+	ipv6Address := len(ip) > 0 && len(f.subnetIDv6) > 0 && net.ParseIP(ip).To4() == nil
+	if len(ip) > 0 && !ipv6Address {
+		sb := f.subnets.forIP(ip)
+		if sb == nil {
+			return nil, fmt.Errorf("ip address %s is not in any subnet of node", ip)
+		}
+		candidates = []*subnets.Subnet{sb}
 	}
 
-	if len(ip) > 0 {
-		opts.IPAddress = ip
+	var err error
+	for _, sb := range candidates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		opts := f.createOpts(sb)
+		if ipv6Address {
+			opts.IPv6Address = ip
+		} else {
+			opts.IPAddress = ip
+		}
+
+		var res types.NetworkResource
+		f.journal.Record(f.poolName, opts.Name, pool.JournalCreating, time.Time{})
+		res, err = f.create(client, opts)
+		if !isContextDone(err) {
+			// port may be created by neutron after request is aborted, it is recovered by journal
+			f.journal.Forget(f.poolName, opts.Name)
+		}
+		if err == nil {
+			f.subnets.markAvailable(sb.ID)
+			return res, nil
+		}
+		exhausted, retry := f.subnetExhaustion(err, sb)
+		if len(ip) > 0 || !retry {
+			return nil, err
+		}
+		if len(exhausted) == 0 {
+			logger.Warnf("no address left for port from subnet %s or ipv6 subnet %s, try next subnet", sb.ID, f.subnetIDv6)
+			continue
+		}
+		logger.Warnf("subnet %s is exhausted, try next subnet", exhausted)
+		f.subnets.markExhausted(exhausted)
 	}
+	return nil, err
+}
+
+func (f *PortFactory) createOpts(sb *subnets.Subnet) *neutron.CreateOpts {
+	return &neutron.CreateOpts{
+		Name:           fmt.Sprintf("rubble-port-%s", types.RandomString(10)),
+		NetworkID:      f.netID,
+		SubnetID:       sb.ID,
+		SubnetIDv6:     f.subnetIDv6,
+		SecurityGroups: f.securityGroups,
+		DeviceOwner:    DeviceOwner,
+		Tags:           VMTag(f.vmUUID),
+	}
+}
 
-	port, err := f.client.CreatePort(&opts)
+func (f *PortFactory) create(client *neutron.Client, opts *neutron.CreateOpts) (types.NetworkResource, error) {
+	port, err := client.CreatePort(opts)
 	if err != nil {
 		logger.Errorf("failed to create port with error: %s", err)
 		return nil, err
 	}
 
-	err = f.client.AddTag("ports", port.ID, fmt.Sprintf("%s:%s", VMTagPrefix, f.vmUUID))
+	// port is created, finish it even if request is cancelled so that it can be put to idle
+	client = client.WithContext(context.Background())
+	err = client.AddTag("ports", port.ID, VMTag(f.vmUUID))
 	if err != nil {
-		fmt.Errorf("failed to add tag to port:%s with error %w", port.ID, err)
-		return nil, err
+		// port without tag is not restored after restart
+		if derr := client.DeletePort(port.ID); derr != nil {
+			logger.Errorf("failed to delete port %s with error: %s", port.ID, derr)
+		}
+		return nil, fmt.Errorf("failed to add tag to port:%s with error %w", port.ID, err)
 	}
 
 	p := &PortResource{
@@ -80,21 +208,30 @@ func (f *PortFactory) Create(ip string) (types.NetworkResource, error) {
 	f.Lock()
 	f.ports = append(f.ports, p)
 	f.Unlock()
+	f.subnets.addUsage(p.subnetID(), 1)
 
 	return p, nil
 }
 
-func (f *PortFactory) Dispose(res types.NetworkResource) (err error) {
+func (f *PortFactory) Dispose(res types.NetworkResource) error {
+	return f.DisposeContext(context.Background(), res)
+}
+
+// DisposeContext delete port like Dispose, request is aborted when ctx is done
+func (f *PortFactory) DisposeContext(ctx context.Context, res types.NetworkResource) (err error) {
 	defer func() {
 		logger.Debugf("dispose result: %v, error: %v", res.GetResourceId(), err != nil)
 	}()
 
 	f.Lock()
-	err = f.client.DeletePort(res.GetResourceId())
+	defer f.Unlock()
+	err = f.client.WithContext(ctx).DeletePort(res.GetResourceId())
 	if err != nil {
 		return fmt.Errorf("failed to delete port with error: %w", err)
 	}
-	f.Unlock()
+	if p, ok := res.(*PortResource); ok {
+		f.subnets.addUsage(p.subnetID(), -1)
+	}
 	return nil
 }
 
@@ -102,20 +239,60 @@ func (f *PortFactory) GetClient() *neutron.Client {
 	return f.client
 }
 
+// GetConfig return network and the subnet new port is created from
 func (f *PortFactory) GetConfig() (string, string) {
-	return f.netID, f.subnetID
+	return f.netID, f.subnets.candidates()[0].ID
 }
 
 type PortResourceManager struct {
 	factory *PortFactory
 	pool    pool.ObjectPool
+
+	config       *types.DaemonConfigure
+	client       *neutron.Client
+	k8s          *k8s.K8s
+	portsMapping map[string][]string
+	// reserved are ports of ip pools attached to this node, keyed by port id
+	reservedLock sync.Mutex
+	reserved     map[string]*reservedPort
+	// subnetIDs map names and ids of subnets pods may select by SubnetAnnotation to ids, subnetPools
+	// are pools of them keyed by subnet id
+	subnetIDs   map[string]string
+	subnetLock  sync.Mutex
+	subnetPools map[string]*subnetPool
+	// securityGroups are ids of security groups in config, allowedSecurityGroups are ids of security
+	// groups pods can select by annotation. sgIDs cache ids of security group names, policyGroups are
+	// ids of security groups created for network policies
+	securityGroups        []string
+	allowedSecurityGroups map[string]bool
+	sgLock                sync.Mutex
+	sgIDs                 map[string]string
+	policyGroups          map[string]bool
+	// journal is write-ahead log of pools, it is replayed when pools are created
+	journal *pool.Journal
+	// policies reclaim idle ports of pools
+	policies []pool.Policy
+	// trunk of node which ports of pods are added into for trunk datapath, nil for other datapaths
+	trunk *nodeTrunk
+}
+
+// portPool is pool of ports created by factory
+type portPool struct {
+	factory *PortFactory
+	pool    pool.ObjectPool
+}
+
+// subnetPool is pool of subnet selected by annotation, ready is closed after pool is created or failed
+type subnetPool struct {
+	*portPool
+	ready chan struct{}
+	err   error
 }
 
 func NetConfFromPort(p *PortResource) ([]*rpc.NetConf, error) {
 	var netConf []*rpc.NetConf
 
 	port := p.port
-	logger.Infof("############# neutron port value is %+v", port)
 	// call api to get eni info
 	podIP := &rpc.IPSet{}
 	cidr := &rpc.IPSet{}
@@ -124,15 +301,27 @@ func NetConfFromPort(p *PortResource) ([]*rpc.NetConf, error) {
 	podIP.IPv4 = port.IP
 	cidr.IPv4 = port.CIDR
 	gw.IPv4 = port.Gateway
+	podIP.IPv6 = port.IPv6
+	cidr.IPv6 = port.CIDRv6
+	gw.IPv6 = port.GatewayV6
 
-	if cidr.IPv4 == "" || gw.IPv4 == "" {
+	if podIP.IPv4 == "" && podIP.IPv6 == "" {
+		return nil, fmt.Errorf("no ip address for port %s", port.ID)
+	}
+	if podIP.IPv4 != "" && (cidr.IPv4 == "" || gw.IPv4 == "") {
 		return nil, fmt.Errorf("empty cidr or gateway")
 	}
+	if podIP.IPv6 != "" && (cidr.IPv6 == "" || gw.IPv6 == "") {
+		return nil, fmt.Errorf("empty ipv6 cidr or gateway")
+	}
 
 	eniInfo := &rpc.ENIInfo{
-		MAC: port.MAC,
+		MAC:   port.MAC,
+		Trunk: p.vid > 0,
+		Vid:   uint32(p.vid),
 		GatewayIP: &rpc.IPSet{
 			IPv4: port.Gateway,
+			IPv6: port.GatewayV6,
 		},
 	}
 
@@ -143,121 +332,565 @@ func NetConfFromPort(p *PortResource) ([]*rpc.NetConf, error) {
 			GatewayIP: gw,
 		},
 		ENIInfo: eniInfo,
+		MTU:     int32(port.MTU),
 	})
 
 	return netConf, nil
 }
 
-func NewPortResourceManager(config *types.DaemonConfigure, client *neutron.Client, portsMapping map[string][]string) (ResourceManager, error) {
+func NewPortResourceManager(config *types.DaemonConfigure, client *neutron.Client, k8sClient *k8s.K8s, portsMapping map[string][]string, journal *pool.Journal) (ResourceManager, error) {
 
 	netId, err := client.GetNetworkID(config.NetID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network id with: %s, error is: %w", config.NetID, err)
 	}
-	logger.Infof("********Net ID is: %s ******", netId)
+	logger.Infof("network id is %s", netId)
 
-	subnetId, err := client.GetSubnetworkID(config.SubnetID)
+	sbs, err := resolveSubnets(config, client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subnet with: %s, error is: %w", config.SubnetID, err)
+		return nil, err
 	}
-	logger.Infof("********Sub Net ID is: %s ******", subnetId)
-	subnet, err := client.GetSubnet(subnetId)
+	selector, err := newSubnetSelector(config.SubnetPolicy, sbs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subnet with error: %w", err)
+		return nil, err
+	}
+	policies, err := pool.NewPolicies(config.PoolPolicy)
+	if err != nil {
+		return nil, err
 	}
-	logger.Infof("********SubNet is: %+v ******", subnet)
 
-	factory := &PortFactory{
-		client:    client,
-		netID:     netId,
-		subnetID:  subnetId,
-		nodeName:  config.Node.Name,
-		vmUUID:    config.Node.UUID,
-		projectID: config.Node.ProjectID,
-		ports:     []*PortResource{},
-	}
-
-	poolCfg := pool.PoolConfig{
-		MaxIdle:     config.MaxIdleSize,
-		MinIdle:     config.MinIdleSize,
-		MaxPoolSize: config.MaxPoolSize,
-		MinPoolSize: config.MinPoolSize,
-		Capacity:    config.MaxPoolSize,
-
-		Factory: factory,
-		Initializer: func(holder pool.ResourceHolder) error {
-			//(TODO) 把initializer 放到外面
-
-			// get all ports assigned to this node
-			f := neutron.ListFilter{
-				NetworkID:   netId,
-				DeviceOwner: DeviceOwner,
-				Tags:        fmt.Sprintf("%s:%s", VMTagPrefix, config.Node.UUID),
+	subnetIdv6 := ""
+	if len(config.IPv6SubnetID) > 0 {
+		subnetIdv6, err = client.GetSubnetworkID(config.IPv6SubnetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ipv6 subnet with: %s, error is: %w", config.IPv6SubnetID, err)
+		}
+		subnetV6, err := client.GetSubnet(subnetIdv6)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ipv6 subnet with error: %w", err)
+		}
+		if subnetV6.IPVersion != 6 {
+			return nil, fmt.Errorf("subnet %s is not an ipv6 subnet", config.IPv6SubnetID)
+		}
+		logger.Infof("ipv6 subnet %s, cidr %s", subnetV6.ID, subnetV6.CIDR)
+		sbs = append(sbs, subnetV6)
+	}
+
+	mgr := &PortResourceManager{
+		config: config,
+		// requests of manager are on pod start path, background requests of factory use low priority
+		client:       client.WithPriority(neutron.PriorityHigh),
+		k8s:          k8sClient,
+		portsMapping: portsMapping,
+		reserved:     make(map[string]*reservedPort),
+		subnetIDs:    make(map[string]string),
+		subnetPools:  make(map[string]*subnetPool),
+		sgIDs:        make(map[string]string),
+		policyGroups: make(map[string]bool),
+		journal:      journal,
+		policies:     policies,
+	}
+	mgr.securityGroups, err = mgr.resolveSecurityGroups(mgr.client, config.SecurityGroups)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := mgr.resolveSecurityGroups(mgr.client, append(config.SecurityGroups, config.AllowedSecurityGroups...))
+	if err != nil {
+		return nil, err
+	}
+	mgr.allowedSecurityGroups = make(map[string]bool, len(allowed))
+	for _, id := range allowed {
+		mgr.allowedSecurityGroups[id] = true
+	}
+	if types.GetDatapath(config.Datapath) == types.DatapathTrunk {
+		if mgr.trunk, err = newNodeTrunk(mgr.client, config, netId); err != nil {
+			return nil, err
+		}
+	}
+	factory := mgr.newFactory(netId, selector, subnetIdv6, sbs)
+	if err = mgr.resolveAnnotationSubnets(factory, sbs); err != nil {
+		return nil, err
+	}
+
+	// get all ports assigned to this node, ports of subnets selected by annotation are restored
+	// into pools of their subnets
+	ports, err := client.ListPortWithFilter(mgr.nodePortFilter())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports allocated by this node %s with error: %w", config.Node.Name, err)
+	}
+	var restored []*PortResource
+	var detached []string
+	subnetRestored := make(map[string][]*PortResource)
+	for _, np := range ports {
+		port, err := factory.convertPort(np)
+		if err != nil {
+			return nil, err
+		}
+		p := &PortResource{port: port}
+		// port left attached to node by a release interrupted is detached before it is put into pool,
+		// ports in use keep vlan ids of their subports, or are added into trunk again
+		_, inUse := portsMapping[np.ID]
+		if inUse && mgr.trunk != nil {
+			var ok bool
+			if p.vid, ok = mgr.trunk.vid(np.ID); !ok {
+				if err = mgr.attach(mgr.client, p); err != nil {
+					return nil, err
+				}
 			}
-			ports, err := client.ListPortWithFilter(f)
-			if err != nil {
-				return fmt.Errorf("failed to list ports allocated by this node %s with error: %w", config.Node.Name, err)
+		}
+		if !inUse && ((mgr.eni() && np.DeviceID == config.Node.UUID) || mgr.trunk != nil) {
+			if err = mgr.detach(p); err != nil {
+				return nil, err
+			}
+		}
+		if ipPool := ipPoolOfPort(np.Tags); len(ipPool) > 0 {
+			// ports of ip pool are not put into pool, detach those not used by pod any more
+			mgr.reserved[np.ID] = &reservedPort{PortResource: p, ipPool: ipPool}
+			if _, ok := portsMapping[np.ID]; !ok {
+				detached = append(detached, np.ID)
 			}
+		} else if selector.has(p.subnetID()) {
+			restored = append(restored, p)
+		} else {
+			subnetRestored[p.subnetID()] = append(subnetRestored[p.subnetID()], p)
+		}
+	}
 
-			// loop ports to initialize pool inUse and idle
-			for _, np := range ports {
-				pod, ok := portsMapping[np.ID]
-				p := &PortResource{
-					port: client.ConvertPort(subnet, np),
-				}
+	pp, err := mgr.newPortPool(pool.DefaultPoolName, factory, restored)
+	if err != nil {
+		return nil, err
+	}
+	mgr.factory = pp.factory
+	mgr.pool = pp.pool
+
+	for id, restored := range subnetRestored {
+		sb, err := factory.getSubnet(id)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("restore %d ports of subnet %s", len(restored), id)
+		pp, err := mgr.newSubnetPool(sb, restored)
+		if err != nil {
+			return nil, err
+		}
+		ready := make(chan struct{})
+		close(ready)
+		mgr.subnetPools[id] = &subnetPool{portPool: pp, ready: ready}
+	}
 
-				// update ports list in factory
-				factory.ports = append(factory.ports, p)
+	for _, id := range detached {
+		if err = mgr.releasePoolAddress(id); err != nil {
+			logger.Errorf("failed to release port %s of ip pool with error: %s", id, err)
+		}
+	}
 
-				if ok {
-					logger.Infof("** port %s in using by pod %s, add it into insue", np.ID, pod)
-					holder.AddInuse(p)
-				} else {
-					logger.Infof("!!!!! port %s is not using by any pod add it into idle", np.ID)
-					holder.AddIdle(p)
-				}
+	return mgr, nil
+}
+
+func (m *PortResourceManager) newFactory(netID string, selector *subnetSelector, subnetIDv6 string, sbs []*subnets.Subnet) *PortFactory {
+	factory := &PortFactory{
+		client:         m.client.WithPriority(neutron.PriorityLow),
+		podClient:      m.client,
+		netID:          netID,
+		subnets:        selector,
+		subnetIDv6:     subnetIDv6,
+		subnetCache:    make(map[string]*subnets.Subnet),
+		mtuCache:       make(map[string]int),
+		securityGroups: m.securityGroups,
+		nodeName:       m.config.Node.Name,
+		vmUUID:         m.config.Node.UUID,
+		projectID:      m.config.Node.ProjectID,
+		ports:          []*PortResource{},
+		journal:        m.journal,
+	}
+	for _, sb := range sbs {
+		factory.subnetCache[sb.ID] = sb
+	}
+	return factory
+}
+
+// newPortPool create pool of factory with sizes in config
+func (m *PortResourceManager) newPortPool(name string, factory *PortFactory, restored []*PortResource) (*portPool, error) {
+	return m.newPortPoolWith(pool.PoolConfig{
+		Name:        name,
+		MaxIdle:     m.config.MaxIdleSize,
+		MinIdle:     m.config.MinIdleSize,
+		MaxPoolSize: m.config.MaxPoolSize,
+		MinPoolSize: m.config.MinPoolSize,
+		Capacity:    m.config.MaxPoolSize,
+	}, factory, restored)
+}
 
+// newPortPoolWith create pool of factory with sizes in poolCfg, restored ports are added into inuse if
+// used by pod, otherwise idle
+func (m *PortResourceManager) newPortPoolWith(poolCfg pool.PoolConfig, factory *PortFactory, restored []*PortResource) (*portPool, error) {
+	factory.poolName = poolCfg.Name
+	poolCfg.Journal = m.journal
+	poolCfg.Policies = m.policies
+	poolCfg.Factory = factory
+	poolCfg.Initializer = func(holder pool.ResourceHolder) error {
+		// loop ports to initialize pool inUse and idle
+		for _, p := range restored {
+			pod, ok := m.portsMapping[p.GetResourceId()]
+
+			// update ports list in factory
+			factory.ports = append(factory.ports, p)
+			factory.subnets.addUsage(p.subnetID(), 1)
+
+			if ok {
+				logger.Debugf("restore port %s in use by pod %s", p.GetResourceId(), pod)
+				holder.AddInuse(p)
+			} else {
+				logger.Debugf("restore port %s not used by any pod as idle", p.GetResourceId())
+				holder.AddIdle(p)
 			}
-			return nil
-		},
+		}
+		return nil
 	}
-	pool, err := pool.NewSimpleObjectPool(poolCfg)
+	objPool, err := pool.NewSimpleObjectPool(poolCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	mgr := &PortResourceManager{
+	return &portPool{
 		factory: factory,
-		pool:    pool,
+		pool:    objPool,
+	}, nil
+}
+
+// newSubnetPool create pool for subnet selected by annotation. the pool keeps no idle ports besides
+// those for recent allocations and holds at most SubnetPoolSize ports
+func (m *PortResourceManager) newSubnetPool(sb *subnets.Subnet, restored []*PortResource) (*portPool, error) {
+	selector, err := newSubnetSelector(SubnetPolicyOrdered, []*subnets.Subnet{sb})
+	if err != nil {
+		return nil, err
+	}
+	size := m.config.SubnetPoolSize
+	if size <= 0 {
+		size = defaultSubnetPoolSize
 	}
+	maxIdle := m.config.MaxIdleSize
+	if maxIdle <= 0 || maxIdle > size {
+		maxIdle = size
+	}
+	factory := m.newFactory(sb.NetworkID, selector, "", []*subnets.Subnet{sb})
+	pp, err := m.newPortPoolWith(pool.PoolConfig{
+		Name:        "subnet:" + sb.ID,
+		MaxIdle:     maxIdle,
+		MaxPoolSize: size,
+		Capacity:    size,
+	}, factory, restored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool for subnet %s with error: %w", sb.ID, err)
+	}
+	return pp, nil
+}
 
-	return mgr, nil
+// resolveAnnotationSubnets map names and ids of subnets of node and AnnotationSubnets to ids, pods can
+// only select these subnets by annotation
+func (m *PortResourceManager) resolveAnnotationSubnets(factory *PortFactory, sbs []*subnets.Subnet) error {
+	for _, sb := range sbs {
+		m.subnetIDs[sb.ID] = sb.ID
+		if len(sb.Name) > 0 {
+			m.subnetIDs[sb.Name] = sb.ID
+		}
+	}
+	for _, name := range m.config.AnnotationSubnets {
+		id, err := m.client.GetSubnetworkID(name)
+		if err != nil {
+			return fmt.Errorf("failed to get subnet with: %s, error is: %w", name, err)
+		}
+		if len(id) == 0 {
+			return fmt.Errorf("subnet %s not found", name)
+		}
+		sb, err := factory.getSubnet(id)
+		if err != nil {
+			return err
+		}
+		if sb.IPVersion == 6 {
+			return fmt.Errorf("ipv6 subnet %s can not be selected by annotation", name)
+		}
+		m.subnetIDs[name] = id
+		m.subnetIDs[id] = id
+		if len(sb.Name) > 0 {
+			m.subnetIDs[sb.Name] = id
+		}
+	}
+	return nil
+}
+
+// podAnnotation return annotation of pod, or annotation of namespace of pod if pod has no such annotation
+func podAnnotation(ctx *ResourceContext, key string) string {
+	if ctx.Pod != nil {
+		if value := ctx.Pod.Annotations[key]; len(value) > 0 {
+			return value
+		}
+	}
+	if ctx.Namespace != nil {
+		return ctx.Namespace.Annotations[key]
+	}
+	return ""
+}
+
+// poolForSubnet return pool of subnet, default pool is returned if subnet is empty or one of subnets
+// of node. pool of subnet selected by annotation is created at first use if create, it is created
+// outside of subnetLock and concurrent callers wait for it until ctx is done
+func (m *PortResourceManager) poolForSubnet(ctx context.Context, subnet string, create bool) (*portPool, error) {
+	defaultPool := &portPool{factory: m.factory, pool: m.pool}
+	if len(subnet) == 0 {
+		return defaultPool, nil
+	}
+	id, ok := m.subnetIDs[subnet]
+	if !ok {
+		return nil, fmt.Errorf("subnet %s is not allowed to be selected by annotation", subnet)
+	}
+	if m.factory.subnets.has(id) {
+		return defaultPool, nil
+	}
+
+	m.subnetLock.Lock()
+	sp, ok := m.subnetPools[id]
+	if !ok {
+		if !create {
+			m.subnetLock.Unlock()
+			return nil, nil
+		}
+		sp = &subnetPool{ready: make(chan struct{})}
+		m.subnetPools[id] = sp
+	}
+	m.subnetLock.Unlock()
+
+	if !ok {
+		sp.portPool, sp.err = m.createSubnetPool(id)
+		if sp.err != nil {
+			m.subnetLock.Lock()
+			delete(m.subnetPools, id)
+			m.subnetLock.Unlock()
+		}
+		close(sp.ready)
+	}
+	select {
+	case <-sp.ready:
+		return sp.portPool, sp.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("pool of subnet %s is not ready: %w", id, ctx.Err())
+	}
+}
+
+func (m *PortResourceManager) createSubnetPool(id string) (*portPool, error) {
+	sb, err := m.factory.getSubnet(id)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("create pool for subnet %s of network %s", sb.ID, sb.NetworkID)
+	return m.newSubnetPool(sb, nil)
+}
+
+// pools return default pool and pools of subnets, pools being created are not included
+func (m *PortResourceManager) pools() []*portPool {
+	m.subnetLock.Lock()
+	defer m.subnetLock.Unlock()
+
+	ret := []*portPool{{factory: m.factory, pool: m.pool}}
+	for _, sp := range m.subnetPools {
+		select {
+		case <-sp.ready:
+			if sp.err == nil {
+				ret = append(ret, sp.portPool)
+			}
+		default:
+		}
+	}
+	return ret
+}
+
+// Close stop refill and dispose of all pools, ports in pools are restored on next start
+func (m *PortResourceManager) Close(ctx context.Context) error {
+	var lastErr error
+	for _, pp := range m.pools() {
+		if err := pp.pool.Close(ctx); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// poolOf return pool which manages the resource
+func (m *PortResourceManager) poolOf(resId string) (*portPool, error) {
+	for _, pp := range m.pools() {
+		if err := pp.pool.Stat(resId); err == nil {
+			return pp, nil
+		}
+	}
+	return nil, pool.ErrNotFound
+}
+
+// clientFor return client bound to context of request, requests are aborted when caller is gone
+func (m *PortResourceManager) clientFor(ctx *ResourceContext) *neutron.Client {
+	if ctx == nil || ctx.Context == nil {
+		return m.client
+	}
+	return m.client.WithContext(ctx.Context)
 }
 
 func (m *PortResourceManager) Allocate(ctx *ResourceContext, resId string) (types.NetworkResource, error) {
+	sgs, err := m.podSecurityGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.attaching() && requireStaticIP(ctx) {
+		return nil, fmt.Errorf("static ip and ip pool are not supported by %s datapath", m.config.Datapath)
+	}
+	res, err := m.allocate(ctx, resId)
+	if err != nil {
+		return nil, err
+	}
+	err = m.ensureSecurityGroups(m.clientFor(ctx), res.(*PortResource), sgs)
+	if err == nil {
+		err = m.attach(m.clientFor(ctx), res)
+	}
+	if err != nil {
+		if rerr := m.Release(nil, res.GetResourceId()); rerr != nil {
+			logger.Errorf("failed to release port %s with error: %s", res.GetResourceId(), rerr)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *PortResourceManager) allocate(ctx *ResourceContext, resId string) (types.NetworkResource, error) {
+	pp, err := m.poolForSubnet(ctx.Context, podAnnotation(ctx, SubnetAnnotation), true)
+	if err != nil {
+		return nil, err
+	}
 	// if ip address specified
 	if requireStaticIP(ctx) {
 		logger.Infof("Allocate Static IP adresses for pod: %s", ctx.PodInfo.PodInfoKey())
-		return m.acquireStaticAddress(ctx)
+		return m.acquireStaticAddress(ctx, pp)
 	}
-	return m.pool.Acquire(ctx.Context, resId)
+	return pp.pool.Acquire(ctx.Context, resId)
 }
 
 func (m *PortResourceManager) Release(ctx *ResourceContext, resId string) error {
+	if _, ok := m.getReserved(resId); ok {
+		return m.releasePoolAddress(resId)
+	}
+	pp, err := m.poolOf(resId)
+	if err != nil {
+		return err
+	}
+	if res, ok := pp.pool.GetInUse()[resId]; ok {
+		if err = m.detach(res); err != nil {
+			return err
+		}
+	}
 	if ctx != nil && ctx.PodInfo != nil {
 		logger.Infof("@@@@@@@@@@@ POd is %s, resource ID is %s, stick time is %s", ctx.PodInfo.PodInfoKey(), resId, ctx.PodInfo.IpStickTime)
-		return m.pool.ReleaseWithReverse(resId, ctx.PodInfo.IpStickTime)
+		return pp.pool.ReleaseWithReverse(resId, ctx.PodInfo.IpStickTime)
 	}
-	return m.pool.Release(resId)
+	return pp.pool.Release(resId)
+}
+
+// Get return resource in use by pod
+func (m *PortResourceManager) Get(resId string) (types.NetworkResource, error) {
+	if res, ok := m.getReserved(resId); ok {
+		return res, nil
+	}
+	for _, pp := range m.pools() {
+		if res, ok := pp.pool.GetInUse()[resId]; ok {
+			return res, nil
+		}
+	}
+	return nil, pool.ErrNotFound
 }
 
 func (m *PortResourceManager) GarbageCollection(inUseSet map[string]interface{}, expireResSet map[string]interface{}) error {
-	for expireRes := range expireResSet {
-		if err := m.pool.Stat(expireRes); err == nil {
-			err = m.Release(nil, expireRes)
-			if err != nil {
+	for expireRes, v := range expireResSet {
+		if _, ok := m.getReserved(expireRes); ok {
+			if err := m.releasePoolAddress(expireRes); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := m.poolOf(expireRes); err == nil {
+			var ctx *ResourceContext
+			if podInfo, ok := v.(*k8s.PodInfo); ok && podInfo != nil {
+				ctx = &ResourceContext{PodInfo: podInfo}
+			}
+			err = m.Release(ctx, expireRes)
+			if err != nil && err != pool.ErrInvalidState {
+				return err
+			}
+		}
+	}
+
+	for _, pp := range m.pools() {
+		for resId, res := range pp.pool.GetInUse() {
+			if _, ok := inUseSet[resId]; ok {
+				continue
+			}
+			if _, ok := expireResSet[resId]; ok {
+				continue
+			}
+			logger.Infof("gc: port %s is in use by pool but not used by any pod, release it", resId)
+			if err := m.detach(res); err != nil {
 				return err
 			}
+			if err := pp.pool.Release(resId); err != nil && err != pool.ErrInvalidState {
+				return err
+			}
+		}
+	}
+
+	for _, resId := range m.reservedIDs() {
+		if _, ok := inUseSet[resId]; ok {
+			continue
+		}
+		logger.Infof("gc: port %s of ip pool is not used by any pod, release it", resId)
+		if err := m.releasePoolAddress(resId); err != nil {
+			return err
+		}
+	}
+
+	return m.gcOrphanPorts()
+}
+
+// gcOrphanPorts delete neutron ports tagged with this node but not managed by any pool
+func (m *PortResourceManager) gcOrphanPorts() error {
+	// snapshot before listing, ports added into pools after listing are not tagged in the list
+	pooled := m.pooledPorts()
+	reserved := m.reservedIDs()
+
+	ports, err := m.factory.client.ListPortWithFilter(m.nodePortFilter())
+	if err != nil {
+		return fmt.Errorf("failed to list ports allocated by this node %s with error: %w", m.factory.nodeName, err)
+	}
+
+	tagged := make(map[string]bool)
+	for _, p := range ports {
+		tagged[p.ID] = true
+	}
+	m.dropTakenOverPorts(pooled, reserved, tagged)
+	handedOver := m.handOverPorts(ports)
+
+	for _, p := range ports {
+		if handedOver[p.ID] {
+			continue
+		}
+		if _, err := m.poolOf(p.ID); err == nil {
+			continue
+		}
+		// ports of ip pool are managed by controller
+		if len(ipPoolOfPort(p.Tags)) > 0 {
+			continue
+		}
+		// port may be creating by factory and not added into pool yet
+		if time.Since(p.CreatedAt) < orphanPortGracePeriod {
+			continue
+		}
+		logger.Infof("gc: port %s is tagged with this node but not in pool, delete it", p.ID)
+		if err := m.factory.client.DeletePort(p.ID); err != nil {
+			logger.Errorf("gc: failed to delete orphan port %s with error: %s", p.ID, err)
 		}
 	}
 	return nil
@@ -268,11 +901,15 @@ func requireStaticIP(ctx *ResourceContext) bool {
 	return len(annotations[IpAddressAnnotation]) > 0 || len(annotations[IpPoolAnnotation]) > 0
 }
 
-func (m *PortResourceManager) acquireStaticAddress(ctx *ResourceContext) (types.NetworkResource, error) {
+func (m *PortResourceManager) acquireStaticAddress(ctx *ResourceContext, pp *portPool) (types.NetworkResource, error) {
+	if name := ctx.Pod.Annotations[IpPoolAnnotation]; len(name) > 0 {
+		return m.acquirePoolAddress(ctx, name)
+	}
+
 	ipAddress := ctx.Pod.Annotations[IpAddressAnnotation]
 
 	filter := neutron.ListFilter{
-		NetworkID:   m.factory.netID,
+		NetworkID:   pp.factory.netID,
 		DeviceOwner: DeviceOwner,
 	}
 
@@ -283,50 +920,53 @@ func (m *PortResourceManager) acquireStaticAddress(ctx *ResourceContext) (types.
 		}
 
 		//get all allocated ports
-		ports, err := m.factory.client.ListPortWithFilter(filter)
+		allocated, err := m.clientFor(ctx).ListPortWithFilter(filter)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list ports allocated by rubble with error: %w", err)
 		}
 
-		occupied := false
+		var occupied *ports.Port
 		// if ip existing return port else create new port with ip address
-		subnet, err := m.factory.client.GetSubnet(m.factory.subnetID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get subnet with error: %w", err)
-		}
-		logger.Infof("********SubNet is: %+v ******", subnet)
-		for _, p := range ports {
-			port := m.factory.client.ConvertPort(subnet, p)
-			if port.IP == ipAddress {
+		for i, p := range allocated {
+			np, err := pp.factory.convertPort(p)
+			if err != nil {
+				return nil, err
+			}
+			port := &PortResource{port: np}
+			if port.HasIPAddress(ipAddress) {
 				logger.Infof("IP address %s is occupied by port %+v", ipAddress, p)
-				occupied = true
+				occupied = &allocated[i]
 				break
 			}
 		}
 
-		if occupied {
-			idle := m.pool.GetIdle()
+		if occupied != nil {
+			idle := pp.pool.GetIdle()
 			if len(idle) > 0 {
 				for _, item := range idle {
 					if item != nil {
-						if item.GetResource().GetIPAddress() == ipAddress {
+						if item.GetResource().(*PortResource).HasIPAddress(ipAddress) {
 							logger.Infof("VVVVVV If occupied by by idel item %+v", item.GetResource())
-							return m.pool.Acquire(ctx.Context, item.GetResource().GetResourceId())
+							return pp.pool.Acquire(ctx.Context, item.GetResource().GetResourceId())
 						}
 					}
 				}
 			}
-			return nil, fmt.Errorf("IP address %s is occupied by port but not in pool idle queue", ipAddress)
+			// port may be attached to other node by pod rescheduled to this node
+			return m.takeOverPort(ctx, pp, *occupied, ipAddress)
 		} else {
 			// create port with specified ip address
-			res, err := m.factory.Create(ipAddress)
+			res, err := pp.factory.CreateContext(ctx.Context, ipAddress)
 			if err != nil {
 				logger.Errorf("error create port with ip address %s, with error: %+v", ipAddress, err)
 			} else {
-				logger.Infof("add resource %s to pool idle", res.GetResourceId())
-				// add to idle and acquire
-				m.pool.AddIdle(res)
-				return m.pool.Acquire(ctx.Context, res.GetResourceId())
+				if err = pp.pool.Adopt(ctx.Context, res); err != nil {
+					if derr := pp.factory.Dispose(res); derr != nil {
+						logger.Errorf("failed to dispose port %s with error: %s", res.GetResourceId(), derr)
+					}
+					return nil, err
+				}
+				return res, nil
 			}
 		}
 	}
@@ -0,0 +1,62 @@
+package ipam
+
+import (
+	"testing"
+
+	"github.com/rubble/pkg/neutron"
+)
+
+func TestNetConfFromPort(t *testing.T) {
+	v4 := neutron.Port{ID: "p", MAC: "fa:16:3e:00:00:01", IP: "10.0.0.5", CIDR: "10.0.0.0/24", Gateway: "10.0.0.1", MTU: 1450}
+	v6 := neutron.Port{ID: "p", MAC: "fa:16:3e:00:00:01", IPv6: "fd00::5", CIDRv6: "fd00::/64", GatewayV6: "fd00::1", MTU: 1450}
+	dual := v4
+	dual.IPv6, dual.CIDRv6, dual.GatewayV6 = v6.IPv6, v6.CIDRv6, v6.GatewayV6
+	noGatewayV6 := dual
+	noGatewayV6.GatewayV6 = ""
+
+	tests := []struct {
+		name    string
+		port    neutron.Port
+		vid     int
+		wantErr bool
+	}{
+		{name: "ipv4", port: v4},
+		{name: "ipv6 only", port: v6},
+		{name: "dual stack", port: dual},
+		{name: "subport", port: v4, vid: 100},
+		{name: "no address", port: neutron.Port{ID: "p"}, wantErr: true},
+		{name: "ipv6 without gateway", port: noGatewayV6, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := tt.port
+			confs, err := NetConfFromPort(&PortResource{port: &port, vid: tt.vid})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", confs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(confs) != 1 {
+				t.Fatalf("got %d net confs, want 1", len(confs))
+			}
+			info := confs[0].BasicInfo
+			if info.PodIP.IPv4 != port.IP || info.PodCIDR.IPv4 != port.CIDR || info.GatewayIP.IPv4 != port.Gateway {
+				t.Errorf("ipv4 = %v %v %v, want %s %s %s", info.PodIP.IPv4, info.PodCIDR.IPv4, info.GatewayIP.IPv4, port.IP, port.CIDR, port.Gateway)
+			}
+			if info.PodIP.IPv6 != port.IPv6 || info.PodCIDR.IPv6 != port.CIDRv6 || info.GatewayIP.IPv6 != port.GatewayV6 {
+				t.Errorf("ipv6 = %v %v %v, want %s %s %s", info.PodIP.IPv6, info.PodCIDR.IPv6, info.GatewayIP.IPv6, port.IPv6, port.CIDRv6, port.GatewayV6)
+			}
+			eni := confs[0].ENIInfo
+			if eni.MAC != port.MAC || eni.Trunk != (tt.vid > 0) || eni.Vid != uint32(tt.vid) {
+				t.Errorf("eni info = %v, want mac %s vid %d", eni, port.MAC, tt.vid)
+			}
+			if confs[0].MTU != int32(port.MTU) {
+				t.Errorf("mtu = %d, want %d", confs[0].MTU, port.MTU)
+			}
+		})
+	}
+}
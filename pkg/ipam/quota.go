@@ -0,0 +1,58 @@
+package ipam
+
+import (
+	"github.com/rubble/pkg/pool"
+)
+
+// PoolUsage return usage of all pools and ids of subnets they create ports from, it is reported to
+// cluster quota which shares the subnets among nodes. pools of subnets selected by annotation are
+// counted so that they do not bypass the budget
+func (m *PortResourceManager) PoolUsage() (pool.Usage, []string) {
+	var total pool.Usage
+	var ids []string
+	seen := make(map[string]bool)
+	for _, pp := range m.pools() {
+		u := pp.pool.Usage()
+		total.InUse += u.InUse
+		total.Idle += u.Idle
+		total.IdleTarget += u.IdleTarget
+		total.Capacity += u.Capacity
+		total.MaxCapacity += u.MaxCapacity
+		for _, id := range pp.factory.subnets.ids() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return total, ids
+}
+
+// SetPoolCapacity share budget granted by cluster quota among pools. pools of annotation subnets keep
+// their ports and get an even share of budget left over, default pool gets the rest
+func (m *PortResourceManager) SetPoolCapacity(capacity int) {
+	pools := m.pools()
+	usages := make([]pool.Usage, len(pools))
+	size := 0
+	for i, pp := range pools {
+		usages[i] = pp.pool.Usage()
+		size += usages[i].InUse + usages[i].Idle
+	}
+	headroom := 0
+	if capacity > size {
+		headroom = (capacity - size) / len(pools)
+	}
+
+	// pools[0] is default pool
+	left := capacity
+	for i := 1; i < len(pools); i++ {
+		u := usages[i]
+		c := u.InUse + u.Idle + headroom
+		if c > u.MaxCapacity {
+			c = u.MaxCapacity
+		}
+		pools[i].pool.SetCapacity(c)
+		left -= c
+	}
+	m.pool.SetCapacity(left)
+}
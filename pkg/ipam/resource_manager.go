@@ -3,9 +3,10 @@ package ipam
 import (
 	"context"
 	corev1 "k8s.io/api/core/v1"
-	"sync"
+	"time"
 
 	"github.com/rubble/pkg/k8s"
+	"github.com/rubble/pkg/pool"
 	types "github.com/rubble/pkg/utils"
 )
 
@@ -15,20 +16,34 @@ type ResourceItem struct {
 }
 
 type PodResources struct {
-	lock      sync.Mutex
 	Resources []ResourceItem
 	PodInfo   *k8s.PodInfo
+	// ReleasedAt is set when a pod with sticky ip released its resources,
+	// the record is kept until IpStickTime passed
+	ReleasedAt time.Time
+	// SecurityGroups are ids of security groups applied to port of pod
+	SecurityGroups []string
+	// ContainerID, NetNS and IfName are sandbox the resources are set up for, ADD repeated for the
+	// same sandbox returns the same resources and DEL of other sandboxes of the pod is ignored
+	ContainerID string
+	NetNS       string
+	IfName      string
+}
+
+// Active return whether resources are allocated to pod and not released
+func (p PodResources) Active() bool {
+	return p.PodInfo != nil && p.ReleasedAt.IsZero()
 }
 
 type ResourceContext struct {
 	Context context.Context
 	PodInfo *k8s.PodInfo
 	Pod     *corev1.Pod
+	// Namespace of pod, annotations of pod take precedence over namespace
+	Namespace *corev1.Namespace
 }
 
 func (p PodResources) GetResourceItemByType(resType string) []ResourceItem {
-	p.lock.Lock()
-	defer p.lock.Unlock()
 	var ret []ResourceItem
 	for _, r := range p.Resources {
 		if resType == r.Type {
@@ -43,5 +58,19 @@ func (p PodResources) GetResourceItemByType(resType string) []ResourceItem {
 type ResourceManager interface {
 	Allocate(context *ResourceContext, prefer string) (types.NetworkResource, error)
 	Release(context *ResourceContext, resId string) error
+	Get(resId string) (types.NetworkResource, error)
+	UpdateSecurityGroups(context *ResourceContext, resId string) ([]string, error)
+	// WarmUp create resources ahead for pods scheduled to node but not started
+	WarmUp(pending []*ResourceContext)
+	// Close stop background refill and dispose of pools until ctx is done
+	Close(ctx context.Context) error
+	// PoolUsage return usage of pools and their subnets, SetPoolCapacity apply budget of cluster quota
+	PoolUsage() (pool.Usage, []string)
+	SetPoolCapacity(capacity int)
+	// Ports list ports managed by node, DrainPool dispose idle ports of pool, all pools if name is empty,
+	// DisposePort delete port in use out of its pool
+	Ports() []PortInfo
+	DrainPool(name string, force bool) (int, error)
+	DisposePort(resId string) error
 	GarbageCollection(inUseResList map[string]interface{}, expireResList map[string]interface{}) error
 }
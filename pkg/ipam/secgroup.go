@@ -0,0 +1,144 @@
+package ipam
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rubble/pkg/neutron"
+)
+
+// podSecurityGroups return ids of security groups for pod, annotation of pod or namespace overrides config,
+// security groups of network policies selecting the pod override both. annotation can only select
+// security groups allowed by config, and groups of network policies
+func (m *PortResourceManager) podSecurityGroups(ctx *ResourceContext) ([]string, error) {
+	if ctx.Pod != nil {
+		if value := ctx.Pod.Annotations[NetworkPolicyAnnotation]; len(value) > 0 {
+			sgs, err := m.resolveSecurityGroups(m.clientFor(ctx), strings.Split(value, ","))
+			if err != nil {
+				return nil, err
+			}
+			return sgs, m.checkPolicyGroups(m.clientFor(ctx), sgs)
+		}
+	}
+	value := podAnnotation(ctx, SecurityGroupsAnnotation)
+	if len(value) == 0 {
+		return m.securityGroups, nil
+	}
+	sgs, err := m.resolveSecurityGroups(m.clientFor(ctx), strings.Split(value, ","))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range sgs {
+		if !m.allowedSecurityGroups[id] {
+			return nil, fmt.Errorf("security group %s is not allowed to be selected by annotation", id)
+		}
+	}
+	return sgs, nil
+}
+
+// checkPolicyGroups check that sgs are security groups created for network policies, groups of
+// policies are listed again when one is not known
+func (m *PortResourceManager) checkPolicyGroups(client *neutron.Client, sgs []string) error {
+	m.sgLock.Lock()
+	defer m.sgLock.Unlock()
+
+	listed := false
+	for _, id := range sgs {
+		if m.policyGroups[id] {
+			continue
+		}
+		if !listed {
+			groups, err := client.ListSecurityGroups(PolicySecurityGroupPrefix)
+			if err != nil {
+				return fmt.Errorf("failed to list security groups of network policies with error: %w", err)
+			}
+			for _, g := range groups {
+				m.policyGroups[g.ID] = true
+			}
+			listed = true
+		}
+		if !m.policyGroups[id] {
+			return fmt.Errorf("security group %s is not created for network policy", id)
+		}
+	}
+	return nil
+}
+
+// resolveSecurityGroups return ids of security groups by names or ids
+func (m *PortResourceManager) resolveSecurityGroups(client *neutron.Client, names []string) ([]string, error) {
+	m.sgLock.Lock()
+	defer m.sgLock.Unlock()
+
+	ret := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		id, ok := m.sgIDs[name]
+		if !ok {
+			var err error
+			id, err = client.GetSecurityGroupID(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get security group %s with error: %w", name, err)
+			}
+			m.sgIDs[name] = id
+		}
+		if !seen[id] {
+			seen[id] = true
+			ret = append(ret, id)
+		}
+	}
+	return ret, nil
+}
+
+// UpdateSecurityGroups update security groups of port used by pod when annotations of pod changed,
+// ids of security groups of the port are returned
+func (m *PortResourceManager) UpdateSecurityGroups(ctx *ResourceContext, resId string) ([]string, error) {
+	res, err := m.Get(resId)
+	if err != nil {
+		return nil, err
+	}
+	sgs, err := m.podSecurityGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	port := res.(*PortResource)
+	if err = m.ensureSecurityGroups(m.clientFor(ctx), port, sgs); err != nil {
+		return nil, err
+	}
+	return port.SecurityGroups(), nil
+}
+
+// ensureSecurityGroups update security groups of port if they are different from sgs,
+// idle ports keep security groups of the pod used them last time
+func (m *PortResourceManager) ensureSecurityGroups(client *neutron.Client, res *PortResource, sgs []string) error {
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	if sameSecurityGroups(res.port.Sgs, sgs) {
+		return nil
+	}
+	logger.Infof("update security groups of port %s from %v to %v", res.port.ID, res.port.Sgs, sgs)
+	if err := client.UpdatePortSecurityGroups(res.port.ID, sgs); err != nil {
+		return fmt.Errorf("failed to update security groups of port %s with error: %w", res.port.ID, err)
+	}
+	res.port.Sgs = append([]string{}, sgs...)
+	return nil
+}
+
+func sameSecurityGroups(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	for _, id := range b {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,275 @@
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/rubble/pkg/neutron"
+	types "github.com/rubble/pkg/utils"
+)
+
+const (
+	// SubnetPolicyOrdered try subnets in configured order, fallback to next one when exhausted
+	SubnetPolicyOrdered = "ordered"
+	// SubnetPolicyLeastUsed try the subnet with least ports allocated by this node first
+	SubnetPolicyLeastUsed = "least-used"
+	// SubnetPolicyZone use subnets configured for availability zone of node in order
+	SubnetPolicyZone = "zone"
+
+	subnetExhaustedCooldown = 5 * time.Minute
+)
+
+// subnetSelector choose subnet to create port from, and track ports allocated from each subnet
+type subnetSelector struct {
+	lock      sync.Mutex
+	policy    string
+	subnets   []*subnets.Subnet
+	usage     map[string]int
+	exhausted map[string]time.Time
+}
+
+func newSubnetSelector(policy string, sbs []*subnets.Subnet) (*subnetSelector, error) {
+	switch policy {
+	case "":
+		policy = SubnetPolicyOrdered
+	case SubnetPolicyOrdered, SubnetPolicyLeastUsed, SubnetPolicyZone:
+	default:
+		return nil, fmt.Errorf("unknown subnet policy: %q", policy)
+	}
+	if len(sbs) == 0 {
+		return nil, fmt.Errorf("no subnet configured")
+	}
+
+	return &subnetSelector{
+		policy:    policy,
+		subnets:   sbs,
+		usage:     make(map[string]int),
+		exhausted: make(map[string]time.Time),
+	}, nil
+}
+
+// candidates return subnets in the order to try, exhausted subnets are tried at last
+func (s *subnetSelector) candidates() []*subnets.Subnet {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ret := make([]*subnets.Subnet, len(s.subnets))
+	copy(ret, s.subnets)
+
+	if s.policy == SubnetPolicyLeastUsed {
+		sort.SliceStable(ret, func(i, j int) bool {
+			return s.usage[ret[i].ID] < s.usage[ret[j].ID]
+		})
+	}
+
+	now := time.Now()
+	sort.SliceStable(ret, func(i, j int) bool {
+		return !now.Before(s.exhausted[ret[i].ID]) && now.Before(s.exhausted[ret[j].ID])
+	})
+	return ret
+}
+
+// spread divide count ports among candidates, least-used policy gives each port to the available subnet
+// with least usage, other policies give all to the first candidate
+func (s *subnetSelector) spread(candidates []*subnets.Subnet, count int) []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ret := make([]int, len(candidates))
+	if s.policy != SubnetPolicyLeastUsed {
+		ret[0] = count
+		return ret
+	}
+	now := time.Now()
+	available := len(candidates)
+	for available > 1 && now.Before(s.exhausted[candidates[available-1].ID]) {
+		available--
+	}
+	for n := 0; n < count; n++ {
+		least := 0
+		for i := 1; i < available; i++ {
+			if s.usage[candidates[i].ID]+ret[i] < s.usage[candidates[least].ID]+ret[least] {
+				least = i
+			}
+		}
+		ret[least]++
+	}
+	return ret
+}
+
+// forIP return the configured subnet which cidr contains ip
+func (s *subnetSelector) forIP(ip string) *subnets.Subnet {
+	addr := net.ParseIP(ip)
+	for _, sb := range s.subnets {
+		_, cidr, err := net.ParseCIDR(sb.CIDR)
+		if err == nil && cidr.Contains(addr) {
+			return sb
+		}
+	}
+	return nil
+}
+
+func (s *subnetSelector) has(id string) bool {
+	for _, sb := range s.subnets {
+		if sb.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ids return ids of subnets
+func (s *subnetSelector) ids() []string {
+	ret := make([]string, 0, len(s.subnets))
+	for _, sb := range s.subnets {
+		ret = append(ret, sb.ID)
+	}
+	return ret
+}
+
+func (s *subnetSelector) markExhausted(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.exhausted[id] = time.Now().Add(subnetExhaustedCooldown)
+}
+
+func (s *subnetSelector) markAvailable(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.exhausted, id)
+}
+
+func (s *subnetSelector) addUsage(id string, delta int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.usage[id] += delta
+}
+
+// subnetExhaustion check whether neutron fails to create port from sb because no address is left.
+// retry is true if the next ipv4 subnet may help, it does not when the ipv6 subnet shared by all of them
+// is exhausted. exhausted is the ipv4 subnet to mark, empty if neutron does not name the subnet in dual
+// stack because it may be either of the two
+func (f *PortFactory) subnetExhaustion(err error, sb *subnets.Subnet) (exhausted string, retry bool) {
+	ok, id := neutron.ExhaustedSubnet(err)
+	switch {
+	case !ok:
+		return "", false
+	case len(id) > 0 && id == f.subnetIDv6:
+		return "", false
+	case len(id) > 0:
+		return id, true
+	case len(f.subnetIDv6) > 0:
+		return "", true
+	default:
+		return sb.ID, true
+	}
+}
+
+// isContextDone check whether request to neutron is aborted because ctx is done
+func isContextDone(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// getSubnet return subnet from cache or neutron
+func (f *PortFactory) getSubnet(id string) (*subnets.Subnet, error) {
+	f.RLock()
+	sb, ok := f.subnetCache[id]
+	f.RUnlock()
+	if ok {
+		return sb, nil
+	}
+
+	sb, err := f.client.GetSubnet(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subnet %s with error: %w", id, err)
+	}
+	f.Lock()
+	f.subnetCache[id] = sb
+	f.Unlock()
+	return sb, nil
+}
+
+// convertPort convert neutron port with subnets of its fixed ips
+func (f *PortFactory) convertPort(port ports.Port) (*neutron.Port, error) {
+	var sbs []*subnets.Subnet
+	for _, ip := range port.FixedIPs {
+		sb, err := f.getSubnet(ip.SubnetID)
+		if err != nil {
+			return nil, err
+		}
+		sbs = append(sbs, sb)
+	}
+	np := f.client.ConvertPort(sbs, port)
+	mtu, err := f.getMTU(port.NetworkID)
+	if err != nil {
+		return nil, err
+	}
+	np.MTU = mtu
+	return np, nil
+}
+
+// getMTU return mtu of network from cache or neutron
+func (f *PortFactory) getMTU(netID string) (int, error) {
+	f.RLock()
+	mtu, ok := f.mtuCache[netID]
+	f.RUnlock()
+	if ok {
+		return mtu, nil
+	}
+
+	mtu, err := f.client.GetNetworkMTU(netID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mtu of network %s with error: %w", netID, err)
+	}
+	f.Lock()
+	f.mtuCache[netID] = mtu
+	f.Unlock()
+	return mtu, nil
+}
+
+// resolveSubnets return subnets configured for node in order, subnets for availability zone of node
+// are used with zone policy
+func resolveSubnets(config *types.DaemonConfigure, client *neutron.Client) ([]*subnets.Subnet, error) {
+	names := append([]string{config.SubnetID}, config.Subnets...)
+	if config.SubnetPolicy == SubnetPolicyZone && config.Node != nil {
+		if zoneSubnets := config.ZoneSubnets[config.Node.AvailabilityZone]; len(zoneSubnets) > 0 {
+			names = zoneSubnets
+		} else {
+			logger.Warnf("no subnet configured for availability zone %q, use default subnets", config.Node.AvailabilityZone)
+		}
+	}
+
+	var sbs []*subnets.Subnet
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+		id, err := client.GetSubnetworkID(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subnet with: %s, error is: %w", name, err)
+		}
+		if len(id) == 0 {
+			return nil, fmt.Errorf("subnet %s not found", name)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		sb, err := client.GetSubnet(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subnet with error: %w", err)
+		}
+		logger.Debugf("subnet %s, cidr %s", sb.ID, sb.CIDR)
+		sbs = append(sbs, sb)
+	}
+	return sbs, nil
+}
@@ -0,0 +1,104 @@
+package ipam
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+)
+
+func generationFailure(subnetID string) error {
+	msg := "No more IP addresses available on network 0c7d0a4e-1f2b-4c3d-9e8f-7a6b5c4d3e2f."
+	if len(subnetID) > 0 {
+		msg = fmt.Sprintf("No more IP addresses available for subnet %s.", subnetID)
+	}
+	return gophercloud.ErrDefault409{ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{
+		Actual: 409,
+		Body:   []byte(fmt.Sprintf(`{"NeutronError": {"type": "IpAddressGenerationFailure", "message": %q}}`, msg)),
+	}}
+}
+
+func TestSubnetExhaustion(t *testing.T) {
+	const (
+		v4 = "11111111-1111-4111-8111-111111111111"
+		v6 = "66666666-6666-4666-8666-666666666666"
+	)
+	sb := &subnets.Subnet{ID: v4}
+	tests := []struct {
+		name       string
+		subnetIDv6 string
+		err        error
+		exhausted  string
+		retry      bool
+	}{
+		{name: "ipv4 subnet named", err: generationFailure(v4), exhausted: v4, retry: true},
+		{name: "network named", err: generationFailure(""), exhausted: v4, retry: true},
+		{name: "dual stack ipv4 subnet named", subnetIDv6: v6, err: generationFailure(v4), exhausted: v4, retry: true},
+		{name: "dual stack ipv6 subnet named", subnetIDv6: v6, err: generationFailure(v6)},
+		{name: "dual stack network named", subnetIDv6: v6, err: generationFailure(""), retry: true},
+		{
+			name: "other conflict",
+			err: gophercloud.ErrDefault409{ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{
+				Body: []byte(`{"NeutronError": {"type": "MacAddressInUse", "message": "in use"}}`),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &PortFactory{subnetIDv6: tt.subnetIDv6}
+			exhausted, retry := f.subnetExhaustion(tt.err, sb)
+			if exhausted != tt.exhausted || retry != tt.retry {
+				t.Errorf("subnetExhaustion() = %q, %v, want %q, %v", exhausted, retry, tt.exhausted, tt.retry)
+			}
+		})
+	}
+}
+
+func TestSpread(t *testing.T) {
+	sbs := []*subnets.Subnet{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	tests := []struct {
+		name      string
+		policy    string
+		usage     map[string]int
+		exhausted []string
+		count     int
+		want      []int
+	}{
+		{name: "ordered", policy: SubnetPolicyOrdered, count: 5, want: []int{5, 0, 0}},
+		{name: "least used even", policy: SubnetPolicyLeastUsed, count: 6, want: []int{2, 2, 2}},
+		{name: "least used fills less used", policy: SubnetPolicyLeastUsed, usage: map[string]int{"a": 4, "b": 1}, count: 4, want: []int{0, 1, 3}},
+		{name: "least used skips exhausted", policy: SubnetPolicyLeastUsed, exhausted: []string{"c"}, count: 4, want: []int{2, 2, 0}},
+		{name: "all exhausted", policy: SubnetPolicyLeastUsed, exhausted: []string{"a", "b", "c"}, count: 3, want: []int{3, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSubnetSelector(tt.policy, sbs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, n := range tt.usage {
+				s.usage[id] = n
+			}
+			for _, id := range tt.exhausted {
+				s.exhausted[id] = time.Now().Add(time.Minute)
+			}
+			candidates := s.candidates()
+			got := s.spread(candidates, tt.count)
+			// shares are in order of candidates, map them back to subnets
+			shares := make(map[string]int)
+			for i, sb := range candidates {
+				shares[sb.ID] = got[i]
+			}
+			want := make(map[string]int)
+			for i, sb := range sbs {
+				want[sb.ID] = tt.want[i]
+			}
+			if !reflect.DeepEqual(shares, want) {
+				t.Errorf("spread() = %v, want %v", shares, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package ipam
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/rubble/pkg/neutron"
+	types "github.com/rubble/pkg/utils"
+)
+
+// maxVlanID is max vlan id of subports
+const maxVlanID = 4094
+
+// nodeTrunk is trunk with port of node as parent, ports of pods are added into it as subports with
+// their own vlan ids
+type nodeTrunk struct {
+	id string
+
+	lock sync.Mutex
+	// vids are vlan ids of subports keyed by port id, including subports not added by this node
+	vids map[string]int
+}
+
+// newNodeTrunk find trunk with parent port trunkPort, or with port of node in network netID if it is
+// empty, and load its subports
+func newNodeTrunk(client *neutron.Client, config *types.DaemonConfigure, netID string) (*nodeTrunk, error) {
+	if !client.HasExtension(neutron.TrunkExtension) {
+		return nil, fmt.Errorf("neutron does not support trunk extension")
+	}
+	candidates, err := trunkParentCandidates(client, config, netID)
+	if err != nil {
+		return nil, err
+	}
+	var t *nodeTrunk
+	for _, portID := range candidates {
+		trunk, err := client.GetTrunkByPort(portID)
+		if err != nil {
+			continue
+		}
+		t = &nodeTrunk{id: trunk.ID, vids: make(map[string]int)}
+		break
+	}
+	if t == nil {
+		return nil, fmt.Errorf("no trunk found with parent port in %v", candidates)
+	}
+
+	subports, err := client.ListSubports(t.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subports of trunk %s with error: %w", t.id, err)
+	}
+	for _, sp := range subports {
+		t.vids[sp.PortID] = sp.SegmentationID
+	}
+	logger.Infof("pod ports are added into trunk %s, %d subports in trunk", t.id, len(subports))
+	return t, nil
+}
+
+// trunkParentCandidates return ids of ports which may be parent of trunk of node
+func trunkParentCandidates(client *neutron.Client, config *types.DaemonConfigure, netID string) ([]string, error) {
+	if types.IsValidUUID(config.TrunkPort) {
+		return []string{config.TrunkPort}, nil
+	}
+	f := neutron.ListFilter{Name: config.TrunkPort}
+	if len(config.TrunkPort) == 0 {
+		f = neutron.ListFilter{NetworkID: netID, DeviceID: config.Node.UUID}
+	}
+	ports, err := client.ListPortWithFilter(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candidates of trunk parent port with error: %w", err)
+	}
+	var ret []string
+	for _, p := range ports {
+		if strings.HasPrefix(p.DeviceOwner, "compute:") || len(config.TrunkPort) > 0 {
+			ret = append(ret, p.ID)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("trunk parent port %q of node not found", config.TrunkPort)
+	}
+	return ret, nil
+}
+
+// reserveVid take the lowest vlan id not used by subports of trunk for port
+func (t *nodeTrunk) reserveVid(portID string) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	used := make(map[int]bool, len(t.vids))
+	for _, vid := range t.vids {
+		used[vid] = true
+	}
+	for vid := 1; vid <= maxVlanID; vid++ {
+		if !used[vid] {
+			t.vids[portID] = vid
+			return vid, nil
+		}
+	}
+	return 0, fmt.Errorf("no vlan id left in trunk %s", t.id)
+}
+
+func (t *nodeTrunk) vid(portID string) (int, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	vid, ok := t.vids[portID]
+	return vid, ok
+}
+
+func (t *nodeTrunk) forget(portID string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.vids, portID)
+}
+
+// addSubport add port into trunk with a vlan id free in trunk
+func (t *nodeTrunk) addSubport(client *neutron.Client, p *PortResource) error {
+	vid, err := t.reserveVid(p.port.ID)
+	if err != nil {
+		return err
+	}
+	if err = client.AddSubport(t.id, p.port.ID, vid); err != nil {
+		t.forget(p.port.ID)
+		return err
+	}
+	p.vid = vid
+	return nil
+}
+
+// removeSubport remove port from trunk, a port not in trunk is not an error
+func (t *nodeTrunk) removeSubport(client *neutron.Client, p *PortResource) error {
+	if _, ok := t.vid(p.port.ID); !ok {
+		p.vid = 0
+		return nil
+	}
+	err := client.RemoveSubport(t.id, p.port.ID)
+	if err != nil {
+		if _, ok := err.(gophercloud.ErrDefault404); !ok {
+			return err
+		}
+	}
+	t.forget(p.port.ID)
+	p.vid = 0
+	return nil
+}
@@ -0,0 +1,197 @@
+package ipam
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubble/pkg/neutron"
+	"github.com/rubble/pkg/neutron/fake"
+	"github.com/rubble/pkg/pool"
+	"github.com/rubble/pkg/storage"
+	types "github.com/rubble/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const trunkVMUUID = "99999999-9999-4999-8999-999999999999"
+
+// trunkEnv is fake neutron with port of node as parent of trunk
+type trunkEnv struct {
+	server   *fake.Server
+	client   *neutron.Client
+	config   *types.DaemonConfigure
+	subnetID string
+	trunkID  string
+}
+
+func newTrunkEnv(t *testing.T) *trunkEnv {
+	t.Helper()
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+	netID := server.AddNetwork("net", 1450)
+	subnetID, err := server.AddSubnet(netID, "sub", "10.0.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := server.AddPort(fake.Port{NetworkID: netID, FixedIPs: []fake.FixedIP{{SubnetID: subnetID}},
+		DeviceID: trunkVMUUID, DeviceOwner: "compute:nova"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trunkID, err := server.AddTrunk("trunk", parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := neutron.NewClientWithAuthOptions(server.AuthOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &trunkEnv{
+		server: server,
+		client: client,
+		config: &types.DaemonConfigure{
+			NetID:       netID,
+			SubnetID:    subnetID,
+			Datapath:    types.DatapathTrunk,
+			MaxPoolSize: 5,
+			MaxIdleSize: 2,
+			Node:        &types.NodeInfo{UUID: trunkVMUUID, Name: "node", ProjectID: fake.ProjectID},
+		},
+		subnetID: subnetID,
+		trunkID:  trunkID,
+	}
+}
+
+func (e *trunkEnv) manager(t *testing.T, portsMapping map[string][]string) *PortResourceManager {
+	t.Helper()
+	mgr, err := NewPortResourceManager(e.config, e.client, nil, portsMapping, pool.NewJournal(storage.NewMemoryStorage()))
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	m := mgr.(*PortResourceManager)
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+	return m
+}
+
+// subports return vlan ids of subports of trunk keyed by port id
+func (e *trunkEnv) subports() map[string]int {
+	ret := make(map[string]int)
+	for _, sp := range e.server.Subports(e.trunkID) {
+		ret[sp.PortID] = sp.SegmentationID
+	}
+	return ret
+}
+
+func allocatePort(t *testing.T, m *PortResourceManager, pod string) *PortResource {
+	t.Helper()
+	res, err := m.Allocate(&ResourceContext{
+		Context: context.Background(),
+		Pod:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: "default"}},
+	}, "")
+	if err != nil {
+		t.Fatalf("allocate port for %s: %v", pod, err)
+	}
+	return res.(*PortResource)
+}
+
+func TestTrunkSubportVid(t *testing.T) {
+	e := newTrunkEnv(t)
+	// subport added by others keeps its vlan id
+	other, err := e.server.AddPort(fake.Port{NetworkID: e.config.NetID, FixedIPs: []fake.FixedIP{{SubnetID: e.subnetID}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.client.AddSubport(e.trunkID, other.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	m := e.manager(t, nil)
+
+	p0 := allocatePort(t, m, "p0")
+	p1 := allocatePort(t, m, "p1")
+	if p0.vid != 2 || p1.vid != 3 {
+		t.Errorf("vlan ids = %d, %d, want 2, 3", p0.vid, p1.vid)
+	}
+	want := map[string]int{other.ID: 1, p0.port.ID: 2, p1.port.ID: 3}
+	if got := e.subports(); !equalVids(got, want) {
+		t.Errorf("subports = %v, want %v", got, want)
+	}
+
+	// released port is removed from trunk, its vlan id is taken by next pod
+	if err = m.Release(nil, p0.port.ID); err != nil {
+		t.Fatalf("release p0: %v", err)
+	}
+	if p0.vid != 0 {
+		t.Errorf("vlan id of released port = %d, want 0", p0.vid)
+	}
+	if vid, ok := e.subports()[p0.port.ID]; ok {
+		t.Errorf("released port is still subport with vlan id %d", vid)
+	}
+	p2 := allocatePort(t, m, "p2")
+	if p2.vid != 2 {
+		t.Errorf("vlan id of p2 = %d, want 2 released by p0", p2.vid)
+	}
+	if vid := e.subports()[p2.port.ID]; vid != 2 {
+		t.Errorf("vlan id of p2 in trunk = %d, want 2", vid)
+	}
+
+	// port removed from trunk behind daemon is released without error
+	if err = e.client.RemoveSubport(e.trunkID, p1.port.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Release(nil, p1.port.ID); err != nil {
+		t.Errorf("release port not in trunk: %v", err)
+	}
+	if _, ok := m.trunk.vid(p1.port.ID); ok {
+		t.Errorf("vlan id of port removed from trunk is kept")
+	}
+	if got := e.subports(); !equalVids(got, map[string]int{other.ID: 1, p2.port.ID: 2}) {
+		t.Errorf("subports after release = %v", got)
+	}
+}
+
+func TestTrunkRestore(t *testing.T) {
+	e := newTrunkEnv(t)
+	m := e.manager(t, nil)
+	p0 := allocatePort(t, m, "p0")
+	p1 := allocatePort(t, m, "p1")
+	p2 := allocatePort(t, m, "p2")
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// p1 is released while daemon is down, p2 is removed from trunk behind daemon
+	if err := e.client.RemoveSubport(e.trunkID, p2.port.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	m = e.manager(t, map[string][]string{p0.port.ID: {"default/p0"}, p2.port.ID: {"default/p2"}})
+	got := e.subports()
+	if got[p0.port.ID] != p0.vid {
+		t.Errorf("vlan id of p0 after restart = %d, want %d", got[p0.port.ID], p0.vid)
+	}
+	if _, ok := got[p1.port.ID]; ok {
+		t.Errorf("port not used by pod is left in trunk")
+	}
+	vid, ok := got[p2.port.ID]
+	if !ok || vid == p0.vid {
+		t.Errorf("port of pod removed from trunk is added back with vlan id %d, ok %v", vid, ok)
+	}
+	res, err := m.Get(p2.port.ID)
+	if err != nil {
+		t.Fatalf("get p2: %v", err)
+	}
+	if res.(*PortResource).vid != vid {
+		t.Errorf("vlan id of p2 in pool = %d, want %d", res.(*PortResource).vid, vid)
+	}
+}
+
+func equalVids(got, want map[string]int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for id, vid := range want {
+		if v, ok := got[id]; !ok || v != vid {
+			return false
+		}
+	}
+	return true
+}
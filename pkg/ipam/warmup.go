@@ -0,0 +1,31 @@
+package ipam
+
+import (
+	"context"
+
+	"github.com/rubble/pkg/pool"
+)
+
+// WarmUp count pods scheduled to node but not started into pools they allocate from, pools create
+// idle ports for them before cni ADD. pods with static address or address of ip pool are skipped, so
+// are pods of subnets which have no pool yet, pools of subnets are only created by allocation
+func (m *PortResourceManager) WarmUp(pending []*ResourceContext) {
+	counts := make(map[pool.ObjectPool]int)
+	for _, ctx := range pending {
+		if requireStaticIP(ctx) {
+			continue
+		}
+		pp, err := m.poolForSubnet(context.Background(), podAnnotation(ctx, SubnetAnnotation), false)
+		if err != nil {
+			logger.Warnf("failed to get pool of pod %s/%s with error: %s", ctx.Pod.Namespace, ctx.Pod.Name, err)
+			continue
+		}
+		if pp == nil {
+			continue
+		}
+		counts[pp.pool]++
+	}
+	for _, pp := range m.pools() {
+		pp.pool.Warm(counts[pp.pool])
+	}
+}
@@ -0,0 +1,82 @@
+package ipam
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/rubble/pkg/pool"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// warmedPool record pending count set by WarmUp
+type warmedPool struct {
+	pool.ObjectPool
+	pending int
+}
+
+func (p *warmedPool) Warm(pending int) {
+	p.pending = pending
+}
+
+func pendingPod(name string, annotations map[string]string, ns *corev1.Namespace) *ResourceContext {
+	return &ResourceContext{
+		Pod:       &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}},
+		Namespace: ns,
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	const (
+		nodeSubnet  = "11111111-1111-4111-8111-111111111111"
+		extraSubnet = "22222222-2222-4222-8222-222222222222"
+		idleSubnet  = "33333333-3333-4333-8333-333333333333"
+	)
+	selector, err := newSubnetSelector("", []*subnets.Subnet{{ID: nodeSubnet}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultPool, extraPool := &warmedPool{}, &warmedPool{}
+	ready := make(chan struct{})
+	close(ready)
+	m := &PortResourceManager{
+		factory: &PortFactory{subnets: selector},
+		pool:    defaultPool,
+		subnetIDs: map[string]string{
+			"node": nodeSubnet, nodeSubnet: nodeSubnet,
+			"extra": extraSubnet, extraSubnet: extraSubnet,
+			"idle": idleSubnet, idleSubnet: idleSubnet,
+		},
+		subnetPools: map[string]*subnetPool{
+			extraSubnet: {portPool: &portPool{pool: extraPool}, ready: ready},
+		},
+	}
+	extraNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default",
+		Annotations: map[string]string{SubnetAnnotation: "extra"}}}
+
+	m.WarmUp([]*ResourceContext{
+		pendingPod("plain", nil, nil),
+		pendingPod("node-subnet", map[string]string{SubnetAnnotation: "node"}, nil),
+		// pod annotation overrides namespace
+		pendingPod("override", map[string]string{SubnetAnnotation: nodeSubnet}, extraNs),
+		pendingPod("extra", map[string]string{SubnetAnnotation: "extra"}, nil),
+		pendingPod("extra-ns", nil, extraNs),
+		// skipped: static address, ip pool, subnet without pool yet and subnet not allowed
+		pendingPod("static", map[string]string{IpAddressAnnotation: "10.0.0.10"}, nil),
+		pendingPod("ip-pool", map[string]string{IpPoolAnnotation: "web"}, extraNs),
+		pendingPod("idle", map[string]string{SubnetAnnotation: "idle"}, nil),
+		pendingPod("unknown", map[string]string{SubnetAnnotation: "unknown"}, nil),
+	})
+	if defaultPool.pending != 3 || extraPool.pending != 2 {
+		t.Errorf("pending of default pool %d, extra pool %d, want 3 and 2", defaultPool.pending, extraPool.pending)
+	}
+	if _, ok := m.subnetPools[idleSubnet]; ok {
+		t.Errorf("pool of subnet %s is created by warm up", idleSubnet)
+	}
+
+	// pools without pending pods are reset
+	m.WarmUp(nil)
+	if defaultPool.pending != 0 || extraPool.pending != 0 {
+		t.Errorf("pending after pods started: default pool %d, extra pool %d, want 0", defaultPool.pending, extraPool.pending)
+	}
+}
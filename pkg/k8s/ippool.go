@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	IPPoolKind = "RubbleIPPool"
+
+	maxIPPoolRangeSize = 65536
+)
+
+// IPPoolGVR is resource of RubbleIPPool, pools are cluster scoped
+var IPPoolGVR = schema.GroupVersionResource{
+	Group:    "rubble.kubernetes.io",
+	Version:  "v1",
+	Resource: "rubbleippools",
+}
+
+// IPPool is a set of fixed addresses in a neutron subnet, ports of addresses are pre-created
+// by controller and taken by pods with ip_pool annotation
+type IPPool struct {
+	v1.TypeMeta   `json:",inline"`
+	v1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPoolSpec   `json:"spec"`
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+type IPPoolSpec struct {
+	// Subnet is name or id of neutron subnet
+	Subnet string `json:"subnet"`
+	// Addresses are ip addresses or ranges like 192.168.1.10-192.168.1.20
+	Addresses []string `json:"addresses"`
+}
+
+type IPPoolStatus struct {
+	// Allocations keyed by ip address
+	Allocations map[string]IPAllocation `json:"allocations,omitempty"`
+}
+
+// IPAllocation record port of address and pod using it, Pod and Owner are kept after pod released
+// the address so that pod with same name gets the same address
+type IPAllocation struct {
+	PortID string `json:"portID,omitempty"`
+	// Owner is kind/name of controller of pod, e.g. StatefulSet/web
+	Owner string `json:"owner,omitempty"`
+	// Pod is namespace/name of pod
+	Pod  string `json:"pod,omitempty"`
+	Node string `json:"node,omitempty"`
+}
+
+// ListAddresses return all ip addresses in spec with ranges expanded
+func (p *IPPool) ListAddresses() ([]string, error) {
+	var ret []string
+	for _, addr := range p.Spec.Addresses {
+		addr = strings.TrimSpace(addr)
+		if !strings.Contains(addr, "-") {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q in ip pool %s", addr, p.Name)
+			}
+			ret = append(ret, ip.String())
+			continue
+		}
+
+		parts := strings.SplitN(addr, "-", 2)
+		start, end := net.ParseIP(strings.TrimSpace(parts[0])).To4(), net.ParseIP(strings.TrimSpace(parts[1])).To4()
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("invalid ipv4 range %q in ip pool %s", addr, p.Name)
+		}
+		s, e := binary.BigEndian.Uint32(start), binary.BigEndian.Uint32(end)
+		if s > e || e-s >= maxIPPoolRangeSize {
+			return nil, fmt.Errorf("invalid ipv4 range %q in ip pool %s", addr, p.Name)
+		}
+		for i := s; ; i++ {
+			ip := make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(ip, i)
+			ret = append(ret, ip.String())
+			if i == e {
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (k *K8s) GetIPPool(name string) (*IPPool, error) {
+	obj, err := k.dynamic.Resource(IPPoolGVR).Get(context.Background(), name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return convertIPPool(obj)
+}
+
+func (k *K8s) ListIPPools() ([]*IPPool, error) {
+	list, err := k.dynamic.Resource(IPPoolGVR).List(context.Background(), v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var ret []*IPPool
+	for i := range list.Items {
+		p, err := convertIPPool(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// UpdateIPPoolStatus update status of pool, a conflict error is returned if pool is modified since read
+func (k *K8s) UpdateIPPoolStatus(p *IPPool) (*IPPool, error) {
+	p.APIVersion = IPPoolGVR.GroupVersion().String()
+	p.Kind = IPPoolKind
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(p)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := k.dynamic.Resource(IPPoolGVR).UpdateStatus(context.Background(), &unstructured.Unstructured{Object: content}, v1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return convertIPPool(obj)
+}
+
+func convertIPPool(obj *unstructured.Unstructured) (*IPPool, error) {
+	p := &IPPool{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), p); err != nil {
+		return nil, fmt.Errorf("failed to convert ip pool %s with error: %w", obj.GetName(), err)
+	}
+	if p.Status.Allocations == nil {
+		p.Status.Allocations = make(map[string]IPAllocation)
+	}
+	return p, nil
+}
@@ -2,12 +2,16 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	types "github.com/rubble/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -38,7 +42,8 @@ func (p *PodInfo) PodInfoKey() string {
 var logger = log.DefaultLogger.WithField("component:", "rubble cni-server")
 
 type K8s struct {
-	client   *kubernetes.Clientset
+	client   kubernetes.Interface
+	dynamic  dynamic.Interface
 	nodeName string
 	nodeCidr *net.IPNet
 	svcCidr  *net.IPNet
@@ -51,17 +56,31 @@ type Filter struct {
 
 func NewK8s(conf string, nodeName string) (*K8s, error) {
 
-	client, err := initKubeClient(conf)
+	config, err := initKubeConfig(conf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create neutron client with error: %w", err)
+		return nil, fmt.Errorf("failed to create kubernetes config with error: %w", err)
 	}
 
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client with error: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes dynamic client with error: %w", err)
+	}
+
+	return NewK8sWithClient(client, dynamicClient, nodeName), nil
+}
+
+// NewK8sWithClient create K8s with initialized kubernetes clients, dynamic client is used for rubble crds
+func NewK8sWithClient(client kubernetes.Interface, dynamicClient dynamic.Interface, nodeName string) *K8s {
 	return &K8s{
 		client:   client,
+		dynamic:  dynamicClient,
 		nodeName: nodeName,
-	}, nil
-
-	return nil, nil
+	}
 }
 
 func (k *K8s) GetPod(namespace, name string) (*PodInfo, *corev1.Pod, error) {
@@ -73,7 +92,29 @@ func (k *K8s) GetPod(namespace, name string) (*PodInfo, *corev1.Pod, error) {
 	return podInfo, pod, nil
 }
 
+func (k *K8s) GetNamespace(name string) (*corev1.Namespace, error) {
+	return k.client.CoreV1().Namespaces().Get(context.Background(), name, v1.GetOptions{})
+}
+
+// Client return kubernetes client, controllers use it to create informers
+func (k *K8s) Client() kubernetes.Interface {
+	return k.client
+}
+
 func (k *K8s) ListLocalPods(filter *Filter) ([]*PodInfo, error) {
+	pods, err := k.ListLocalPodObjects(filter)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*PodInfo
+	for _, pod := range pods {
+		ret = append(ret, convertPod(pod))
+	}
+	return ret, nil
+}
+
+// ListLocalPodObjects return pods on this node
+func (k *K8s) ListLocalPodObjects(filter *Filter) ([]*corev1.Pod, error) {
 	var selectors []fields.Selector
 	selectors = append(selectors, fields.OneTermEqualSelector("spec.nodeName", k.nodeName))
 
@@ -83,17 +124,18 @@ func (k *K8s) ListLocalPods(filter *Filter) ([]*PodInfo, error) {
 	//}
 
 	options := v1.ListOptions{
-		LabelSelector: v1.FormatLabelSelector(v1.SetAsLabelSelector(filter.Labels)),
 		FieldSelector: fields.AndSelectors(selectors...).String(),
 	}
+	if len(filter.Labels) > 0 {
+		options.LabelSelector = v1.FormatLabelSelector(v1.SetAsLabelSelector(filter.Labels))
+	}
 	list, err := k.client.CoreV1().Pods(corev1.NamespaceAll).List(context.Background(), options)
 	if err != nil {
 		return nil, fmt.Errorf("failed listting pods on node:%s from apiserver with error: %w", k.nodeName, err)
 	}
-	var ret []*PodInfo
-	for _, pod := range list.Items {
-		info := convertPod(&pod)
-		ret = append(ret, info)
+	var ret []*corev1.Pod
+	for i := range list.Items {
+		ret = append(ret, &list.Items[i])
 	}
 
 	return ret, nil
@@ -128,7 +170,7 @@ func parseBool(s string) bool {
 	return b
 }
 
-func initKubeClient(kubeConf string) (*kubernetes.Clientset, error) {
+func initKubeConfig(kubeConf string) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 
@@ -149,10 +191,77 @@ func initKubeClient(kubeConf string) (*kubernetes.Clientset, error) {
 	config.QPS = 1000
 	config.Burst = 2000
 
-	client, err := kubernetes.NewForConfig(config)
+	return config, nil
+}
+
+// PodOwner return kind/name of controller of pod, empty if pod has no controller
+func PodOwner(pod *corev1.Pod) string {
+	if ref := v1.GetControllerOf(pod); ref != nil {
+		return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+	}
+	return ""
+}
+
+// PodExists check whether pod exists
+func (k *K8s) PodExists(namespace, name string) (bool, error) {
+	_, err := k.client.CoreV1().Pods(namespace).Get(context.Background(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// StatefulSetExists check whether statefulset exists
+func (k *K8s) StatefulSetExists(namespace, name string) (bool, error) {
+	_, err := k.client.AppsV1().StatefulSets(namespace).Get(context.Background(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListPodsWithIP return pods not terminated with ip address
+func (k *K8s) ListPodsWithIP(ip string) ([]*corev1.Pod, error) {
+	list, err := k.client.CoreV1().Pods(corev1.NamespaceAll).List(context.Background(), v1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("status.podIP", ip).String(),
+	})
 	if err != nil {
-		logger.Fatalf("Failed to create Kubernetes client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed listting pods with ip %s from apiserver with error: %w", ip, err)
+	}
+	var ret []*corev1.Pod
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Status.PodIP == ip {
+			ret = append(ret, pod)
+			continue
+		}
+		for _, podIP := range pod.Status.PodIPs {
+			if podIP.IP == ip {
+				ret = append(ret, pod)
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// SetPodAnnotation set annotation of pod, the annotation is removed if value is empty
+func (k *K8s) SetPodAnnotation(namespace, name, key, value string) error {
+	var v interface{}
+	if len(value) > 0 {
+		v = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{key: v},
+		},
+	})
+	if err != nil {
+		return err
 	}
-	return client, nil
+	_, err = k.client.CoreV1().Pods(namespace).Patch(context.Background(), name, k8stypes.MergePatchType, patch, v1.PatchOptions{})
+	return err
 }
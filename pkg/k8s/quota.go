@@ -0,0 +1,164 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// QuotaNamespace hold leases reporting usage of nodes and config map of budgets granted by controller
+	QuotaNamespace = "kube-system"
+	// QuotaConfigMapName is config map of budgets, keyed by node name
+	QuotaConfigMapName = "rubble-quota"
+	// QuotaLeasePrefix is name prefix of leases of nodes, node name follows the prefix
+	QuotaLeasePrefix = "rubble-node-"
+	// QuotaUsageAnnotation of lease is usage of node in json
+	QuotaUsageAnnotation = "rubble.kubernetes.io/usage"
+)
+
+// NodeUsage is usage of default pool reported by daemon of node
+type NodeUsage struct {
+	Node string `json:"node"`
+	// Subnets are ids of subnets pool creates ports from
+	Subnets []string `json:"subnets"`
+	InUse   int      `json:"inuse"`
+	Idle    int      `json:"idle"`
+	// Demand is ports node wants, ports in use and idle target
+	Demand int `json:"demand"`
+	// MaxCapacity is max_pool_size of node
+	MaxCapacity int `json:"maxCapacity"`
+}
+
+// NodeQuota is budget granted to node by controller
+type NodeQuota struct {
+	Capacity int `json:"capacity"`
+}
+
+// ReportNodeUsage create or renew lease of node with usage, lease expires after duration
+// if daemon stops reporting
+func (k *K8s) ReportNodeUsage(usage NodeUsage, duration time.Duration) error {
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	leases := k.client.CoordinationV1().Leases(QuotaNamespace)
+	name := QuotaLeasePrefix + usage.Node
+	seconds := int32(duration.Seconds())
+	now := v1.NewMicroTime(time.Now())
+
+	lease, err := leases.Get(context.Background(), name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        name,
+				Namespace:   QuotaNamespace,
+				Annotations: map[string]string{QuotaUsageAnnotation: string(data)},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &usage.Node,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(context.Background(), lease, v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lease %s with error: %w", name, err)
+	}
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[QuotaUsageAnnotation] = string(data)
+	lease.Spec.HolderIdentity = &usage.Node
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(context.Background(), lease, v1.UpdateOptions{})
+	return err
+}
+
+// ListNodeUsages return usages in leases of nodes not expired at now
+func (k *K8s) ListNodeUsages(now time.Time) ([]NodeUsage, error) {
+	list, err := k.client.CoordinationV1().Leases(QuotaNamespace).List(context.Background(), v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases with error: %w", err)
+	}
+	var ret []NodeUsage
+	for _, lease := range list.Items {
+		if !strings.HasPrefix(lease.Name, QuotaLeasePrefix) || leaseExpired(&lease, now) {
+			continue
+		}
+		usage := NodeUsage{}
+		if err = json.Unmarshal([]byte(lease.Annotations[QuotaUsageAnnotation]), &usage); err != nil {
+			logger.Warnf("invalid usage in lease %s: %v", lease.Name, err)
+			continue
+		}
+		ret = append(ret, usage)
+	}
+	return ret, nil
+}
+
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expire := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return now.After(expire)
+}
+
+// GetNodeQuota return budget granted to node, false if controller has not granted any
+func (k *K8s) GetNodeQuota(node string) (NodeQuota, bool, error) {
+	quota := NodeQuota{}
+	cm, err := k.client.CoreV1().ConfigMaps(QuotaNamespace).Get(context.Background(), QuotaConfigMapName, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return quota, false, nil
+	}
+	if err != nil {
+		return quota, false, fmt.Errorf("failed to get config map %s with error: %w", QuotaConfigMapName, err)
+	}
+	data, ok := cm.Data[node]
+	if !ok {
+		return quota, false, nil
+	}
+	if err = json.Unmarshal([]byte(data), &quota); err != nil {
+		return quota, false, fmt.Errorf("invalid quota of node %s: %w", node, err)
+	}
+	return quota, true, nil
+}
+
+// UpdateNodeQuotas replace budgets in config map, it is created if not found
+func (k *K8s) UpdateNodeQuotas(quotas map[string]NodeQuota) error {
+	data := make(map[string]string, len(quotas))
+	for node, quota := range quotas {
+		value, err := json.Marshal(quota)
+		if err != nil {
+			return err
+		}
+		data[node] = string(value)
+	}
+
+	configMaps := k.client.CoreV1().ConfigMaps(QuotaNamespace)
+	cm, err := configMaps.Get(context.Background(), QuotaConfigMapName, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: QuotaConfigMapName, Namespace: QuotaNamespace},
+			Data:       data,
+		}, v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get config map %s with error: %w", QuotaConfigMapName, err)
+	}
+	cm.Data = data
+	_, err = configMaps.Update(context.Background(), cm, v1.UpdateOptions{})
+	return err
+}
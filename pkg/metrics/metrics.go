@@ -0,0 +1,169 @@
+// Package metrics defines prometheus metrics of rubble daemon and serves them on /metrics
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rubble/pkg/log"
+)
+
+const (
+	namespace = "rubble"
+
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+var logger = log.DefaultLogger.WithField("component:", "rubble metrics")
+
+var (
+	// FactoryDuration is latency of creating and disposing resources by pool factory
+	FactoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "factory",
+		Name:      "duration_seconds",
+		Help:      "Latency of factory Create and Dispose.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"pool", "op", "outcome"})
+
+	// NeutronDuration is latency of openstack api requests sent by neutron client
+	NeutronDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "neutron",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of openstack api requests by operation and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"operation", "outcome"})
+
+	// NeutronRateLimitWait is time requests wait in client side rate limiter by priority
+	NeutronRateLimitWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "neutron",
+		Name:      "ratelimit_wait_seconds",
+		Help:      "Time openstack api requests wait in rate limiter by priority.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"priority"})
+
+	// NeutronThrottled count responses with 429 or 503 which pause requests
+	NeutronThrottled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "neutron",
+		Name:      "throttled_total",
+		Help:      "Openstack api responses with 429 or 503 status.",
+	})
+
+	// RPCDuration is latency of daemon rpc, e.g. AllocateIP and ReleaseIP
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "daemon",
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of daemon rpc by method and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"method", "outcome"})
+
+	// RPCErrors count failed daemon rpc by cause
+	RPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "daemon",
+		Name:      "rpc_errors_total",
+		Help:      "Failed daemon rpc by method and cause.",
+	}, []string{"method", "cause"})
+
+	// Releases count ports released by ReleaseIP by reason, e.g. cni-del and cni-add-rollback
+	Releases = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "daemon",
+		Name:      "releases_total",
+		Help:      "Ports released by ReleaseIP by reason.",
+	}, []string{"reason"})
+
+	poolIdleDesc     = poolDesc("idle", "Idle resources in pool.")
+	poolInUseDesc    = poolDesc("inuse", "Resources in use by pods.")
+	poolCapacityDesc = poolDesc("capacity", "Max resources of pool.")
+	poolTokensDesc   = poolDesc("tokens_available", "Resources can be created before pool reaches capacity.")
+
+	pools = &poolCollector{stats: make(map[string]func() PoolStats)}
+)
+
+func init() {
+	prometheus.MustRegister(FactoryDuration, NeutronDuration, NeutronRateLimitWait, NeutronThrottled, RPCDuration, RPCErrors, Releases, pools)
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, []string{"pool"}, nil)
+}
+
+// PoolStats is sizes of pool when metrics are scraped
+type PoolStats struct {
+	Idle     int
+	InUse    int
+	Capacity int
+	Tokens   int
+}
+
+// poolCollector collect stats of pools on scrape, pools are created at runtime e.g. pools of subnets
+type poolCollector struct {
+	lock  sync.Mutex
+	stats map[string]func() PoolStats
+}
+
+// RegisterPool add pool to metrics, stats is called on every scrape
+func RegisterPool(name string, stats func() PoolStats) {
+	pools.lock.Lock()
+	defer pools.lock.Unlock()
+	pools.stats[name] = stats
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolIdleDesc
+	ch <- poolInUseDesc
+	ch <- poolCapacityDesc
+	ch <- poolTokensDesc
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	names := make([]string, 0, len(c.stats))
+	for name := range c.stats {
+		names = append(names, name)
+	}
+	c.lock.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		c.lock.Lock()
+		stats := c.stats[name]
+		c.lock.Unlock()
+		s := stats()
+		ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.Idle), name)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(s.InUse), name)
+		ch <- prometheus.MustNewConstMetric(poolCapacityDesc, prometheus.GaugeValue, float64(s.Capacity), name)
+		ch <- prometheus.MustNewConstMetric(poolTokensDesc, prometheus.GaugeValue, float64(s.Tokens), name)
+	}
+}
+
+// Outcome return outcome label of error
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
+
+// ObserveFactory record latency of factory operation started at start
+func ObserveFactory(pool, op string, start time.Time, err error) {
+	FactoryDuration.WithLabelValues(pool, op, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// Serve start http server exposing /metrics on addr, it blocks until server fails
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	logger.Infof("serving metrics on %s/metrics", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,114 @@
+package neutron
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+)
+
+// BulkTagExtension allow tags in body of bulk port creation
+const BulkTagExtension = "tag-ports-during-bulk-creation"
+
+// extensionCache remember extensions supported by neutron
+type extensionCache struct {
+	lock      sync.Mutex
+	supported map[string]bool
+}
+
+// HasExtension check whether neutron supports extension, the result is cached unless neutron fails
+func (c Client) HasExtension(alias string) bool {
+	c.extensions.lock.Lock()
+	defer c.extensions.lock.Unlock()
+	if supported, ok := c.extensions.supported[alias]; ok {
+		return supported
+	}
+
+	_, err := extensions.Get(c.network(), alias).Extract()
+	var notFound gophercloud.ErrDefault404
+	switch {
+	case err == nil:
+		c.extensions.supported[alias] = true
+	case errors.As(err, &notFound):
+		c.extensions.supported[alias] = false
+	default:
+		return false
+	}
+	return c.extensions.supported[alias]
+}
+
+// CreatePorts create ports in one request, neutron creates all of them or none. Tags in opts are
+// set if neutron supports BulkTagExtension, otherwise they are ignored and caller should tag ports
+func (c Client) CreatePorts(opts []*CreateOpts) ([]Port, error) {
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	withTags := c.HasExtension(BulkTagExtension)
+
+	body := make([]map[string]interface{}, 0, len(opts))
+	sbRes := make(map[string]func() (*subnets.Subnet, error))
+	for _, opt := range opts {
+		m, err := portCreateOpts(opt).ToPortCreateMap()
+		if err != nil {
+			return nil, err
+		}
+		port := m["port"].(map[string]interface{})
+		if withTags && len(opt.Tags) > 0 {
+			port["tags"] = []string{opt.Tags}
+		}
+		body = append(body, port)
+
+		for _, id := range []string{opt.SubnetID, opt.SubnetIDv6} {
+			if _, ok := sbRes[id]; len(id) > 0 && !ok {
+				sbRes[id] = c.getSubnetAsync(id)
+			}
+		}
+	}
+	netRes := c.getNetworkAsync(opts[0].NetworkID)
+
+	var r gophercloud.Result
+	network := c.network()
+	_, r.Err = network.Post(network.ServiceURL("ports"), map[string]interface{}{"ports": body}, &r.Body,
+		&gophercloud.RequestOpts{OkCodes: []int{http.StatusCreated}})
+	var created []ports.Port
+	if err := r.ExtractIntoSlicePtr(&created, "ports"); err != nil {
+		return nil, err
+	}
+
+	deleteAll := func() {
+		for _, p := range created {
+			_ = c.DeletePort(p.ID)
+		}
+	}
+	var sbs []*subnets.Subnet
+	for _, res := range sbRes {
+		sb, err := res()
+		if err != nil {
+			deleteAll()
+			return nil, err
+		}
+		sbs = append(sbs, sb)
+	}
+	_, mtu, err := netRes()
+	if err != nil {
+		deleteAll()
+		return nil, err
+	}
+	if len(created) != len(opts) {
+		deleteAll()
+		return nil, fmt.Errorf("%d ports created in bulk request of %d ports", len(created), len(opts))
+	}
+
+	ret := make([]Port, 0, len(created))
+	for _, p := range created {
+		np := c.ConvertPort(sbs, p)
+		np.MTU = mtu
+		ret = append(ret, *np)
+	}
+	return ret, nil
+}
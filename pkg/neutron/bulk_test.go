@@ -0,0 +1,89 @@
+package neutron
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rubble/pkg/neutron/fake"
+)
+
+func TestCreatePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		fault   *fake.Fault
+		wantErr bool
+		// deleted is the number of ports deleted after creation
+		deleted int
+	}{
+		{
+			name:  "created",
+			count: 3,
+		},
+		{
+			name:    "not enough addresses",
+			count:   6,
+			wantErr: true,
+		},
+		{
+			// ports are created but can not be converted, they are deleted
+			name:    "subnet lookup fails",
+			count:   3,
+			fault:   &fake.Fault{Method: http.MethodGet, Path: "/v2.0/subnets", StatusCode: http.StatusInternalServerError},
+			wantErr: true,
+			deleted: 3,
+		},
+		{
+			name:    "network lookup fails",
+			count:   3,
+			fault:   &fake.Fault{Method: http.MethodGet, Path: "/v2.0/networks", StatusCode: http.StatusInternalServerError},
+			wantErr: true,
+			deleted: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fake.NewServer()
+			defer s.Close()
+			netID := s.AddNetwork("net", 1450)
+			// 5 addresses are available in /29
+			subnetID, err := s.AddSubnet(netID, "subnet", "10.0.0.0/29")
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, err := NewClientWithAuthOptions(s.AuthOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.fault != nil {
+				s.AddFault(*tt.fault)
+			}
+
+			var opts []*CreateOpts
+			for i := 0; i < tt.count; i++ {
+				opts = append(opts, &CreateOpts{NetworkID: netID, SubnetID: subnetID, Tags: "vm_uuid:vm"})
+			}
+			ports, err := client.CreatePorts(opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreatePorts() error = %v, want error %v", err, tt.wantErr)
+			}
+			if n := s.RequestCount(http.MethodDelete, "/v2.0/ports"); n != tt.deleted {
+				t.Errorf("deleted ports = %d, want %d", n, tt.deleted)
+			}
+			if err != nil {
+				if len(ports) != 0 || len(s.Ports()) != 0 {
+					t.Errorf("ports returned %d, left in neutron %d, want none", len(ports), len(s.Ports()))
+				}
+				return
+			}
+			if len(ports) != tt.count || len(s.Ports()) != tt.count {
+				t.Errorf("ports returned %d, in neutron %d, want %d", len(ports), len(s.Ports()), tt.count)
+			}
+			for _, p := range ports {
+				if p.MTU != 1450 || len(p.IP) == 0 {
+					t.Errorf("port %s has mtu %d ip %q", p.ID, p.MTU, p.IP)
+				}
+			}
+		})
+	}
+}
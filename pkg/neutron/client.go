@@ -1,14 +1,15 @@
 package neutron
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"sync"
-	"time"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/rubble/pkg/utils"
 )
 
 const (
@@ -18,17 +19,38 @@ const (
 type Client struct {
 	networkCliV2  *gophercloud.ServiceClient
 	identityCliV3 *gophercloud.ServiceClient
+	// computeCliV2 attach ports to vm for eni datapath, nil if there is no compute endpoint
+	computeCliV2 *gophercloud.ServiceClient
 
 	podsDeleteLock *sync.Mutex
 	portIDs        map[string]string
+	extensions     *extensionCache
+	// limiter is shared by copies of client with different priority
+	limiter  *RateLimiter
+	priority Priority
+	// ctx bounds requests of client, requests are aborted when it is done
+	ctx context.Context
 }
 
 func NewClient() (*Client, error) {
-	provider, err := newProviderClientOrDie(false)
+	opt, err := openstack.AuthOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithAuthOptions(opt)
+}
+
+// NewClientWithAuthOptions create client with auth options instead of OS_* environments
+func NewClientWithAuthOptions(opt gophercloud.AuthOptions) (*Client, error) {
+	limiter := NewRateLimiter(utils.RateLimitConfig{})
+	provider, err := newProviderClientOrDie(opt, false, &rateLimitedTransport{
+		limiter: limiter,
+		next:    &instrumentedTransport{next: http.DefaultTransport},
+	})
 	if err != nil {
 		return nil, err
 	}
-	domainTokenProvider, err := newProviderClientOrDie(true)
+	domainTokenProvider, err := newProviderClientOrDie(opt, true, &instrumentedTransport{next: http.DefaultTransport})
 	if err != nil {
 		return nil, err
 	}
@@ -43,25 +65,86 @@ func NewClient() (*Client, error) {
 		return nil, err
 	}
 
+	// compute is only required by eni datapath, endpoint may be missing for other datapaths
+	computeV2, err := openstack.NewComputeV2(provider, gophercloud.EndpointOpts{})
+	if err != nil {
+		computeV2 = nil
+	}
+
 	return &Client{
 		networkCliV2:   netV2,
 		identityCliV3:  idenV3,
+		computeCliV2:   computeV2,
 		podsDeleteLock: &sync.Mutex{},
 		portIDs:        make(map[string]string),
+		extensions:     &extensionCache{supported: make(map[string]bool)},
+		limiter:        limiter,
 	}, nil
 }
 
-func newProviderClientOrDie(domainScope bool) (*gophercloud.ProviderClient, error) {
-	opt, err := openstack.AuthOptionsFromEnv()
-	if err != nil {
-		return nil, err
+// WithPriority return copy of client sending requests with priority p, copies share rate limiter
+func (c Client) WithPriority(p Priority) *Client {
+	c.priority = p
+	return &c
+}
+
+// WithContext return copy of client bound to ctx, requests are aborted when ctx is done,
+// including requests waiting in rate limiter
+func (c Client) WithContext(ctx context.Context) *Client {
+	c.ctx = ctx
+	return &c
+}
+
+// context return context of client, background if not bound
+func (c Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
+	return c.ctx
+}
+
+// SetRateLimit update rate limit of neutron requests, zero values use defaults
+func (c *Client) SetRateLimit(cfg utils.RateLimitConfig) {
+	c.limiter.Configure(cfg)
+}
+
+// network return network client sending requests with context and priority of c. provider is copied
+// to carry them in its context, token is refreshed from the shared provider on reauth
+func (c Client) network() *gophercloud.ServiceClient {
+	return c.scoped(c.networkCliV2)
+}
+
+// compute return compute client sending requests with context and priority of c like network
+func (c Client) compute() (*gophercloud.ServiceClient, error) {
+	if c.computeCliV2 == nil {
+		return nil, fmt.Errorf("compute endpoint not found in catalog")
+	}
+	return c.scoped(c.computeCliV2), nil
+}
+
+func (c Client) scoped(cli *gophercloud.ServiceClient) *gophercloud.ServiceClient {
+	shared := cli.ProviderClient
+	provider := *shared
+	provider.Context = WithPriority(c.context(), c.priority)
+	provider.ReauthFunc = func() error {
+		if err := shared.ReauthFunc(); err != nil {
+			return err
+		}
+		provider.CopyTokenFrom(shared)
+		return nil
+	}
+	sc := *cli
+	sc.ProviderClient = &provider
+	return &sc
+}
+
+func newProviderClientOrDie(opt gophercloud.AuthOptions, domainScope bool, transport http.RoundTripper) (*gophercloud.ProviderClient, error) {
 	// with OS_PROJECT_NAME in env, AuthOptionsFromEnv return project scope token
 	// which can not list projects, we need a domain scope token here
 	if domainScope {
 		opt.TenantName = ""
 		opt.Scope = &gophercloud.AuthScope{
-			DomainName: os.Getenv("OS_DOMAIN_NAME"),
+			DomainName: opt.DomainName,
 		}
 	}
 	p, err := openstack.AuthenticatedClient(opt)
@@ -69,8 +152,11 @@ func newProviderClientOrDie(domainScope bool) (*gophercloud.ProviderClient, erro
 		return nil, err
 	}
 	p.HTTPClient = http.Client{
-		Transport: http.DefaultTransport,
-		Timeout:   time.Second * 60,
+		Transport: transport,
+	}
+	// rate limited transport bounds every attempt, timeout of client would also cover waits and backoff in limiter
+	if _, ok := transport.(*rateLimitedTransport); !ok {
+		p.HTTPClient.Timeout = requestTimeout
 	}
 	p.ReauthFunc = func() error {
 		newprov, err := openstack.AuthenticatedClient(opt)
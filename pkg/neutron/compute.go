@@ -0,0 +1,53 @@
+package neutron
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/attachinterfaces"
+)
+
+// detachPollInterval is interval to check if port detached from vm
+const detachPollInterval = time.Second
+
+// AttachPort hot-plug port into vm as a new interface, the interface appears on vm with mac of port
+func (c Client) AttachPort(serverID, portID string) error {
+	cli, err := c.compute()
+	if err != nil {
+		return err
+	}
+	_, err = attachinterfaces.Create(cli, serverID, attachinterfaces.CreateOpts{PortID: portID}).Extract()
+	return err
+}
+
+// DetachPort unplug port from vm and wait until neutron shows the port detached, so it can be attached
+// again. a port not attached to the vm is not an error
+func (c Client) DetachPort(serverID, portID string, timeout time.Duration) error {
+	cli, err := c.compute()
+	if err != nil {
+		return err
+	}
+	err = attachinterfaces.Delete(cli, serverID, portID).ExtractErr()
+	if err != nil {
+		if _, ok := err.(gophercloud.ErrDefault404); !ok {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.context(), timeout)
+	defer cancel()
+	c.ctx = ctx
+	for {
+		p, err := c.GetPort(portID)
+		if err == nil && p.DeviceID != serverID {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting port %s detached from %s timeout with error: %w", portID, serverID, ctx.Err())
+		case <-time.After(detachPollInterval):
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package neutron
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+// ErrIPAddressGenerationFailure is type of neutron error when no address is left to allocate
+const ErrIPAddressGenerationFailure = "IpAddressGenerationFailure"
+
+var subnetInMessage = regexp.MustCompile(`subnet ([0-9a-fA-F-]{36})`)
+
+// neutronError is body of error response of neutron
+type neutronError struct {
+	NeutronError struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"NeutronError"`
+}
+
+// ErrorType return type and message of neutron error in err, empty if err is not an error response
+func ErrorType(err error) (string, string) {
+	var resp gophercloud.ErrUnexpectedResponseCode
+	var conflict gophercloud.ErrDefault409
+	switch {
+	case errors.As(err, &conflict):
+		resp = conflict.ErrUnexpectedResponseCode
+	case errors.As(err, &resp):
+	default:
+		return "", ""
+	}
+	var body neutronError
+	if json.Unmarshal(resp.Body, &body) != nil {
+		return "", ""
+	}
+	return body.NeutronError.Type, body.NeutronError.Message
+}
+
+// ExhaustedSubnet check whether err is IpAddressGenerationFailure of neutron. the subnet with no
+// address left is returned if neutron names it, neutron names only the network in some versions
+func ExhaustedSubnet(err error) (bool, string) {
+	typ, msg := ErrorType(err)
+	if typ != ErrIPAddressGenerationFailure {
+		return false, ""
+	}
+	if m := subnetInMessage.FindStringSubmatch(msg); m != nil {
+		return true, m[1]
+	}
+	return true, ""
+}
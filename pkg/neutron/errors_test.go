@@ -0,0 +1,64 @@
+package neutron
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+func conflict(typ, msg string) error {
+	return gophercloud.ErrDefault409{ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{
+		Actual: 409,
+		Body:   []byte(fmt.Sprintf(`{"NeutronError": {"type": %q, "message": %q, "detail": ""}}`, typ, msg)),
+	}}
+}
+
+func TestExhaustedSubnet(t *testing.T) {
+	const subnetID = "6a1b6f1e-2d3c-4b5a-8f9e-0a1b2c3d4e5f"
+	tests := []struct {
+		name      string
+		err       error
+		exhausted bool
+		subnet    string
+	}{
+		{
+			name:      "subnet exhausted",
+			err:       conflict(ErrIPAddressGenerationFailure, "No more IP addresses available for subnet "+subnetID+"."),
+			exhausted: true,
+			subnet:    subnetID,
+		},
+		{
+			name:      "network exhausted",
+			err:       conflict(ErrIPAddressGenerationFailure, "No more IP addresses available on network 0c7d0a4e-1f2b-4c3d-9e8f-7a6b5c4d3e2f."),
+			exhausted: true,
+		},
+		{
+			name:      "wrapped",
+			err:       fmt.Errorf("failed to create port with error: %w", conflict(ErrIPAddressGenerationFailure, "No more IP addresses available for subnet "+subnetID+".")),
+			exhausted: true,
+			subnet:    subnetID,
+		},
+		{
+			name: "address in use",
+			err:  conflict("IpAddressAlreadyAllocated", "IP address 192.168.0.2 already allocated in subnet "+subnetID),
+		},
+		{
+			name: "quota",
+			err:  conflict("OverQuota", "Quota exceeded for resources: ['port']."),
+		},
+		{
+			name: "not neutron error",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exhausted, subnet := ExhaustedSubnet(tt.err)
+			if exhausted != tt.exhausted || subnet != tt.subnet {
+				t.Errorf("ExhaustedSubnet() = %v, %q, want %v, %q", exhausted, subnet, tt.exhausted, tt.subnet)
+			}
+		})
+	}
+}
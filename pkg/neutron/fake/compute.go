@@ -0,0 +1,73 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// computePrefix is path of the fake nova endpoint, only interface attachments of servers are implemented
+const computePrefix = "/compute/v2.1/"
+
+// serveCompute attach and detach ports of servers, attaching set device_id and device_owner of port
+// like nova does, any server id is accepted
+func (s *Server) serveCompute(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, computePrefix), "/"), "/")
+	if len(parts) < 3 || parts[0] != "servers" || parts[2] != "os-interface" {
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("path %s not found", r.URL.Path))
+		return
+	}
+	serverID := parts[1]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 3:
+		var body struct {
+			Attachment struct {
+				PortID string `json:"port_id"`
+			} `json:"interfaceAttachment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid interface attachment body")
+			return
+		}
+		p, ok := s.ports[body.Attachment.PortID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "PortNotFound", fmt.Sprintf("Port %s could not be found.", body.Attachment.PortID))
+			return
+		}
+		if len(p.DeviceID) > 0 {
+			writeError(w, http.StatusConflict, "PortInUse", fmt.Sprintf("Port %s is still in use.", p.ID))
+			return
+		}
+		p.DeviceID = serverID
+		p.DeviceOwner = "compute:nova"
+		p.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"interfaceAttachment": attachment(p)})
+	case r.Method == http.MethodDelete && len(parts) == 4:
+		p, ok := s.ports[parts[3]]
+		if !ok || p.DeviceID != serverID {
+			writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("Port %s is not attached to %s", parts[3], serverID))
+			return
+		}
+		p.DeviceID = ""
+		p.DeviceOwner = ""
+		p.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusAccepted, nil)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func attachment(p *Port) map[string]interface{} {
+	return map[string]interface{}{
+		"port_id":    p.ID,
+		"net_id":     p.NetworkID,
+		"mac_addr":   p.MACAddress,
+		"port_state": p.Status,
+		"fixed_ips":  p.FixedIPs,
+	}
+}
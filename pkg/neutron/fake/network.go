@@ -0,0 +1,556 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Network struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Status       string   `json:"status"`
+	Subnets      []string `json:"subnets"`
+	AdminStateUp bool     `json:"admin_state_up"`
+	TenantID     string   `json:"tenant_id"`
+	ProjectID    string   `json:"project_id"`
+	Shared       bool     `json:"shared"`
+	MTU          int      `json:"mtu"`
+	Tags         []string `json:"tags"`
+}
+
+type AllocationPool struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type Subnet struct {
+	ID              string           `json:"id"`
+	NetworkID       string           `json:"network_id"`
+	Name            string           `json:"name"`
+	IPVersion       int              `json:"ip_version"`
+	CIDR            string           `json:"cidr"`
+	GatewayIP       string           `json:"gateway_ip"`
+	AllocationPools []AllocationPool `json:"allocation_pools"`
+	EnableDHCP      bool             `json:"enable_dhcp"`
+	TenantID        string           `json:"tenant_id"`
+	ProjectID       string           `json:"project_id"`
+	Tags            []string         `json:"tags"`
+}
+
+type FixedIP struct {
+	SubnetID  string `json:"subnet_id"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+type Port struct {
+	ID             string    `json:"id"`
+	NetworkID      string    `json:"network_id"`
+	Name           string    `json:"name"`
+	AdminStateUp   bool      `json:"admin_state_up"`
+	Status         string    `json:"status"`
+	MACAddress     string    `json:"mac_address"`
+	FixedIPs       []FixedIP `json:"fixed_ips"`
+	TenantID       string    `json:"tenant_id"`
+	ProjectID      string    `json:"project_id"`
+	DeviceOwner    string    `json:"device_owner"`
+	SecurityGroups []string  `json:"security_groups"`
+	DeviceID       string    `json:"device_id"`
+	Tags           []string  `json:"tags"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AddNetwork create a network with mtu and return its id
+func (s *Server) AddNetwork(name string, mtu int) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := &Network{
+		ID:           newUUID(),
+		Name:         name,
+		Status:       "ACTIVE",
+		Subnets:      []string{},
+		AdminStateUp: true,
+		TenantID:     ProjectID,
+		ProjectID:    ProjectID,
+		MTU:          mtu,
+		Tags:         []string{},
+	}
+	s.networks[n.ID] = n
+	return n.ID
+}
+
+// AddSubnet create a subnet in network, the first address is used as gateway
+func (s *Server) AddSubnet(networkID, name, cidr string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n, ok := s.networks[networkID]
+	if !ok {
+		return "", fmt.Errorf("network %s not found", networkID)
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	version := 4
+	if ipNet.IP.To4() == nil {
+		version = 6
+	}
+	gateway := nextIP(ipNet.IP)
+	sb := &Subnet{
+		ID:        newUUID(),
+		NetworkID: networkID,
+		Name:      name,
+		IPVersion: version,
+		CIDR:      ipNet.String(),
+		GatewayIP: gateway.String(),
+		AllocationPools: []AllocationPool{
+			{Start: nextIP(gateway).String(), End: lastIP(ipNet).String()},
+		},
+		EnableDHCP: true,
+		TenantID:   ProjectID,
+		ProjectID:  ProjectID,
+		Tags:       []string{},
+	}
+	s.subnets[sb.ID] = sb
+	n.Subnets = append(n.Subnets, sb.ID)
+	return sb.ID, nil
+}
+
+// AddPort create a port directly, e.g. ports left by a crashed daemon. NetworkID and FixedIPs
+// of port are required, ip addresses are allocated if not specified
+func (s *Server) AddPort(port Port) (*Port, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	port.FixedIPs = append([]FixedIP{}, port.FixedIPs...)
+	port.Tags = append([]string{}, port.Tags...)
+	p, err := s.createPortLocked(&port)
+	if err != nil {
+		return nil, err
+	}
+	ret := *p
+	return &ret, nil
+}
+
+// Ports return a copy of all ports
+func (s *Server) Ports() []Port {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var ret []Port
+	for _, p := range s.ports {
+		ret = append(ret, *p)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CreatedAt.Before(ret[j].CreatedAt)
+	})
+	return ret
+}
+
+func (s *Server) GetPort(id string) (Port, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p, ok := s.ports[id]
+	if !ok {
+		return Port{}, false
+	}
+	return *p, true
+}
+
+// SetPortTags replace tags of port, e.g. tags set by daemon of another node
+func (s *Server) SetPortTags(id string, tags []string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p, ok := s.ports[id]
+	if !ok {
+		return fmt.Errorf("port %s not found", id)
+	}
+	p.Tags = append([]string{}, tags...)
+	return nil
+}
+
+func (s *Server) serveNetwork(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v2.0/"), "/"), "/")
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case parts[0] == "networks" && len(parts) == 1 && r.Method == http.MethodGet:
+		var ret []*Network
+		for _, n := range s.networks {
+			if matchQuery(r.URL.Query(), map[string]string{"id": n.ID, "name": n.Name}, n.Tags) {
+				ret = append(ret, n)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"networks": ret})
+	case parts[0] == "networks" && len(parts) == 2 && r.Method == http.MethodGet:
+		n, ok := s.networks[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NetworkNotFound", fmt.Sprintf("Network %s could not be found.", parts[1]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"network": n})
+	case parts[0] == "subnets" && len(parts) == 1 && r.Method == http.MethodGet:
+		var ret []*Subnet
+		for _, sb := range s.subnets {
+			if matchQuery(r.URL.Query(), map[string]string{"id": sb.ID, "name": sb.Name, "network_id": sb.NetworkID}, sb.Tags) {
+				ret = append(ret, sb)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"subnets": ret})
+	case parts[0] == "subnets" && len(parts) == 2 && r.Method == http.MethodGet:
+		sb, ok := s.subnets[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "SubnetNotFound", fmt.Sprintf("Subnet %s could not be found.", parts[1]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"subnet": sb})
+	case parts[0] == "extensions" && len(parts) == 2 && r.Method == http.MethodGet:
+		if !s.extensions[parts[1]] {
+			writeError(w, http.StatusNotFound, "ExtensionNotFound", fmt.Sprintf("Extension with alias %s does not exist", parts[1]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"extension": map[string]string{"alias": parts[1], "name": parts[1]},
+		})
+	case parts[0] == "security-groups":
+		s.serveSecurityGroups(w, r, parts[1:])
+	case parts[0] == "security-group-rules":
+		s.serveSecurityGroupRules(w, r, parts[1:])
+	case parts[0] == "trunks":
+		s.serveTrunks(w, r, parts[1:])
+	case parts[0] == "ports" && len(parts) == 1:
+		s.servePorts(w, r)
+	case parts[0] == "ports" && len(parts) == 2:
+		s.servePort(w, r, parts[1])
+	case len(parts) == 4 && parts[2] == "tags" && r.Method == http.MethodPut:
+		s.serveAddTag(w, parts[0], parts[1], parts[3])
+	case len(parts) == 4 && parts[2] == "tags" && r.Method == http.MethodDelete:
+		s.serveRemoveTag(w, parts[0], parts[1], parts[3])
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s not supported", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) servePorts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var ret []*Port
+		for _, p := range s.ports {
+			fields := map[string]string{
+				"id":           p.ID,
+				"name":         p.Name,
+				"network_id":   p.NetworkID,
+				"device_owner": p.DeviceOwner,
+				"device_id":    p.DeviceID,
+			}
+			if matchQuery(r.URL.Query(), fields, p.Tags) {
+				ret = append(ret, p)
+			}
+		}
+		sort.Slice(ret, func(i, j int) bool {
+			return ret[i].CreatedAt.Before(ret[j].CreatedAt)
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ports": ret})
+	case http.MethodPost:
+		var body struct {
+			Port  *Port   `json:"port"`
+			Ports []*Port `json:"ports"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (body.Port == nil && len(body.Ports) == 0) {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid port body")
+			return
+		}
+		if body.Port == nil {
+			s.serveBulkCreatePorts(w, body.Ports)
+			return
+		}
+		p, err := s.createPortLocked(body.Port)
+		if err != nil {
+			writeConflict(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"port": p})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// serveBulkCreatePorts create all ports or none of them like neutron
+func (s *Server) serveBulkCreatePorts(w http.ResponseWriter, ports []*Port) {
+	var created []*Port
+	for _, port := range ports {
+		p, err := s.createPortLocked(port)
+		if err != nil {
+			for _, c := range created {
+				delete(s.ports, c.ID)
+			}
+			writeConflict(w, err)
+			return
+		}
+		created = append(created, p)
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"ports": created})
+}
+
+func (s *Server) servePort(w http.ResponseWriter, r *http.Request, id string) {
+	p, ok := s.ports[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "PortNotFound", fmt.Sprintf("Port %s could not be found.", id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"port": p})
+	case http.MethodDelete:
+		if p.DeviceOwner == subportOwner {
+			writeError(w, http.StatusConflict, "PortInUse", fmt.Sprintf("Port %s is currently a subport for trunk %s.", id, p.DeviceID))
+			return
+		}
+		delete(s.ports, id)
+		writeJSON(w, http.StatusNoContent, nil)
+	case http.MethodPut:
+		var body struct {
+			Port map[string]json.RawMessage `json:"port"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid port body")
+			return
+		}
+		for k, v := range body.Port {
+			var err error
+			switch k {
+			case "name":
+				err = json.Unmarshal(v, &p.Name)
+			case "device_owner":
+				err = json.Unmarshal(v, &p.DeviceOwner)
+			case "device_id":
+				err = json.Unmarshal(v, &p.DeviceID)
+			case "security_groups":
+				var sgs []string
+				if err = json.Unmarshal(v, &sgs); err == nil {
+					for _, id := range sgs {
+						if _, ok := s.sgs[id]; !ok {
+							writeError(w, http.StatusNotFound, "SecurityGroupNotFound", fmt.Sprintf("Security group %s does not exist", id))
+							return
+						}
+					}
+					p.SecurityGroups = sgs
+				}
+			case "admin_state_up":
+				err = json.Unmarshal(v, &p.AdminStateUp)
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "BadRequest", fmt.Sprintf("invalid value of %s", k))
+				return
+			}
+		}
+		p.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"port": p})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func (s *Server) serveAddTag(w http.ResponseWriter, resourceType, id, tag string) {
+	tags := s.tagsLocked(resourceType, id)
+	if tags == nil {
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s could not be found.", resourceType, id))
+		return
+	}
+	for _, t := range *tags {
+		if t == tag {
+			writeJSON(w, http.StatusCreated, nil)
+			return
+		}
+	}
+	*tags = append(*tags, tag)
+	writeJSON(w, http.StatusCreated, nil)
+}
+
+func (s *Server) serveRemoveTag(w http.ResponseWriter, resourceType, id, tag string) {
+	tags := s.tagsLocked(resourceType, id)
+	if tags == nil {
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s could not be found.", resourceType, id))
+		return
+	}
+	for i, t := range *tags {
+		if t == tag {
+			*tags = append((*tags)[:i], (*tags)[i+1:]...)
+			writeJSON(w, http.StatusNoContent, nil)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("tag %s could not be found.", tag))
+}
+
+// tagsLocked return tags of resource, nil if resource not found
+func (s *Server) tagsLocked(resourceType, id string) *[]string {
+	var tags *[]string
+	switch resourceType {
+	case "ports":
+		if p, ok := s.ports[id]; ok {
+			tags = &p.Tags
+		}
+	case "networks":
+		if n, ok := s.networks[id]; ok {
+			tags = &n.Tags
+		}
+	case "subnets":
+		if sb, ok := s.subnets[id]; ok {
+			tags = &sb.Tags
+		}
+	}
+	return tags
+}
+
+// createPortLocked allocate fixed ips for port and save it, must in lock
+func (s *Server) createPortLocked(p *Port) (*Port, error) {
+	n, ok := s.networks[p.NetworkID]
+	if !ok {
+		return nil, fmt.Errorf("NetworkNotFound")
+	}
+	for _, id := range p.SecurityGroups {
+		if _, ok := s.sgs[id]; !ok {
+			return nil, fmt.Errorf("SecurityGroupNotFound")
+		}
+	}
+	if len(p.FixedIPs) == 0 {
+		if len(n.Subnets) == 0 {
+			return nil, &apiError{Type: "IpAddressGenerationFailure", Message: fmt.Sprintf("No more IP addresses available on network %s.", n.ID)}
+		}
+		p.FixedIPs = []FixedIP{{SubnetID: n.Subnets[0]}}
+	}
+
+	for i := range p.FixedIPs {
+		fip := &p.FixedIPs[i]
+		sb, ok := s.subnets[fip.SubnetID]
+		if !ok || sb.NetworkID != n.ID {
+			return nil, fmt.Errorf("SubnetNotFound")
+		}
+		if len(fip.IPAddress) > 0 {
+			if s.ipUsedLocked(sb.ID, fip.IPAddress) {
+				return nil, fmt.Errorf("IpAddressAlreadyAllocated")
+			}
+			continue
+		}
+		ip := s.allocateIPLocked(sb)
+		if ip == "" {
+			return nil, &apiError{Type: "IpAddressGenerationFailure", Message: fmt.Sprintf("No more IP addresses available for subnet %s.", sb.ID)}
+		}
+		fip.IPAddress = ip
+	}
+
+	now := time.Now().UTC()
+	p.ID = newUUID()
+	p.AdminStateUp = true
+	p.Status = "DOWN"
+	p.MACAddress = newMAC()
+	p.TenantID = ProjectID
+	p.ProjectID = ProjectID
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	if p.SecurityGroups == nil {
+		p.SecurityGroups = []string{}
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	s.ports[p.ID] = p
+	return p, nil
+}
+
+func (s *Server) ipUsedLocked(subnetID, ip string) bool {
+	for _, p := range s.ports {
+		for _, fip := range p.FixedIPs {
+			if fip.SubnetID == subnetID && net.ParseIP(fip.IPAddress).Equal(net.ParseIP(ip)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) allocateIPLocked(sb *Subnet) string {
+	for _, pool := range sb.AllocationPools {
+		end := net.ParseIP(pool.End)
+		for ip := net.ParseIP(pool.Start); ; ip = nextIP(ip) {
+			if !s.ipUsedLocked(sb.ID, ip.String()) {
+				return ip.String()
+			}
+			if ip.Equal(end) {
+				break
+			}
+		}
+	}
+	return ""
+}
+
+// matchQuery check fields and tags against neutron list filters
+func matchQuery(query url.Values, fields map[string]string, tags []string) bool {
+	for k, values := range query {
+		if k == "tags" {
+			for _, tag := range strings.Split(values[0], ",") {
+				if !hasTag(tags, tag) {
+					return false
+				}
+			}
+			continue
+		}
+		v, ok := fields[k]
+		if !ok {
+			continue
+		}
+		if len(values) > 0 && len(values[0]) > 0 && values[0] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	if v4 := next.To4(); v4 != nil {
+		next = v4
+	}
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// lastIP return the last usable address of ip net, the broadcast address is excluded for ipv4
+func lastIP(ipNet *net.IPNet) net.IP {
+	ip := ipNet.IP
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	last := make(net.IP, len(ip))
+	for i := range ip {
+		last[i] = ip[i] | ^ipNet.Mask[i]
+	}
+	if len(last) == net.IPv4len {
+		last[len(last)-1]--
+	}
+	return last
+}
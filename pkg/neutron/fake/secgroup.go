@@ -0,0 +1,196 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type SecurityGroup struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TenantID    string    `json:"tenant_id"`
+	ProjectID   string    `json:"project_id"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SecurityGroupRule struct {
+	ID              string  `json:"id"`
+	SecurityGroupID string  `json:"security_group_id"`
+	Direction       string  `json:"direction"`
+	EtherType       string  `json:"ethertype"`
+	Protocol        *string `json:"protocol"`
+	PortRangeMin    *int    `json:"port_range_min"`
+	PortRangeMax    *int    `json:"port_range_max"`
+	RemoteIPPrefix  *string `json:"remote_ip_prefix"`
+	RemoteGroupID   *string `json:"remote_group_id"`
+	TenantID        string  `json:"tenant_id"`
+	ProjectID       string  `json:"project_id"`
+}
+
+// AddSecurityGroup create a security group and return its id
+func (s *Server) AddSecurityGroup(name string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.createSecurityGroupLocked(&SecurityGroup{Name: name}).ID
+}
+
+// SecurityGroupRules return rules of security group
+func (s *Server) SecurityGroupRules(sgID string) []SecurityGroupRule {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var ret []SecurityGroupRule
+	for _, rule := range s.rules {
+		if rule.SecurityGroupID == sgID {
+			ret = append(ret, *rule)
+		}
+	}
+	return ret
+}
+
+// createSecurityGroupLocked save security group with rules allowing all egress traffic like neutron,
+// must in lock
+func (s *Server) createSecurityGroupLocked(sg *SecurityGroup) *SecurityGroup {
+	now := time.Now().UTC()
+	sg.ID = newUUID()
+	sg.TenantID = ProjectID
+	sg.ProjectID = ProjectID
+	sg.Tags = []string{}
+	sg.CreatedAt = now
+	sg.UpdatedAt = now
+	s.sgs[sg.ID] = sg
+	for _, etherType := range []string{"IPv4", "IPv6"} {
+		s.createRuleLocked(&SecurityGroupRule{SecurityGroupID: sg.ID, Direction: "egress", EtherType: etherType})
+	}
+	return sg
+}
+
+func (s *Server) createRuleLocked(rule *SecurityGroupRule) *SecurityGroupRule {
+	rule.ID = newUUID()
+	rule.TenantID = ProjectID
+	rule.ProjectID = ProjectID
+	s.rules[rule.ID] = rule
+	return rule
+}
+
+// serveSecurityGroups serve /v2.0/security-groups, must in lock
+func (s *Server) serveSecurityGroups(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		var ret []*SecurityGroup
+		for _, sg := range s.sgs {
+			if matchQuery(r.URL.Query(), map[string]string{"id": sg.ID, "name": sg.Name}, sg.Tags) {
+				ret = append(ret, sg)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"security_groups": ret})
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var body struct {
+			SecurityGroup *SecurityGroup `json:"security_group"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.SecurityGroup == nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid security group body")
+			return
+		}
+		sg := s.createSecurityGroupLocked(body.SecurityGroup)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"security_group": sg})
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		sg, ok := s.sgs[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "SecurityGroupNotFound", fmt.Sprintf("Security group %s does not exist", parts[0]))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"security_group": sg})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if _, ok := s.sgs[parts[0]]; !ok {
+			writeError(w, http.StatusNotFound, "SecurityGroupNotFound", fmt.Sprintf("Security group %s does not exist", parts[0]))
+			return
+		}
+		for _, p := range s.ports {
+			if hasTag(p.SecurityGroups, parts[0]) {
+				writeError(w, http.StatusConflict, "SecurityGroupInUse", fmt.Sprintf("Security Group %s in use.", parts[0]))
+				return
+			}
+		}
+		delete(s.sgs, parts[0])
+		for id, rule := range s.rules {
+			if rule.SecurityGroupID == parts[0] {
+				delete(s.rules, id)
+			}
+		}
+		writeJSON(w, http.StatusNoContent, nil)
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s not supported", r.Method, r.URL.Path))
+	}
+}
+
+// serveSecurityGroupRules serve /v2.0/security-group-rules, must in lock
+func (s *Server) serveSecurityGroupRules(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		ret := []*SecurityGroupRule{}
+		for _, rule := range s.rules {
+			fields := map[string]string{
+				"id":                rule.ID,
+				"security_group_id": rule.SecurityGroupID,
+				"direction":         rule.Direction,
+				"ethertype":         rule.EtherType,
+			}
+			if matchQuery(r.URL.Query(), fields, nil) {
+				ret = append(ret, rule)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"security_group_rules": ret})
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var body struct {
+			Rule *SecurityGroupRule `json:"security_group_rule"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Rule == nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid security group rule body")
+			return
+		}
+		if _, ok := s.sgs[body.Rule.SecurityGroupID]; !ok {
+			writeError(w, http.StatusNotFound, "SecurityGroupNotFound", fmt.Sprintf("Security group %s does not exist", body.Rule.SecurityGroupID))
+			return
+		}
+		for _, rule := range s.rules {
+			if rule.key() == body.Rule.key() {
+				writeError(w, http.StatusConflict, "SecurityGroupRuleExists", fmt.Sprintf("Security group rule already exists. Rule id is %s.", rule.ID))
+				return
+			}
+		}
+		rule := s.createRuleLocked(body.Rule)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"security_group_rule": rule})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if _, ok := s.rules[parts[0]]; !ok {
+			writeError(w, http.StatusNotFound, "SecurityGroupRuleNotFound", fmt.Sprintf("Security group rule %s does not exist", parts[0]))
+			return
+		}
+		delete(s.rules, parts[0])
+		writeJSON(w, http.StatusNoContent, nil)
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s not supported", r.Method, r.URL.Path))
+	}
+}
+
+func (r *SecurityGroupRule) key() string {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	num := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s-%s/%s/%s", r.SecurityGroupID, r.Direction, r.EtherType, str(r.Protocol),
+		num(r.PortRangeMin), num(r.PortRangeMax), str(r.RemoteIPPrefix), str(r.RemoteGroupID))
+}
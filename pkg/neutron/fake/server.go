@@ -0,0 +1,284 @@
+// Package fake provides an in-process Keystone and Neutron API server, it implements the
+// endpoints used by neutron.Client, and nova interface attachments of eni datapath, so that pool,
+// ipam and daemon can run without an OpenStack cloud.
+package fake
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+const (
+	ProjectID  = "a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5"
+	DomainName = "Default"
+	Username   = "rubble"
+	Password   = "rubble"
+)
+
+// Fault make requests matching Method and Path prefix slow or fail
+type Fault struct {
+	// Method of the request, empty matches any method
+	Method string
+	// Path prefix of the request, e.g. /v2.0/ports
+	Path string
+	// StatusCode returned instead of handling the request, 0 only adds latency
+	StatusCode int
+	// Latency added before the request is handled
+	Latency time.Duration
+	// Times is the number of requests affected, 0 means forever
+	Times int
+}
+
+func (f *Fault) match(r *http.Request) bool {
+	if len(f.Method) > 0 && f.Method != r.Method {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+type Server struct {
+	srv *httptest.Server
+	URL string
+
+	lock     sync.Mutex
+	networks map[string]*Network
+	subnets  map[string]*Subnet
+	ports    map[string]*Port
+	sgs      map[string]*SecurityGroup
+	rules    map[string]*SecurityGroupRule
+	trunks   map[string]*Trunk
+	// extensions are aliases of supported neutron extensions
+	extensions map[string]bool
+	faults     []*Fault
+	requests   map[string]int
+	tokens     int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		networks: make(map[string]*Network),
+		subnets:  make(map[string]*Subnet),
+		ports:    make(map[string]*Port),
+		sgs:      make(map[string]*SecurityGroup),
+		rules:    make(map[string]*SecurityGroupRule),
+		trunks:   make(map[string]*Trunk),
+		extensions: map[string]bool{
+			"tag-ports-during-bulk-creation": true,
+			"trunk":                          true,
+		},
+		requests: make(map[string]int),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AuthOptions return options to authenticate against the fake keystone
+func (s *Server) AuthOptions() gophercloud.AuthOptions {
+	return gophercloud.AuthOptions{
+		IdentityEndpoint: s.URL + "/v3/",
+		Username:         Username,
+		Password:         Password,
+		DomainName:       DomainName,
+		TenantID:         ProjectID,
+	}
+}
+
+// AddFault inject fault for following requests
+func (s *Server) AddFault(f Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// SetExtension enable or disable neutron extension
+func (s *Server) SetExtension(alias string, enabled bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.extensions[alias] = enabled
+}
+
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+// RequestCount return count of handled requests with method and path prefix, empty method matches any
+func (s *Server) RequestCount(method, path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for k, v := range s.requests {
+		parts := strings.SplitN(k, " ", 2)
+		if (len(method) == 0 || parts[0] == method) && strings.HasPrefix(parts[1], path) {
+			count += v
+		}
+	}
+	return count
+}
+
+func (s *Server) takeFault(r *http.Request) *Fault {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, f := range s.faults {
+		if !f.match(r) {
+			continue
+		}
+		fault := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &fault
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if f := s.takeFault(r); f != nil {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.StatusCode > 0 {
+			writeError(w, f.StatusCode, "InjectedFault", fmt.Sprintf("fault injected for %s %s", r.Method, r.URL.Path))
+			return
+		}
+	}
+
+	s.lock.Lock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	s.lock.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/v3/") {
+		s.serveIdentity(w, r)
+		return
+	}
+
+	if len(r.Header.Get("X-Auth-Token")) == 0 {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "missing token")
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/v2.0/") {
+		s.serveNetwork(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, computePrefix) {
+		s.serveCompute(w, r)
+		return
+	}
+	writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("path %s not found", r.URL.Path))
+}
+
+func (s *Server) serveIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v3/auth/tokens" {
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("path %s not found", r.URL.Path))
+		return
+	}
+
+	s.lock.Lock()
+	s.tokens++
+	token := fmt.Sprintf("fake-token-%d", s.tokens)
+	s.lock.Unlock()
+
+	endpoint := func(url string) []map[string]string {
+		return []map[string]string{
+			{"id": newUUID(), "interface": "public", "region": "RegionOne", "region_id": "RegionOne", "url": url},
+			{"id": newUUID(), "interface": "internal", "region": "RegionOne", "region_id": "RegionOne", "url": url},
+		}
+	}
+	now := time.Now().UTC()
+	body := map[string]interface{}{
+		"token": map[string]interface{}{
+			"methods":    []string{"password"},
+			"issued_at":  now.Format(time.RFC3339),
+			"expires_at": now.Add(time.Hour).Format(time.RFC3339),
+			"user": map[string]interface{}{
+				"id":     newUUID(),
+				"name":   Username,
+				"domain": map[string]string{"id": "default", "name": DomainName},
+			},
+			"project": map[string]interface{}{
+				"id":     ProjectID,
+				"name":   "service",
+				"domain": map[string]string{"id": "default", "name": DomainName},
+			},
+			"catalog": []map[string]interface{}{
+				{"id": newUUID(), "type": "network", "name": "neutron", "endpoints": endpoint(s.URL + "/")},
+				{"id": newUUID(), "type": "compute", "name": "nova", "endpoints": endpoint(s.URL + computePrefix)},
+				{"id": newUUID(), "type": "identity", "name": "keystone", "endpoints": endpoint(s.URL + "/v3/")},
+			},
+		},
+	}
+	w.Header().Set("X-Subject-Token", token)
+	writeJSON(w, http.StatusCreated, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, errType, message string) {
+	writeJSON(w, code, map[string]interface{}{
+		"NeutronError": map[string]string{
+			"type":    errType,
+			"message": message,
+			"detail":  "",
+		},
+	})
+}
+
+// apiError is error with type and message of neutron, other errors are returned with message as type
+type apiError struct {
+	Type    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return e.Type
+}
+
+// writeConflict write err as conflict error of neutron
+func writeConflict(w http.ResponseWriter, err error) {
+	if e, ok := err.(*apiError); ok {
+		writeError(w, http.StatusConflict, e.Type, e.Message)
+		return
+	}
+	writeError(w, http.StatusConflict, err.Error(), err.Error())
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func newMAC() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", b[0], b[1], b[2])
+}
@@ -0,0 +1,169 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// subportOwner is device owner of ports added into trunk
+const subportOwner = "trunk:subport"
+
+type Subport struct {
+	PortID           string `json:"port_id"`
+	SegmentationType string `json:"segmentation_type"`
+	SegmentationID   int    `json:"segmentation_id"`
+}
+
+type Trunk struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	PortID       string    `json:"port_id"`
+	Status       string    `json:"status"`
+	AdminStateUp bool      `json:"admin_state_up"`
+	Subports     []Subport `json:"sub_ports"`
+	TenantID     string    `json:"tenant_id"`
+	ProjectID    string    `json:"project_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AddTrunk create a trunk with parent port and return its id
+func (s *Server) AddTrunk(name, parentPortID string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.ports[parentPortID]; !ok {
+		return "", fmt.Errorf("port %s not found", parentPortID)
+	}
+	now := time.Now().UTC()
+	t := &Trunk{
+		ID:           newUUID(),
+		Name:         name,
+		PortID:       parentPortID,
+		Status:       "ACTIVE",
+		AdminStateUp: true,
+		Subports:     []Subport{},
+		TenantID:     ProjectID,
+		ProjectID:    ProjectID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	s.trunks[t.ID] = t
+	return t.ID, nil
+}
+
+// Subports return copy of subports of trunk
+func (s *Server) Subports(trunkID string) []Subport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, ok := s.trunks[trunkID]
+	if !ok {
+		return nil
+	}
+	return append([]Subport{}, t.Subports...)
+}
+
+func (s *Server) serveTrunks(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 && r.Method == http.MethodGet {
+		var ret []*Trunk
+		for _, t := range s.trunks {
+			if matchQuery(r.URL.Query(), map[string]string{"id": t.ID, "name": t.Name, "port_id": t.PortID}, nil) {
+				ret = append(ret, t)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"trunks": ret})
+		return
+	}
+	if len(parts) == 0 {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+		return
+	}
+
+	t, ok := s.trunks[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "TrunkNotFound", fmt.Sprintf("Trunk %s could not be found.", parts[0]))
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"trunk": t})
+	case len(parts) == 2 && parts[1] == "get_subports" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sub_ports": t.Subports})
+	case len(parts) == 2 && parts[1] == "add_subports" && r.Method == http.MethodPut:
+		var body struct {
+			Subports []Subport `json:"sub_ports"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid subports body")
+			return
+		}
+		for _, sp := range body.Subports {
+			if err := s.checkSubportLocked(t, sp); err != nil {
+				writeError(w, http.StatusConflict, "SubportConflict", err.Error())
+				return
+			}
+		}
+		for _, sp := range body.Subports {
+			p := s.ports[sp.PortID]
+			p.DeviceOwner = subportOwner
+			p.DeviceID = t.ID
+			t.Subports = append(t.Subports, sp)
+		}
+		t.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusOK, t)
+	case len(parts) == 2 && parts[1] == "remove_subports" && r.Method == http.MethodPut:
+		var body struct {
+			Subports []Subport `json:"sub_ports"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", "invalid subports body")
+			return
+		}
+		for _, sp := range body.Subports {
+			i := subportIndex(t, sp.PortID)
+			if i < 0 {
+				writeError(w, http.StatusNotFound, "SubPortNotFound", fmt.Sprintf("SubPort %s could not be found.", sp.PortID))
+				return
+			}
+			t.Subports = append(t.Subports[:i], t.Subports[i+1:]...)
+			if p, ok := s.ports[sp.PortID]; ok {
+				p.DeviceOwner = ""
+				p.DeviceID = ""
+			}
+		}
+		t.UpdatedAt = time.Now().UTC()
+		writeJSON(w, http.StatusOK, t)
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s not supported", r.Method, r.URL.Path))
+	}
+}
+
+// checkSubportLocked reject subport with port in use or vlan id taken in trunk like neutron
+func (s *Server) checkSubportLocked(t *Trunk, sp Subport) error {
+	p, ok := s.ports[sp.PortID]
+	if !ok {
+		return fmt.Errorf("port %s could not be found", sp.PortID)
+	}
+	if p.DeviceOwner == subportOwner {
+		return fmt.Errorf("port %s is in use by another trunk", sp.PortID)
+	}
+	if sp.SegmentationType != "vlan" || sp.SegmentationID < 1 || sp.SegmentationID > 4094 {
+		return fmt.Errorf("invalid segmentation %s %d", sp.SegmentationType, sp.SegmentationID)
+	}
+	for _, other := range t.Subports {
+		if other.SegmentationID == sp.SegmentationID {
+			return fmt.Errorf("segmentation id %d is in use by port %s", sp.SegmentationID, other.PortID)
+		}
+	}
+	return nil
+}
+
+func subportIndex(t *Trunk, portID string) int {
+	for i, sp := range t.Subports {
+		if sp.PortID == portID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,46 @@
+package neutron
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rubble/pkg/metrics"
+	"github.com/rubble/pkg/utils"
+)
+
+// instrumentedTransport record latency and outcome of every openstack api request
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	outcome := metrics.OutcomeSuccess
+	switch {
+	case err != nil:
+		outcome = metrics.OutcomeError
+	case resp.StatusCode >= http.StatusInternalServerError:
+		outcome = "server_error"
+	case resp.StatusCode >= http.StatusBadRequest:
+		outcome = "client_error"
+	}
+	metrics.NeutronDuration.WithLabelValues(operation(req), outcome).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// operation return method and path of request with ids and tags replaced, e.g. PUT /v2.0/ports/:id/tags/:tag
+func operation(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, part := range parts {
+		switch {
+		case utils.IsValidUUID(part):
+			parts[i] = ":id"
+		case i > 0 && parts[i-1] == "tags":
+			parts[i] = ":tag"
+		}
+	}
+	return req.Method + " /" + strings.Join(parts, "/")
+}
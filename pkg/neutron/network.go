@@ -13,7 +13,7 @@ func (c Client) getNetwork(id string) (*networks.Network, int, error) {
 		mtu.NetworkMTUExt
 	}
 
-	allPages, err := networks.List(c.networkCliV2, networks.ListOpts{ID: id}).AllPages()
+	allPages, err := networks.List(c.network(), networks.ListOpts{ID: id}).AllPages()
 	if err != nil {
 		return nil, 0, err
 	}
@@ -34,7 +34,7 @@ func (c Client) getNetwork(id string) (*networks.Network, int, error) {
 		return nil, 0, fmt.Errorf("mtu not found for network %s", id)
 	}
 
-	r := networks.Get(c.networkCliV2, id)
+	r := networks.Get(c.network(), id)
 	n, err := r.Extract()
 	return n, mTU, err
 }
@@ -68,13 +68,19 @@ func (c Client) getNetworkAsync(id string) func() (*networks.Network, int, error
 	}
 }
 
+// GetNetworkMTU return mtu of network
+func (c Client) GetNetworkMTU(id string) (int, error) {
+	_, mtu, err := c.getNetwork(id)
+	return mtu, err
+}
+
 func (c Client) GetNetwork(id string) (*networks.Network, error) {
-	return networks.Get(c.networkCliV2, id).Extract()
+	return networks.Get(c.network(), id).Extract()
 }
 
 func (c Client) ListNetworks() ([]networks.Network, error) {
 	opts := networks.ListOpts{}
-	pages, _ := networks.List(c.networkCliV2, opts).AllPages()
+	pages, _ := networks.List(c.network(), opts).AllPages()
 	allNetworks, _ := networks.ExtractNetworks(pages)
 	return allNetworks, nil
 }
@@ -1,6 +1,7 @@
 package neutron
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
@@ -17,30 +18,56 @@ const (
 )
 
 type CreateOpts struct {
-	Name          string
-	NetworkID     string
-	SubnetID      string
-	IPAddress     string
-	ProjectID     string
-	SecurityGroup string
-	DeviceID      string
-	DeviceOwner   string
-	Tags          string
+	Name      string
+	NetworkID string
+	SubnetID  string
+	IPAddress string
+	// SubnetIDv6 and IPv6Address add a fixed ip from ipv6 subnet for dual stack port,
+	// for ipv6 only port SubnetID and IPAddress are used
+	SubnetIDv6  string
+	IPv6Address string
+	ProjectID   string
+	// SecurityGroups are ids of security groups, port has no security group if empty
+	SecurityGroups []string
+	DeviceID       string
+	DeviceOwner    string
+	Tags           string
 }
 
 type Port struct {
-	Name     string
-	ID       string
-	SubnetID string
-	MAC      string
-	IP       string
-	CIDR     string
-	Gateway  string
-	MTU      int
-	Sgs      []string
+	Name       string
+	ID         string
+	SubnetID   string
+	MAC        string
+	IP         string
+	CIDR       string
+	Gateway    string
+	SubnetIDv6 string
+	IPv6       string
+	CIDRv6     string
+	GatewayV6  string
+	MTU        int
+	Sgs        []string
+	Tags       []string
+}
+
+// setAddress fill ipv4 or ipv6 address of port by ip version of subnet
+func (p *Port) setAddress(sb *subnets.Subnet, ip string) {
+	if sb.IPVersion == 6 {
+		p.SubnetIDv6 = sb.ID
+		p.IPv6 = ip
+		p.CIDRv6 = sb.CIDR
+		p.GatewayV6 = sb.GatewayIP
+		return
+	}
+	p.SubnetID = sb.ID
+	p.IP = ip
+	p.CIDR = sb.CIDR
+	p.Gateway = sb.GatewayIP
 }
 
 type ListFilter struct {
+	Name        string
 	NetworkID   string
 	DeviceOwner string
 	DeviceID    string
@@ -56,7 +83,7 @@ func (c Client) ListPortWithNetworkID(networkID string) ([]ports.Port, error) {
 	opts = ports.ListOpts{
 		NetworkID: networkID,
 	}
-	err = ports.List(c.networkCliV2, opts).EachPage(func(page pagination.Page) (bool, error) {
+	err = ports.List(c.network(), opts).EachPage(func(page pagination.Page) (bool, error) {
 		actual, err = ports.ExtractPorts(page)
 		if err != nil {
 			return false, err
@@ -74,11 +101,12 @@ func (c Client) ListPortWithFilter(filter ListFilter) ([]ports.Port, error) {
 		err    error
 	)
 	opts = ports.ListOpts{
+		Name:        filter.Name,
 		NetworkID:   filter.NetworkID,
 		DeviceOwner: filter.DeviceOwner,
 		Tags:        filter.Tags,
 	}
-	err = ports.List(c.networkCliV2, opts).EachPage(func(page pagination.Page) (bool, error) {
+	err = ports.List(c.network(), opts).EachPage(func(page pagination.Page) (bool, error) {
 		actual, err = ports.ExtractPorts(page)
 		if err != nil {
 			return false, err
@@ -107,7 +135,7 @@ func (c Client) CreatePortWithFip(networkID, floatingip string) (*ports.Port, er
 		DeviceOwner: FipDeviceOwner,
 	}
 
-	p, err := ports.Create(c.networkCliV2, opts).Extract()
+	p, err := ports.Create(c.network(), opts).Extract()
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +151,7 @@ func (c Client) DeletePortWithFip(networkID, floatingip string) error {
 	opts = ports.ListOpts{
 		NetworkID: networkID,
 	}
-	err = ports.List(c.networkCliV2, opts).EachPage(func(page pagination.Page) (bool, error) {
+	err = ports.List(c.network(), opts).EachPage(func(page pagination.Page) (bool, error) {
 		actual, err = ports.ExtractPorts(page)
 		if err != nil {
 			return false, err
@@ -134,15 +162,15 @@ func (c Client) DeletePortWithFip(networkID, floatingip string) error {
 	for _, p := range actual {
 		for _, ip := range p.FixedIPs {
 			if ip.IPAddress == floatingip {
-				return ports.Delete(c.networkCliV2, p.ID).ExtractErr()
+				return ports.Delete(c.network(), p.ID).ExtractErr()
 			}
 		}
 	}
 	return errors.New("delete port failed, err: not found")
 }
 
-func (c Client) CreatePort(opts *CreateOpts) (Port, error) {
-
+// portCreateOpts convert opts to gophercloud create opts
+func portCreateOpts(opts *CreateOpts) ports.CreateOpts {
 	type FixedIPOpt struct {
 		SubnetID        string `json:"subnet_id,omitempty"`
 		IPAddress       string `json:"ip_address,omitempty"`
@@ -150,32 +178,52 @@ func (c Client) CreatePort(opts *CreateOpts) (Port, error) {
 	}
 	type FixedIPOpts []FixedIPOpt
 
-	copts := ports.CreateOpts{
-		Name:      opts.Name,
-		NetworkID: opts.NetworkID,
-		FixedIPs: FixedIPOpts{
-			{
-				SubnetID:  opts.SubnetID,
-				IPAddress: opts.IPAddress,
-			},
+	fixedIPs := FixedIPOpts{
+		{
+			SubnetID:  opts.SubnetID,
+			IPAddress: opts.IPAddress,
 		},
-		SecurityGroups: &[]string{},
+	}
+	if len(opts.SubnetIDv6) > 0 {
+		fixedIPs = append(fixedIPs, FixedIPOpt{
+			SubnetID:  opts.SubnetIDv6,
+			IPAddress: opts.IPv6Address,
+		})
+	}
+
+	sgs := append([]string{}, opts.SecurityGroups...)
+	return ports.CreateOpts{
+		Name:           opts.Name,
+		NetworkID:      opts.NetworkID,
+		FixedIPs:       fixedIPs,
+		SecurityGroups: &sgs,
 		DeviceOwner:    opts.DeviceOwner,
 		DeviceID:       opts.DeviceID,
 	}
+}
 
-	sbRes := c.getSubnetAsync(opts.SubnetID)
+func (c Client) CreatePort(opts *CreateOpts) (Port, error) {
+	copts := portCreateOpts(opts)
+
+	sbRes := []func() (*subnets.Subnet, error){c.getSubnetAsync(opts.SubnetID)}
+	if len(opts.SubnetIDv6) > 0 {
+		sbRes = append(sbRes, c.getSubnetAsync(opts.SubnetIDv6))
+	}
 	netRes := c.getNetworkAsync(opts.NetworkID)
 
-	p, err := ports.Create(c.networkCliV2, copts).Extract()
+	p, err := ports.Create(c.network(), copts).Extract()
 	if err != nil {
 		return Port{}, err
 	}
 
-	sb, err := sbRes()
-	if err != nil {
-		defer c.DeletePort(p.ID)
-		return Port{}, err
+	var sbs []*subnets.Subnet
+	for _, res := range sbRes {
+		sb, err := res()
+		if err != nil {
+			defer c.DeletePort(p.ID)
+			return Port{}, err
+		}
+		sbs = append(sbs, sb)
 	}
 
 	_, mtu, err := netRes()
@@ -184,39 +232,37 @@ func (c Client) CreatePort(opts *CreateOpts) (Port, error) {
 		return Port{}, err
 	}
 
-	np := Port{
-		Name:     p.Name,
-		ID:       p.ID,
-		SubnetID: opts.SubnetID,
-		MAC:      p.MACAddress,
-		IP:       p.FixedIPs[0].IPAddress,
-		CIDR:     sb.CIDR,
-		Gateway:  sb.GatewayIP,
-		MTU:      mtu,
-		Sgs:      p.SecurityGroups,
-	}
-	return np, nil
+	np := c.ConvertPort(sbs, *p)
+	np.MTU = mtu
+	return *np, nil
 }
 
-func (c Client) ConvertPort(subnet *subnets.Subnet, port ports.Port) *Port {
+// ConvertPort convert neutron port to Port, addresses are filled from fixed ips in subnets
+func (c Client) ConvertPort(sbs []*subnets.Subnet, port ports.Port) *Port {
 	p := &Port{
-		Name:    port.Name,
-		ID:      port.ID,
-		MAC:     port.MACAddress,
-		IP:      port.FixedIPs[0].IPAddress,
-		CIDR:    subnet.CIDR,
-		Gateway: subnet.GatewayIP,
-		Sgs:     port.SecurityGroups,
+		Name: port.Name,
+		ID:   port.ID,
+		MAC:  port.MACAddress,
+		Sgs:  port.SecurityGroups,
+		Tags: port.Tags,
+	}
+	for _, ip := range port.FixedIPs {
+		for _, sb := range sbs {
+			if sb.ID == ip.SubnetID {
+				p.setAddress(sb, ip.IPAddress)
+				break
+			}
+		}
 	}
 	return p
 }
 
-func (c Client) getPort(id string) (*ports.Port, error) {
-	return ports.Get(c.networkCliV2, id).Extract()
+func (c Client) GetPort(id string) (*ports.Port, error) {
+	return ports.Get(c.network(), id).Extract()
 }
 
 func (c Client) DeletePort(id string) error {
-	r := ports.Delete(c.networkCliV2, id)
+	r := ports.Delete(c.network(), id)
 	return r.ExtractErr()
 }
 
@@ -252,41 +298,29 @@ func (c Client) BindPortToHost(id, hostID, deviceID string) error {
 		VNICType: "normal",
 	}
 
-	_, err := ports.Update(c.networkCliV2, id, updateOpts).Extract()
+	_, err := ports.Update(c.network(), id, updateOpts).Extract()
 
 	return err
 }
 
-// WaitPortActive 返回一个函数，调用该函数会阻塞指定的 Neutron Port 状态变成 ACTIVE,
-// 或者超时返回错误
+// WaitPortActive 阻塞直到指定的 Neutron Port 状态变成 ACTIVE,
+// 超时或者 client 的 context 结束时返回错误
 func (c Client) WaitPortActive(id string, timeout float64) error {
-	start := time.Now()
-	ch := make(chan error, 1)
-	status := "EMPTY"
-
-	go func() {
-		tk := time.NewTicker(time.Duration(2) * time.Second)
-		for range tk.C {
-			elapse := time.Since(start)
-			if elapse.Seconds() > timeout {
-				ch <- fmt.Errorf("waiting port %s become ACTIVE timeout out: %.2f seconds", id, timeout)
-				break
-			}
-
-			p, err := c.getPort(id)
-			if err != nil {
-				continue
-			} else {
-				if status != p.Status {
-					status = p.Status
-				}
-				if p.Status == "ACTIVE" {
-					ch <- nil
-					break
-				}
-			}
+	ctx, cancel := context.WithTimeout(c.context(), time.Duration(timeout*float64(time.Second)))
+	defer cancel()
+	c.ctx = ctx
+
+	tk := time.NewTicker(time.Duration(2) * time.Second)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting port %s become ACTIVE timeout out: %.2f seconds with error: %w", id, timeout, ctx.Err())
+		case <-tk.C:
+		}
+		p, err := c.GetPort(id)
+		if err == nil && p.Status == "ACTIVE" {
+			return nil
 		}
-		tk.Stop()
-	}()
-	return <-ch
+	}
 }
@@ -0,0 +1,314 @@
+package neutron
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rubble/pkg/metrics"
+	"github.com/rubble/pkg/utils"
+)
+
+// Priority of requests to neutron, high priority requests are sent before waiting low priority ones
+type Priority int
+
+const (
+	// PriorityLow is for background work, e.g. pool refill and dispose, gc
+	PriorityLow Priority = iota
+	// PriorityHigh is for requests on the pod start path
+	PriorityHigh
+)
+
+const (
+	DefaultQPS            = 10
+	DefaultBurst          = 20
+	DefaultMaxConcurrency = 10
+	DefaultMaxBackoff     = 60 * time.Second
+
+	minBackoff = 500 * time.Millisecond
+	// maxThrottleRetries is retries of a request throttled with 429 or 503
+	maxThrottleRetries = 3
+	// requestTimeout bound every attempt of a request to neutron
+	requestTimeout = 60 * time.Second
+)
+
+func (p Priority) String() string {
+	if p == PriorityHigh {
+		return "high"
+	}
+	return "low"
+}
+
+type priorityKey struct{}
+
+// WithPriority return context carrying priority of requests sent with it
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom return priority in context, PriorityLow if not set
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityLow
+}
+
+// RateLimiter is a token bucket shared by all requests of a client, it also bounds concurrent requests.
+// high priority requests are served first, all requests are paused with exponential backoff
+// when neutron throttles with 429 or 503
+type RateLimiter struct {
+	lock           sync.Mutex
+	qps            float64
+	burst          int
+	maxConcurrency int
+	maxBackoff     time.Duration
+
+	tokens  float64
+	last    time.Time
+	running int
+	waiting [PriorityHigh + 1]int
+	// backoff is current pause after throttled, reset after a request succeeds
+	backoff     time.Duration
+	pausedUntil time.Time
+	// changed is closed and replaced when a waiting request may proceed
+	changed chan struct{}
+}
+
+// NewRateLimiter create limiter of config, zero values use defaults
+func NewRateLimiter(cfg utils.RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{changed: make(chan struct{})}
+	l.Configure(cfg)
+	l.tokens = float64(l.burst)
+	l.last = time.Now()
+	return l
+}
+
+// Configure update limits, zero values use defaults
+func (l *RateLimiter) Configure(cfg utils.RateLimitConfig) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.qps = cfg.QPS
+	if l.qps <= 0 {
+		l.qps = DefaultQPS
+	}
+	l.burst = cfg.Burst
+	if l.burst <= 0 {
+		l.burst = DefaultBurst
+	}
+	l.maxConcurrency = cfg.MaxConcurrency
+	if l.maxConcurrency <= 0 {
+		l.maxConcurrency = DefaultMaxConcurrency
+	}
+	l.maxBackoff = time.Duration(cfg.MaxBackoff) * time.Second
+	if l.maxBackoff <= 0 {
+		l.maxBackoff = DefaultMaxBackoff
+	}
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.broadcastLocked()
+}
+
+// Wait block until request of priority can be sent, release must be called after request is done
+func (l *RateLimiter) Wait(ctx context.Context, p Priority) (release func(), err error) {
+	start := time.Now()
+	defer func() {
+		metrics.NeutronRateLimitWait.WithLabelValues(p.String()).Observe(time.Since(start).Seconds())
+	}()
+
+	l.lock.Lock()
+	l.waiting[p]++
+	for {
+		now := time.Now()
+		l.refillLocked(now)
+
+		var wait time.Duration
+		switch {
+		case now.Before(l.pausedUntil):
+			wait = l.pausedUntil.Sub(now)
+		case p == PriorityLow && l.waiting[PriorityHigh] > 0:
+			// wait until high priority requests are sent
+		case l.running >= l.maxConcurrency:
+			// wait until a request is done
+		case l.tokens < 1:
+			wait = time.Duration((1 - l.tokens) / l.qps * float64(time.Second))
+		default:
+			l.tokens--
+			l.running++
+			l.waiting[p]--
+			l.broadcastLocked()
+			l.lock.Unlock()
+			return l.release, nil
+		}
+
+		changed := l.changed
+		l.lock.Unlock()
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			l.lock.Lock()
+			l.waiting[p]--
+			l.broadcastLocked()
+			l.lock.Unlock()
+			return nil, ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		l.lock.Lock()
+	}
+}
+
+func (l *RateLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.running--
+	l.broadcastLocked()
+}
+
+// Throttled pause all requests after neutron returns 429 or 503, backoff doubles on every throttled response
+// up to max backoff. retryAfter from response is used if it is longer and not above max backoff
+func (l *RateLimiter) Throttled(retryAfter time.Duration) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.backoff *= 2
+	if l.backoff < minBackoff {
+		l.backoff = minBackoff
+	}
+	if l.backoff > l.maxBackoff {
+		l.backoff = l.maxBackoff
+	}
+	pause := l.backoff
+	if retryAfter > pause {
+		pause = retryAfter
+		if pause > l.maxBackoff {
+			pause = l.maxBackoff
+		}
+	}
+	if until := time.Now().Add(pause); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	return pause
+}
+
+// Succeeded reset backoff after a request is not throttled
+func (l *RateLimiter) Succeeded() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.backoff = 0
+}
+
+func (l *RateLimiter) refillLocked(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.qps
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+}
+
+func (l *RateLimiter) broadcastLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// rateLimitedTransport send requests through limiter, throttled requests are retried after backoff.
+// every attempt is bounded by requestTimeout, waits in limiter are bounded by context of request only
+type rateLimitedTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	p := PriorityFrom(ctx)
+	for i := 0; ; i++ {
+		release, err := t.limiter.Wait(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		attempt, cancel, err := attemptRequest(req, i)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(attempt)
+		release()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// attempt is done when body of response is closed
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			t.limiter.Succeeded()
+			return resp, nil
+		}
+
+		metrics.NeutronThrottled.Inc()
+		pause := t.limiter.Throttled(retryAfter(resp))
+		if i >= maxThrottleRetries || !canRetry(req) {
+			return resp, nil
+		}
+		// do not retry if the request would time out before it is sent again
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(pause).After(deadline) {
+			return resp, nil
+		}
+		resp.Body.Close()
+	}
+}
+
+// attemptRequest clone req for the nth attempt with requestTimeout, body is read again for retries.
+// req itself is not modified as required by http.RoundTripper
+func attemptRequest(req *http.Request, n int) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	attempt := req.Clone(ctx)
+	if n > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		attempt.Body = body
+	}
+	return attempt, cancel, nil
+}
+
+// canRetry check whether body of req can be sent again
+func canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cancelBody cancel context of an attempt when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryAfter return delay in Retry-After header in seconds, zero if absent
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
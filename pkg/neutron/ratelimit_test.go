@@ -0,0 +1,189 @@
+package neutron
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubble/pkg/utils"
+)
+
+// stubTransport return responses of codes in order and record bodies of attempts
+type stubTransport struct {
+	lock     sync.Mutex
+	codes    []int
+	header   http.Header
+	bodies   []string
+	deadline []bool
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	body := ""
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+	}
+	s.bodies = append(s.bodies, body)
+	_, ok := req.Context().Deadline()
+	s.deadline = append(s.deadline, ok)
+
+	code := s.codes[0]
+	if len(s.codes) > 1 {
+		s.codes = s.codes[1:]
+	}
+	return &http.Response{StatusCode: code, Header: s.header, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		codes      []int
+		retryAfter string
+		noGetBody  bool
+		maxBackoff time.Duration
+		timeout    time.Duration
+		wantCode   int
+		attempts   int
+	}{
+		{name: "success", codes: []int{200}, wantCode: 200, attempts: 1},
+		{name: "throttled then success", codes: []int{429, 503, 200}, wantCode: 200, attempts: 3},
+		{name: "retries exhausted", codes: []int{503}, wantCode: 503, attempts: maxThrottleRetries + 1},
+		{name: "body can not be read again", codes: []int{429, 200}, noGetBody: true, wantCode: 429, attempts: 1},
+		{
+			name:       "deadline before retry",
+			codes:      []int{429, 200},
+			retryAfter: "1",
+			maxBackoff: time.Second,
+			timeout:    100 * time.Millisecond,
+			wantCode:   429,
+			attempts:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubTransport{codes: tt.codes, header: http.Header{}}
+			if len(tt.retryAfter) > 0 {
+				stub.header.Set("Retry-After", tt.retryAfter)
+			}
+			limiter := NewRateLimiter(utils.RateLimitConfig{})
+			limiter.maxBackoff = 10 * time.Millisecond
+			if tt.maxBackoff > 0 {
+				limiter.maxBackoff = tt.maxBackoff
+			}
+			transport := &rateLimitedTransport{limiter: limiter, next: stub}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://neutron/v2.0/ports", bytes.NewBufferString("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+			body := req.Body
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if len(stub.bodies) != tt.attempts {
+				t.Fatalf("attempts = %d, want %d", len(stub.bodies), tt.attempts)
+			}
+			for i, b := range stub.bodies {
+				if b != "body" {
+					t.Errorf("body of attempt %d = %q, want %q", i, b, "body")
+				}
+				if !stub.deadline[i] {
+					t.Errorf("attempt %d has no deadline", i)
+				}
+			}
+			if req.Body != body {
+				t.Errorf("body of request is replaced")
+			}
+		})
+	}
+}
+
+func TestRateLimiterThrottled(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBackoff time.Duration
+		retryAfter []time.Duration
+		want       []time.Duration
+	}{
+		{
+			name:       "exponential backoff",
+			maxBackoff: 3 * time.Second,
+			retryAfter: []time.Duration{0, 0, 0, 0},
+			want:       []time.Duration{minBackoff, 2 * minBackoff, 4 * minBackoff, 3 * time.Second},
+		},
+		{
+			name:       "retry after is longer",
+			maxBackoff: 10 * time.Second,
+			retryAfter: []time.Duration{2 * time.Second, 0},
+			want:       []time.Duration{2 * time.Second, 2 * minBackoff},
+		},
+		{
+			name:       "retry after above max backoff",
+			maxBackoff: time.Second,
+			retryAfter: []time.Duration{time.Minute},
+			want:       []time.Duration{time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(utils.RateLimitConfig{})
+			l.maxBackoff = tt.maxBackoff
+			for i, ra := range tt.retryAfter {
+				if got := l.Throttled(ra); got != tt.want[i] {
+					t.Errorf("pause %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     utils.RateLimitConfig
+		pause   bool
+		wantErr bool
+	}{
+		{name: "token available", cfg: utils.RateLimitConfig{QPS: 1, Burst: 1}},
+		{name: "no token before deadline", cfg: utils.RateLimitConfig{QPS: 1, Burst: 1}, pause: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(tt.cfg)
+			if tt.pause {
+				l.Throttled(time.Minute)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			release, err := l.Wait(ctx, PriorityHigh)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Wait() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				release()
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package neutron
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/rubble/pkg/utils"
+)
+
+// GetSecurityGroupID return id of security group by name or id
+func (c Client) GetSecurityGroupID(name string) (string, error) {
+	if utils.IsValidUUID(name) {
+		return name, nil
+	}
+
+	pages, err := groups.List(c.network(), groups.ListOpts{Name: name}).AllPages()
+	if err != nil {
+		return "", err
+	}
+	sgs, err := groups.ExtractGroups(pages)
+	if err != nil {
+		return "", err
+	}
+	switch len(sgs) {
+	case 0:
+		return "", fmt.Errorf("security group %s not found", name)
+	case 1:
+		return sgs[0].ID, nil
+	default:
+		return "", fmt.Errorf("more than one security group named %s, use id instead", name)
+	}
+}
+
+// UpdatePortSecurityGroups replace security groups of port
+func (c Client) UpdatePortSecurityGroups(id string, sgs []string) error {
+	sgs = append([]string{}, sgs...)
+	_, err := ports.Update(c.network(), id, ports.UpdateOpts{SecurityGroups: &sgs}).Extract()
+	return err
+}
+
+// ListSecurityGroups return security groups which name starts with prefix
+func (c Client) ListSecurityGroups(prefix string) ([]groups.SecGroup, error) {
+	pages, err := groups.List(c.network(), groups.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	sgs, err := groups.ExtractGroups(pages)
+	if err != nil {
+		return nil, err
+	}
+	var ret []groups.SecGroup
+	for _, sg := range sgs {
+		if strings.HasPrefix(sg.Name, prefix) {
+			ret = append(ret, sg)
+		}
+	}
+	return ret, nil
+}
+
+// CreateSecurityGroup create security group, neutron adds rules allowing all egress traffic to it
+func (c Client) CreateSecurityGroup(name, description string) (*groups.SecGroup, error) {
+	return groups.Create(c.network(), groups.CreateOpts{
+		Name:        name,
+		Description: description,
+	}).Extract()
+}
+
+// DeleteSecurityGroup delete security group, it fails if the group is still used by ports
+func (c Client) DeleteSecurityGroup(id string) error {
+	return groups.Delete(c.network(), id).ExtractErr()
+}
+
+// ListSecurityGroupRules return rules of security group
+func (c Client) ListSecurityGroupRules(sgID string) ([]rules.SecGroupRule, error) {
+	pages, err := rules.List(c.network(), rules.ListOpts{SecGroupID: sgID}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return rules.ExtractRules(pages)
+}
+
+func (c Client) CreateSecurityGroupRule(opts rules.CreateOpts) (*rules.SecGroupRule, error) {
+	return rules.Create(c.network(), opts).Extract()
+}
+
+func (c Client) DeleteSecurityGroupRule(id string) error {
+	return rules.Delete(c.network(), id).ExtractErr()
+}
@@ -6,13 +6,13 @@ import (
 )
 
 func (c Client) GetSubnet(id string) (*subnets.Subnet, error) {
-	r := subnets.Get(c.networkCliV2, id)
+	r := subnets.Get(c.network(), id)
 	return r.Extract()
 }
 
 func (c Client) ListSubnetworks() ([]subnets.Subnet, error) {
 	opts := subnets.ListOpts{}
-	pages, _ := subnets.List(c.networkCliV2, opts).AllPages()
+	pages, _ := subnets.List(c.network(), opts).AllPages()
 	allSubnets, _ := subnets.ExtractSubnets(pages)
 	return allSubnets, nil
 }
@@ -6,5 +6,10 @@ import (
 
 // AddTag create network from proton api
 func (c Client) AddTag(resourceType, resourceID, tag string) error {
-	return attributestags.Add(c.networkCliV2, resourceType, resourceID, tag).ExtractErr()
+	return attributestags.Add(c.network(), resourceType, resourceID, tag).ExtractErr()
+}
+
+// RemoveTag delete tag of resource
+func (c Client) RemoveTag(resourceType, resourceID, tag string) error {
+	return attributestags.Delete(c.network(), resourceType, resourceID, tag).ExtractErr()
 }
@@ -0,0 +1,55 @@
+package neutron
+
+import (
+	"fmt"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/trunks"
+)
+
+const (
+	// TrunkExtension is alias of neutron trunk extension
+	TrunkExtension = "trunk"
+	// SegmentationTypeVlan is segmentation type of subports, pod interface is vlan on trunk parent
+	SegmentationTypeVlan = "vlan"
+)
+
+// GetTrunkByPort return trunk with parent port portID
+func (c Client) GetTrunkByPort(portID string) (*trunks.Trunk, error) {
+	pages, err := trunks.List(c.network(), trunks.ListOpts{PortID: portID}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	ts, err := trunks.ExtractTrunks(pages)
+	if err != nil {
+		return nil, err
+	}
+	if len(ts) == 0 {
+		return nil, fmt.Errorf("port %s is not parent of any trunk", portID)
+	}
+	return &ts[0], nil
+}
+
+// ListSubports return subports of trunk
+func (c Client) ListSubports(trunkID string) ([]trunks.Subport, error) {
+	return trunks.GetSubports(c.network(), trunkID).Extract()
+}
+
+// AddSubport add port into trunk as subport with vlan id vid
+func (c Client) AddSubport(trunkID, portID string, vid int) error {
+	opts := trunks.AddSubportsOpts{
+		Subports: []trunks.Subport{
+			{PortID: portID, SegmentationType: SegmentationTypeVlan, SegmentationID: vid},
+		},
+	}
+	_, err := trunks.AddSubports(c.network(), trunkID, opts).Extract()
+	return err
+}
+
+// RemoveSubport remove subport from trunk
+func (c Client) RemoveSubport(trunkID, portID string) error {
+	opts := trunks.RemoveSubportsOpts{
+		Subports: []trunks.RemoveSubport{{PortID: portID}},
+	}
+	_, err := trunks.RemoveSubports(c.network(), trunkID, opts).Extract()
+	return err
+}
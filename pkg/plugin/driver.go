@@ -0,0 +1,211 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// Driver set up interfaces of pod with net config allocated by daemon, it is selected by datapath in
+// net conf
+type Driver interface {
+	// Setup create interfaces in container, undo of each step is added to rb
+	Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error)
+	// Check verify interfaces and routes created by Setup with net config recorded by daemon
+	Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) error
+	// TearDown remove interfaces in container, interfaces already removed are not an error
+	TearDown(args *utils.CniCmdArgs) error
+}
+
+// podLinkDriver create the interface of pod carrying its ip, the veth pair to host is created by PTPDriver
+type podLinkDriver interface {
+	Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error)
+	Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error)
+	TearDown(args *utils.CniCmdArgs) error
+}
+
+// NewDriver return driver of datapath in net conf, ipvlan by default
+func NewDriver(conf *utils.NetConf) (Driver, error) {
+	var link podLinkDriver
+	switch utils.GetDatapath(conf.Datapath) {
+	case utils.DatapathIPVlan:
+		link = NewIPVlanDriver()
+	case utils.DatapathMacvlan:
+		link = NewMacvlanDriver()
+	case utils.DatapathENI:
+		link = NewENIDriver()
+	case utils.DatapathTrunk:
+		link = NewTrunkDriver()
+	default:
+		return nil, fmt.Errorf("unknown datapath %q", conf.Datapath)
+	}
+	return &vethChainDriver{link: link, ptp: NewPTPDriver()}, nil
+}
+
+// vethChainDriver add veth pair to host after interface of pod, traffic to node and services goes
+// through host netns by the veth, which pod interface on master or attached to vm can not reach
+type vethChainDriver struct {
+	link podLinkDriver
+	ptp  *PTPDriver
+}
+
+func (d *vethChainDriver) Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
+	result, err := d.link.Setup(logger, reply, args, rb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup %s interface with error: %w", utils.GetDatapath(args.Datapath), err)
+	}
+	result, err = d.ptp.Setup(logger, result, args, rb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup ptp veth pair with error: %w", err)
+	}
+	return result, nil
+}
+
+func (d *vethChainDriver) Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) error {
+	result, err := d.link.Check(logger, netConfs, args)
+	if err != nil {
+		return fmt.Errorf("%s interface check failed: %w", utils.GetDatapath(args.Datapath), err)
+	}
+	if err = d.ptp.Check(logger, result, args); err != nil {
+		return fmt.Errorf("ptp veth check failed: %w", err)
+	}
+	return nil
+}
+
+// TearDown remove veth before pod interface, host side of veth and its routes go with the veth
+func (d *vethChainDriver) TearDown(args *utils.CniCmdArgs) error {
+	if err := d.ptp.TearDown(args); err != nil {
+		return fmt.Errorf("failed to teardown ptp veth with error: %w", err)
+	}
+	if err := d.link.TearDown(args); err != nil {
+		return fmt.Errorf("failed to teardown %s interface with error: %w", utils.GetDatapath(args.Datapath), err)
+	}
+	return nil
+}
+
+// addContainerLink create link on master in container and rename it to ifName, undo of the link is added
+// to rb. link is created with a tmp name like ipvlan, it might collide with the name on the host
+func addContainerLink(args *utils.CniCmdArgs, netns ns.NetNS, link netlink.Link, rb *Rollback) (*current.Interface, error) {
+	tmpName, err := ip.RandomVethName()
+	if err != nil {
+		return nil, err
+	}
+	link.Attrs().Name = tmpName
+	link.Attrs().Namespace = netlink.NsFd(int(netns.Fd()))
+	if err = netlink.LinkAdd(link); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %v", link.Type(), err)
+	}
+	name, nsPath := tmpName, netns.Path()
+	rb.Add(link.Type()+" link", func() error {
+		return delLink(nsPath, name)
+	})
+
+	iface := &current.Interface{}
+	err = netns.Do(func(_ ns.NetNS) error {
+		if err := ip.RenameLink(tmpName, args.RawArgs.IfName); err != nil {
+			return fmt.Errorf("failed to rename %s to %q: %w", link.Type(), args.RawArgs.IfName, err)
+		}
+		name = args.RawArgs.IfName
+
+		contLink, err := netlink.LinkByName(args.RawArgs.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to refetch %s %q: %w", link.Type(), args.RawArgs.IfName, err)
+		}
+		iface.Name = args.RawArgs.IfName
+		iface.Mac = contLink.Attrs().HardwareAddr.String()
+		iface.Sandbox = netns.Path()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return iface, nil
+}
+
+// newPodResult convert net config returned by daemon to cni result, ipv4 and ipv6 addresses are both
+// configured for dual stack pod
+func newPodResult(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error) {
+	if len(netConfs) == 0 || netConfs[0].BasicInfo == nil {
+		return nil, fmt.Errorf("missing net config")
+	}
+	basicInfo := netConfs[0].BasicInfo
+
+	result := &current.Result{
+		CNIVersion: current.ImplementedSpecVersion,
+	}
+
+	// extra routes from daemon and net conf are via gateway of pod subnet of the same ip family
+	var extraDsts []*net.IPNet
+	extra := append([]string{}, args.ExtraRoutes...)
+	for _, route := range netConfs[0].ExtraRoutes {
+		extra = append(extra, route.Dst)
+	}
+	for _, dst := range extra {
+		_, ipNet, err := net.ParseCIDR(dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse extra route %q: %w", dst, err)
+		}
+		extraDsts = append(extraDsts, ipNet)
+	}
+
+	families := []struct {
+		ipaddr, gwaddr, cidr, dst string
+	}{
+		{basicInfo.PodIP.GetIPv4(), basicInfo.GatewayIP.GetIPv4(), basicInfo.PodCIDR.GetIPv4(), utils.DefaultDst},
+		{basicInfo.PodIP.GetIPv6(), basicInfo.GatewayIP.GetIPv6(), basicInfo.PodCIDR.GetIPv6(), utils.DefaultDstV6},
+	}
+	for _, f := range families {
+		if len(f.ipaddr) == 0 {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(f.cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pod cidr %q: %w", f.cidr, err)
+		}
+
+		ip := &current.IPConfig{
+			Interface: current.Int(0),
+			Address: net.IPNet{
+				IP:   net.ParseIP(f.ipaddr),
+				Mask: ipNet.Mask,
+			},
+			Gateway: net.ParseIP(f.gwaddr),
+		}
+		logger.Debugf("pod address %s, gateway %s", ip.Address.String(), ip.Gateway)
+		result.IPs = append(result.IPs, ip)
+
+		if utils.GetIpVlanDefaultRoute(args.NetConf) {
+			_, dst, err := net.ParseCIDR(f.dst)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add default route with error: %w", err)
+			}
+			result.Routes = append(result.Routes, &types.Route{
+				Dst: *dst,
+				GW:  net.ParseIP(f.gwaddr),
+			})
+		}
+
+		for _, dst := range extraDsts {
+			if (dst.IP.To4() != nil) != (ip.Address.IP.To4() != nil) {
+				continue
+			}
+			result.Routes = append(result.Routes, &types.Route{
+				Dst: *dst,
+				GW:  net.ParseIP(f.gwaddr),
+			})
+		}
+	}
+
+	if len(result.IPs) == 0 {
+		return nil, fmt.Errorf("missing pod ip in net config")
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewPodResult(t *testing.T) {
+	v4 := &rpc.BasicInfo{
+		PodIP:     &rpc.IPSet{IPv4: "10.0.0.5"},
+		PodCIDR:   &rpc.IPSet{IPv4: "10.0.0.0/24"},
+		GatewayIP: &rpc.IPSet{IPv4: "10.0.0.1"},
+	}
+	v6 := &rpc.BasicInfo{
+		PodIP:     &rpc.IPSet{IPv6: "fd00::5"},
+		PodCIDR:   &rpc.IPSet{IPv6: "fd00::/64"},
+		GatewayIP: &rpc.IPSet{IPv6: "fd00::1"},
+	}
+	dual := &rpc.BasicInfo{
+		PodIP:     &rpc.IPSet{IPv4: "10.0.0.5", IPv6: "fd00::5"},
+		PodCIDR:   &rpc.IPSet{IPv4: "10.0.0.0/24", IPv6: "fd00::/64"},
+		GatewayIP: &rpc.IPSet{IPv4: "10.0.0.1", IPv6: "fd00::1"},
+	}
+	extra := []*rpc.Route{{Dst: "172.16.0.0/16"}, {Dst: "fd01::/64"}}
+
+	tests := []struct {
+		name       string
+		info       *rpc.BasicInfo
+		extra      []string
+		wantIPs    []string
+		wantRoutes []string
+		wantErr    bool
+	}{
+		{
+			name:       "ipv4",
+			info:       v4,
+			extra:      []string{"10.96.0.0/12"},
+			wantIPs:    []string{"10.0.0.5/24 via 10.0.0.1"},
+			wantRoutes: []string{"0.0.0.0/0 via 10.0.0.1", "10.96.0.0/12 via 10.0.0.1", "172.16.0.0/16 via 10.0.0.1"},
+		},
+		{
+			name:       "ipv6 only",
+			info:       v6,
+			wantIPs:    []string{"fd00::5/64 via fd00::1"},
+			wantRoutes: []string{"::/0 via fd00::1", "fd01::/64 via fd00::1"},
+		},
+		{
+			name:    "dual stack",
+			info:    dual,
+			wantIPs: []string{"10.0.0.5/24 via 10.0.0.1", "fd00::5/64 via fd00::1"},
+			wantRoutes: []string{"0.0.0.0/0 via 10.0.0.1", "172.16.0.0/16 via 10.0.0.1",
+				"::/0 via fd00::1", "fd01::/64 via fd00::1"},
+		},
+		{
+			name:    "no pod ip",
+			info:    &rpc.BasicInfo{PodIP: &rpc.IPSet{}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cidr",
+			info:    &rpc.BasicInfo{PodIP: &rpc.IPSet{IPv4: "10.0.0.5"}, PodCIDR: &rpc.IPSet{IPv4: "10.0.0.0"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid extra route",
+			info:    v4,
+			extra:   []string{"10.96.0.0"},
+			wantErr: true,
+		},
+	}
+	logger := logrus.NewEntry(logrus.New())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &utils.CniCmdArgs{NetConf: &utils.NetConf{ExtraRoutes: tt.extra}}
+			result, err := newPodResult(logger, []*rpc.NetConf{{BasicInfo: tt.info, ExtraRoutes: extra}}, args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ips, routes []string
+			for _, ip := range result.IPs {
+				ips = append(ips, ip.Address.String()+" via "+ip.Gateway.String())
+			}
+			for _, route := range result.Routes {
+				routes = append(routes, route.Dst.String()+" via "+route.GW.String())
+			}
+			if !reflect.DeepEqual(ips, tt.wantIPs) {
+				t.Errorf("ips = %v, want %v", ips, tt.wantIPs)
+			}
+			if !reflect.DeepEqual(routes, tt.wantRoutes) {
+				t.Errorf("routes = %v, want %v", routes, tt.wantRoutes)
+			}
+		})
+	}
+
+	if _, err := newPodResult(logger, nil, &utils.CniCmdArgs{NetConf: &utils.NetConf{}}); err == nil {
+		t.Errorf("result without net config succeeded")
+	}
+}
@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// eniPollInterval is interval to look for interface of port hot-plugged into vm
+const eniPollInterval = 200 * time.Millisecond
+
+// ENIDriver move interface of neutron port, which daemon hot-plugs into vm, from host into container.
+// name of the interface on host is kept in its alias, so it is moved back with the name on teardown
+type ENIDriver struct{}
+
+func NewENIDriver() *ENIDriver {
+	return &ENIDriver{}
+}
+
+// Setup move interface with mac of port into container and configure addresses allocated, undo moving
+// the interface back to host is added to rb
+func (d *ENIDriver) Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
+	if reply.IPType != rpc.IPType_TypeVPCENI {
+		return nil, fmt.Errorf("daemon returned ip type %s, eni datapath requires daemon with eni datapath", reply.IPType)
+	}
+	mac, err := portMAC(reply.NetConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, reply.NetConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := waitLinkByMAC(mac, utils.DefaultENIWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+	hostName := link.Attrs().Name
+	// interface of port has mtu of neutron network, veth to host uses the same mtu
+	if linkMTU := link.Attrs().MTU; args.MTU == 0 || args.MTU > linkMTU {
+		args.MTU = linkMTU
+	}
+	logger.Infof("move interface %s of port with mac %s into container", hostName, mac)
+	if err = netlink.LinkSetDown(link); err != nil {
+		return nil, fmt.Errorf("failed to set %q down: %w", hostName, err)
+	}
+	if err = netlink.LinkSetAlias(link, hostName); err != nil {
+		return nil, fmt.Errorf("failed to set alias of %q: %w", hostName, err)
+	}
+	if err = netlink.LinkSetNsFd(link, int(netNs.Fd())); err != nil {
+		return nil, fmt.Errorf("failed to move %q into container: %w", hostName, err)
+	}
+	name, nsPath := hostName, netNs.Path()
+	rb.Add("eni link", func() error {
+		return moveLinkToHost(nsPath, name)
+	})
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		if err := ip.RenameLink(hostName, args.RawArgs.IfName); err != nil {
+			return fmt.Errorf("failed to rename %q to %q: %w", hostName, args.RawArgs.IfName, err)
+		}
+		name = args.RawArgs.IfName
+
+		link, err := netlink.LinkByName(args.RawArgs.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to refetch %q: %w", args.RawArgs.IfName, err)
+		}
+		if err = netlink.LinkSetMTU(link, args.MTU); err != nil {
+			return fmt.Errorf("failed to set mtu of %q to %d: %w", args.RawArgs.IfName, args.MTU, err)
+		}
+		result.Interfaces = []*current.Interface{
+			{
+				Name:    args.RawArgs.IfName,
+				Mac:     mac.String(),
+				Sandbox: netNs.Path(),
+			},
+		}
+		return ipam.ConfigureIface(args.RawArgs.IfName, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure interface of port with error: %w", err)
+	}
+	return result, nil
+}
+
+// Check verify the interface in container has mac of port, the address and routes from net config
+func (d *ENIDriver) Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error) {
+	mac, err := portMAC(netConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, netConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(args.RawArgs.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to find interface %q: %w", args.RawArgs.IfName, err)
+		}
+		if link.Type() != "device" {
+			return fmt.Errorf("interface %q is %s, not interface of port", args.RawArgs.IfName, link.Type())
+		}
+		if !bytes.Equal(link.Attrs().HardwareAddr, mac) {
+			return fmt.Errorf("mac of interface %q is %s, not %s of port", args.RawArgs.IfName, link.Attrs().HardwareAddr, mac)
+		}
+		result.Interfaces = []*current.Interface{
+			{
+				Name:    args.RawArgs.IfName,
+				Mac:     mac.String(),
+				Sandbox: netNs.Path(),
+			},
+		}
+
+		if err := ip.ValidateExpectedInterfaceIPs(args.RawArgs.IfName, result.IPs); err != nil {
+			return err
+		}
+		return ip.ValidateExpectedRoute(result.Routes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TearDown move interface of port back to host before daemon detaches the port, interface left in a
+// netns being deleted is moved back by kernel
+func (d *ENIDriver) TearDown(args *utils.CniCmdArgs) error {
+	return moveLinkToHost(args.NetNS, args.RawArgs.IfName)
+}
+
+// waitLinkByMAC wait for interface with mac to appear on host, nova hot-plugs it asynchronously
+func waitLinkByMAC(mac net.HardwareAddr, timeout time.Duration) (netlink.Link, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		links, err := netlink.LinkList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list links: %w", err)
+		}
+		for _, link := range links {
+			if link.Type() == "device" && bytes.Equal(link.Attrs().HardwareAddr, mac) {
+				return link, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("interface with mac %s not found on host in %s", mac, timeout)
+		}
+		time.Sleep(eniPollInterval)
+	}
+}
+
+// moveLinkToHost rename interface in netns at nsPath back to name in its alias and move it to host
+// netns, a link or netns already gone is not an error
+func moveLinkToHost(nsPath, name string) error {
+	hostNS, err := ns.GetCurrentNS()
+	if err != nil {
+		return fmt.Errorf("failed to open host netns: %w", err)
+	}
+	defer hostNS.Close()
+
+	err = ns.WithNetNSPath(nsPath, func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				return nil
+			}
+			return err
+		}
+		if link.Type() != "device" {
+			return fmt.Errorf("interface %q is %s, not interface of port", name, link.Type())
+		}
+		if err = netlink.LinkSetDown(link); err != nil {
+			return err
+		}
+		// name in container may be taken on host, e.g. eth0
+		hostName := link.Attrs().Alias
+		if len(hostName) == 0 {
+			if hostName, err = ip.RandomVethName(); err != nil {
+				return err
+			}
+		}
+		if hostName != name {
+			if err = ip.RenameLink(name, hostName); err != nil {
+				return err
+			}
+		}
+		return netlink.LinkSetNsFd(link, int(hostNS.Fd()))
+	})
+	if _, ok := err.(ns.NSPathNotExistErr); ok {
+		return nil
+	}
+	return err
+}
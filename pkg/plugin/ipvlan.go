@@ -2,13 +2,11 @@ package plugin
 
 import (
 	"fmt"
-	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ip"
 	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/rubble/pkg/rpc"
 	"github.com/rubble/pkg/utils"
-	"net"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/sirupsen/logrus"
@@ -21,7 +19,8 @@ func NewIPVlanDriver() *IPVlanDriver {
 	return &IPVlanDriver{}
 }
 
-func (d *IPVlanDriver) Setup(logger *logrus.Entry, allocateResult *rpc.AllocateIPReply, args *utils.CniCmdArgs) (*current.Result, error) {
+// Setup create ipvlan interface in container with addresses allocated, undo of the interface is added to rb
+func (d *IPVlanDriver) Setup(logger *logrus.Entry, allocateResult *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
 	netNs, err := ns.GetNS(args.NetNS)
 
 	if err != nil {
@@ -29,61 +28,63 @@ func (d *IPVlanDriver) Setup(logger *logrus.Entry, allocateResult *rpc.AllocateI
 	}
 	defer netNs.Close()
 
-	ipVlanSlave, err := createIPVlan(args, netNs)
+	result, err := newPodResult(logger, allocateResult.NetConfs, args)
 	if err != nil {
 		return nil, err
 	}
 
-	ipaddr := allocateResult.NetConfs[0].BasicInfo.PodIP.IPv4
-	gwaddr := allocateResult.NetConfs[0].BasicInfo.GatewayIP.IPv4
-	cidr := allocateResult.NetConfs[0].BasicInfo.PodCIDR.IPv4
-
-	logger.Infof("*********** IP SetUP args is: %s, %s, %s", ipaddr, gwaddr, cidr)
-
-	_, ipv4Net, _ := net.ParseCIDR(cidr)
+	ipVlanSlave, err := createIPVlan(args, netNs, rb)
+	if err != nil {
+		return nil, err
+	}
+	result.Interfaces = []*current.Interface{ipVlanSlave}
+	logger.Debugf("ipvlan result %+v", result)
 
-	ip := &current.IPConfig{
-		Interface: current.Int(0),
-		Address: net.IPNet{
-			IP:   net.ParseIP(ipaddr),
-			Mask: ipv4Net.Mask,
-		},
-		Gateway: net.ParseIP(gwaddr),
+	err = netNs.Do(func(_ ns.NetNS) error {
+		return ipam.ConfigureIface(args.RawArgs.IfName, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure ip address for ipvlan interface with error: %w", err)
 	}
+	return result, nil
+}
 
-	logger.Infof("*********** IP SetUP IP is: %+v", *ip)
-	logger.Infof("*********** IP SetUP interface is: %+v", *ipVlanSlave)
+// Check verify the ipvlan interface in container has the address and routes from net config
+func (d *IPVlanDriver) Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error) {
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
 
-	result := &current.Result{
-		CNIVersion: current.ImplementedSpecVersion,
-		Interfaces: []*current.Interface{ipVlanSlave},
-		IPs:        []*current.IPConfig{ip},
+	result, err := newPodResult(logger, netConfs, args)
+	if err != nil {
+		return nil, err
 	}
 
-	if utils.GetIpVlanDefaultRoute(args.NetConf) {
-		var routes []*types.Route
-		dst, mask, err := net.ParseCIDR(utils.DefaultDst)
+	err = netNs.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(args.RawArgs.IfName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to add default route with error: %w", err)
+			return fmt.Errorf("failed to find ipvlan interface %q: %w", args.RawArgs.IfName, err)
+		}
+		if link.Type() != "ipvlan" {
+			return fmt.Errorf("interface %q is %s, not ipvlan", args.RawArgs.IfName, link.Type())
 		}
-		routes = append(routes, &types.Route{
-			Dst: net.IPNet{
-				IP:   dst,
-				Mask: mask.Mask,
+		result.Interfaces = []*current.Interface{
+			{
+				Name:    args.RawArgs.IfName,
+				Mac:     link.Attrs().HardwareAddr.String(),
+				Sandbox: netNs.Path(),
 			},
-			GW: net.ParseIP(gwaddr),
-		})
-
-		result.Routes = routes
-	}
-
-	logger.Infof("*********** result is %+v", result)
+		}
 
-	err = netNs.Do(func(_ ns.NetNS) error {
-		return ipam.ConfigureIface(args.RawArgs.IfName, result)
+		if err := ip.ValidateExpectedInterfaceIPs(args.RawArgs.IfName, result.IPs); err != nil {
+			return err
+		}
+		return ip.ValidateExpectedRoute(result.Routes)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure ip address for ipvlan interface with error: %w", err)
+		return nil, err
 	}
 	return result, nil
 }
@@ -134,9 +135,7 @@ func modeFromString(s string) (netlink.IPVlanMode, error) {
 	}
 }
 
-func createIPVlan(args *utils.CniCmdArgs, netns ns.NetNS) (*current.Interface, error) {
-	slave := &current.Interface{}
-
+func createIPVlan(args *utils.CniCmdArgs, netns ns.NetNS, rb *Rollback) (*current.Interface, error) {
 	mode, err := modeFromString(utils.GetIpVlanMode(args.NetConf))
 	if err != nil {
 		return nil, err
@@ -147,47 +146,12 @@ func createIPVlan(args *utils.CniCmdArgs, netns ns.NetNS) (*current.Interface, e
 		return nil, fmt.Errorf("failed to lookup master %q: %v", utils.GetIpVlanMaster(args.NetConf), err)
 	}
 
-	// due to kernel bug we have to create with tmpname or it might
-	// collide with the name on the host and error out
-	tmpName, err := ip.RandomVethName()
-	if err != nil {
-		return nil, err
-	}
-
 	mv := &netlink.IPVlan{
 		LinkAttrs: netlink.LinkAttrs{
 			MTU:         args.MTU,
-			Name:        tmpName,
 			ParentIndex: m.Attrs().Index,
-			Namespace:   netlink.NsFd(int(netns.Fd())),
 		},
 		Mode: mode,
 	}
-
-	if err = netlink.LinkAdd(mv); err != nil {
-		return nil, fmt.Errorf("failed to create ipvlan: %v", err)
-	}
-
-	err = netns.Do(func(_ ns.NetNS) error {
-		err = ip.RenameLink(tmpName, args.RawArgs.IfName)
-		if err != nil {
-			return fmt.Errorf("failed to rename ipvlan to %q: %w", args.RawArgs.IfName, err)
-		}
-		slave.Name = args.RawArgs.IfName
-
-		// Re-fetch ipvlan to get all properties/attributes
-		contIPVlan, err := netlink.LinkByName(slave.Name)
-		if err != nil {
-			return fmt.Errorf("failed to refetch ipvlan %q: %w", slave.Name, err)
-		}
-		slave.Mac = contIPVlan.Attrs().HardwareAddr.String()
-		slave.Sandbox = netns.Path()
-
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return slave, nil
+	return addContainerLink(args, netns, mv, rb)
 }
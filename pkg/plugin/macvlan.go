@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// MacvlanDriver create macvlan on master with mac of neutron port. the port is not bound to node, frames
+// of pod leave through port of master and are dropped by anti-spoofing of it, so port security of port of
+// master must be disabled like ipvlan
+type MacvlanDriver struct{}
+
+func NewMacvlanDriver() *MacvlanDriver {
+	return &MacvlanDriver{}
+}
+
+// Setup create macvlan interface in container with mac of port and addresses allocated, undo of the
+// interface is added to rb
+func (d *MacvlanDriver) Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
+	mac, err := portMAC(reply.NetConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, reply.NetConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	iface, err := createMacvlan(args, netNs, mac, rb)
+	if err != nil {
+		return nil, err
+	}
+	result.Interfaces = []*current.Interface{iface}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		return ipam.ConfigureIface(args.RawArgs.IfName, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure ip address for macvlan interface with error: %w", err)
+	}
+	return result, nil
+}
+
+// Check verify the macvlan interface in container has mac of port, the address and routes from net config
+func (d *MacvlanDriver) Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error) {
+	mac, err := portMAC(netConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, netConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(args.RawArgs.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to find macvlan interface %q: %w", args.RawArgs.IfName, err)
+		}
+		if link.Type() != "macvlan" {
+			return fmt.Errorf("interface %q is %s, not macvlan", args.RawArgs.IfName, link.Type())
+		}
+		if link.Attrs().HardwareAddr.String() != mac.String() {
+			return fmt.Errorf("mac of interface %q is %s, not %s of port", args.RawArgs.IfName, link.Attrs().HardwareAddr, mac)
+		}
+		result.Interfaces = []*current.Interface{
+			{
+				Name:    args.RawArgs.IfName,
+				Mac:     mac.String(),
+				Sandbox: netNs.Path(),
+			},
+		}
+
+		if err := ip.ValidateExpectedInterfaceIPs(args.RawArgs.IfName, result.IPs); err != nil {
+			return err
+		}
+		return ip.ValidateExpectedRoute(result.Routes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *MacvlanDriver) TearDown(args *utils.CniCmdArgs) error {
+	return delLink(args.NetNS, args.RawArgs.IfName)
+}
+
+// portMAC return mac of neutron port in net config
+func portMAC(netConfs []*rpc.NetConf) (net.HardwareAddr, error) {
+	if len(netConfs) == 0 || netConfs[0].ENIInfo == nil || len(netConfs[0].ENIInfo.MAC) == 0 {
+		return nil, fmt.Errorf("missing mac of port in net config")
+	}
+	mac, err := net.ParseMAC(netConfs[0].ENIInfo.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mac of port %q: %w", netConfs[0].ENIInfo.MAC, err)
+	}
+	return mac, nil
+}
+
+func createMacvlan(args *utils.CniCmdArgs, netns ns.NetNS, mac net.HardwareAddr, rb *Rollback) (*current.Interface, error) {
+	m, err := netlink.LinkByName(utils.GetIpVlanMaster(args.NetConf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup master %q: %v", utils.GetIpVlanMaster(args.NetConf), err)
+	}
+	mv := &netlink.Macvlan{
+		LinkAttrs: netlink.LinkAttrs{
+			MTU:          args.MTU,
+			HardwareAddr: mac,
+			ParentIndex:  m.Attrs().Index,
+		},
+		Mode: netlink.MACVLAN_MODE_BRIDGE,
+	}
+	return addContainerLink(args, netns, mv, rb)
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// maxIfNameLen is max length of interface name in linux
+	maxIfNameLen = 15
+	// minVethHashLen is min hex chars of container id hash in name of host veth, names of sandboxes
+	// collide too likely with shorter hash
+	minVethHashLen = 8
+)
+
+// PodMTU return mtu of pod interfaces: mtu in net conf if set, otherwise mtu of neutron network less
+// mtu_overhead. interfaces of ipvlan, macvlan and vlan of trunk are on master and limited to its mtu.
+// interface of port for eni is not on master, eni driver limits mtu to the interface, 0 keeps its mtu
+func PodMTU(args *utils.CniCmdArgs, netConfs []*rpc.NetConf) (int, error) {
+	mtu := args.MTU
+	if mtu == 0 && len(netConfs) > 0 && netConfs[0].MTU > 0 {
+		mtu = int(netConfs[0].MTU) - args.MTUOverhead
+		if mtu <= 0 {
+			return 0, fmt.Errorf("invalid mtu %d, mtu_overhead %d is too large", mtu, args.MTUOverhead)
+		}
+	}
+	if utils.GetDatapath(args.Datapath) == utils.DatapathENI {
+		if mtu < 0 {
+			return 0, fmt.Errorf("invalid mtu %d", mtu)
+		}
+		return mtu, nil
+	}
+	master, err := netlink.LinkByName(utils.GetIpVlanMaster(args.NetConf))
+	if err != nil {
+		return 0, fmt.Errorf("failed to lookup master %q: %v", utils.GetIpVlanMaster(args.NetConf), err)
+	}
+	if masterMTU := master.Attrs().MTU; mtu == 0 || mtu > masterMTU {
+		mtu = masterMTU
+	}
+	if mtu <= 0 {
+		return 0, fmt.Errorf("invalid mtu %d", mtu)
+	}
+	return mtu, nil
+}
+
+// hostVethName return name of veth on host for sandbox with host_veth_prefix, empty if no prefix
+// configured so veth is named randomly. prefix leaving less than minVethHashLen chars of hash is refused
+func hostVethName(args *utils.CniCmdArgs) (string, error) {
+	if len(args.HostVethPrefix) == 0 {
+		return "", nil
+	}
+	if len(args.HostVethPrefix) > maxIfNameLen-minVethHashLen {
+		return "", fmt.Errorf("host_veth_prefix %q is longer than %d chars", args.HostVethPrefix, maxIfNameLen-minVethHashLen)
+	}
+	sum := sha1.Sum([]byte(args.K8sInfraContainerID))
+	name := args.HostVethPrefix + hex.EncodeToString(sum[:])
+	return name[:maxIfNameLen], nil
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/vishvananda/netlink"
+)
+
+func TestPodMTU(t *testing.T) {
+	lo, err := netlink.LinkByName("lo")
+	if err != nil {
+		t.Skipf("no loopback: %v", err)
+	}
+	loMTU := lo.Attrs().MTU
+	network := []*rpc.NetConf{{MTU: 1450}}
+
+	tests := []struct {
+		name     string
+		conf     utils.NetConf
+		netConfs []*rpc.NetConf
+		want     int
+		wantErr  bool
+	}{
+		{name: "network mtu", conf: utils.NetConf{Master: "lo"}, netConfs: network, want: 1450},
+		{name: "network mtu less overhead", conf: utils.NetConf{Master: "lo", MTUOverhead: 50}, netConfs: network, want: 1400},
+		{name: "mtu in net conf", conf: utils.NetConf{Master: "lo", MTU: 1300, MTUOverhead: 50}, netConfs: network, want: 1300},
+		{name: "master mtu without network mtu", conf: utils.NetConf{Master: "lo"}, want: loMTU},
+		{name: "limited to master", conf: utils.NetConf{Master: "lo", MTU: loMTU + 1}, want: loMTU},
+		{name: "overhead too large", conf: utils.NetConf{Master: "lo", MTUOverhead: 1500}, netConfs: network, wantErr: true},
+		{name: "overhead equal to network mtu", conf: utils.NetConf{Master: "lo", MTUOverhead: 1450}, netConfs: network, wantErr: true},
+		{name: "negative mtu", conf: utils.NetConf{Master: "lo", MTU: -1}, wantErr: true},
+		{name: "master not found", conf: utils.NetConf{Master: "rubble-none"}, netConfs: network, wantErr: true},
+		// eni does not use master, mtu is limited to interface of port by eni driver
+		{name: "eni ignores master", conf: utils.NetConf{Master: "rubble-none", Datapath: utils.DatapathENI, MTUOverhead: 50}, netConfs: network, want: 1400},
+		{name: "eni without network mtu", conf: utils.NetConf{Master: "rubble-none", Datapath: utils.DatapathENI}, want: 0},
+		{name: "eni overhead too large", conf: utils.NetConf{Datapath: utils.DatapathENI, MTUOverhead: 1450}, netConfs: network, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			mtu, err := PodMTU(&utils.CniCmdArgs{NetConf: &conf}, tt.netConfs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got mtu %d, want error", mtu)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mtu != tt.want {
+				t.Errorf("mtu = %d, want %d", mtu, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostVethName(t *testing.T) {
+	maxPrefix := strings.Repeat("p", maxIfNameLen-minVethHashLen)
+	tests := []struct {
+		name    string
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{name: "no prefix"},
+		{name: "short prefix", prefix: "rbl", want: "rbl"},
+		{name: "longest prefix", prefix: maxPrefix, want: maxPrefix},
+		{name: "prefix too long", prefix: maxPrefix + "p", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &utils.CniCmdArgs{
+				NetConf: &utils.NetConf{HostVethPrefix: tt.prefix},
+				K8sArgs: &utils.K8sArgs{K8sInfraContainerID: "c0"},
+			}
+			name, err := hostVethName(args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want error", name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.prefix) == 0 {
+				if name != "" {
+					t.Errorf("name = %q, want random name", name)
+				}
+				return
+			}
+			if !strings.HasPrefix(name, tt.want) || len(name) != maxIfNameLen {
+				t.Errorf("name = %q, want %d chars with prefix %q", name, maxIfNameLen, tt.want)
+			}
+			if again, _ := hostVethName(args); again != name {
+				t.Errorf("name of the same sandbox changed from %q to %q", name, again)
+			}
+			args.K8sInfraContainerID = "c1"
+			if other, _ := hostVethName(args); other == name {
+				t.Errorf("sandboxes got the same name %q", name)
+			}
+		})
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/utils/sysctl"
 	"github.com/rubble/pkg/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 	"net"
+	"strings"
 )
 
 type PTPDriver struct{}
@@ -17,12 +21,11 @@ func NewPTPDriver() *PTPDriver {
 	return &PTPDriver{}
 }
 
-func (d *PTPDriver) Setup(logger *logrus.Entry, pre *current.Result, args *utils.CniCmdArgs) (*current.Result, error) {
+// Setup create veth pair between container and host with routes to node and services, undo of the veth
+// pair and routes are added to rb
+func (d *PTPDriver) Setup(logger *logrus.Entry, pre *current.Result, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
 	// Convert whatever the IPAM result was into the current Result type
-
-	logger.Infof("######## Start PTP, current result is: %+v", *pre)
 	result, _ := current.NewResultFromResult(pre)
-	logger.Infof("######### After convert, result is: %+v", *result)
 
 	if len(result.IPs) == 0 {
 		return nil, fmt.Errorf("missing IP config")
@@ -34,18 +37,83 @@ func (d *PTPDriver) Setup(logger *logrus.Entry, pre *current.Result, args *utils
 	}
 	defer netNs.Close()
 
-	hostInterface, _, err := setupContainerVeth(logger, netNs, utils.DefaultContainerVethName, args, result)
+	hostInterface, _, err := setupContainerVeth(logger, netNs, utils.GetVethName(args.NetConf), args, result, rb)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create veth with error: %w", err)
 	}
 
-	if err = setupHostVeth(logger, hostInterface.Name, result); err != nil {
+	if err = setupHostVeth(logger, hostInterface.Name, result, rb); err != nil {
 		return nil, fmt.Errorf("failed to setup veth pair on host with error: %w", err)
 	}
 
 	return result, nil
 }
 
+// Check verify the veth pair and routes created by Setup, result is the one returned by ipvlan driver
+func (d *PTPDriver) Check(logger *logrus.Entry, result *current.Result, args *utils.CniCmdArgs) error {
+	if len(result.IPs) == 0 {
+		return fmt.Errorf("missing IP config")
+	}
+
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	nodeGws, err := getNodeGateways(utils.GetIpVlanMaster(args.NetConf), result)
+	if err != nil {
+		return fmt.Errorf("failed to get node gateway with error: %w", err)
+	}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		vethName := utils.GetVethName(args.NetConf)
+		contVeth, err := netlink.LinkByName(vethName)
+		if err != nil {
+			return fmt.Errorf("failed to find veth %q: %w", vethName, err)
+		}
+		if contVeth.Type() != "veth" {
+			return fmt.Errorf("interface %q is %s, not veth", vethName, contVeth.Type())
+		}
+
+		routes, err := containerVethRoutes(contVeth.Attrs().Index, nodeGws, args)
+		if err != nil {
+			return err
+		}
+		for _, route := range routes {
+			if err := checkRoute(&route); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// find the host veth by the route to pod ip
+	for _, ipc := range result.IPs {
+		podIP := ipc.Address.IP
+		route := hostVethRoute(0, podIP)
+		found, err := netlink.RouteListFiltered(routeFamily(&route), &netlink.Route{Dst: route.Dst}, netlink.RT_FILTER_DST)
+		if err != nil {
+			return fmt.Errorf("failed to list route to %s on host with error: %w", podIP, err)
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("route to %s not found on host", podIP)
+		}
+		hostVeth, err := netlink.LinkByIndex(found[0].LinkIndex)
+		if err != nil {
+			return fmt.Errorf("failed to get link of route to %s on host with error: %w", podIP, err)
+		}
+		if hostVeth.Type() != "veth" {
+			return fmt.Errorf("route to %s on host is via %s, not veth", podIP, hostVeth.Attrs().Name)
+		}
+		logger.Debugf("host veth for %s is %s", podIP, hostVeth.Attrs().Name)
+	}
+	return nil
+}
+
 func (d *PTPDriver) TearDown(args *utils.CniCmdArgs) error {
 	netNs, err := ns.GetNS(args.NetNS)
 	if err != nil {
@@ -56,7 +124,7 @@ func (d *PTPDriver) TearDown(args *utils.CniCmdArgs) error {
 	// There is a netns so try to clean up. Delete can be called multiple times
 	// so don't return an error if the device is already removed.
 	err = ns.WithNetNSPath(args.NetNS, func(_ ns.NetNS) error {
-		if err := ip.DelLinkByName(utils.DefaultContainerVethName); err != nil {
+		if err := ip.DelLinkByName(utils.GetVethName(args.NetConf)); err != nil {
 			if err != ip.ErrLinkNotFound {
 				return err
 			}
@@ -80,7 +148,7 @@ func (d *PTPDriver) TearDown(args *utils.CniCmdArgs) error {
 	return nil
 }
 
-func getLinkIpAddrs(name string) (*net.IPNet, error) {
+func getLinkIpAddrs(name string, ipv6 bool) (*net.IPNet, error) {
 	iface, err := net.InterfaceByName(name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ip vlan master: %s, with error: %w", name, err)
@@ -91,28 +159,87 @@ func getLinkIpAddrs(name string) (*net.IPNet, error) {
 	}
 	for _, addr := range addrs {
 		ipnet, ok := addr.(*net.IPNet)
-		if ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		if !ipv6 && ipnet.IP.To4() != nil {
+			return ipnet, nil
+		}
+		if ipv6 && ipnet.IP.To4() == nil && ipnet.IP.IsGlobalUnicast() {
 			return ipnet, nil
 		}
 	}
 	return nil, fmt.Errorf("no ipaddress found for interface: %s", name)
 }
 
-func setupContainerVeth(logger *logrus.Entry, netns ns.NetNS, ifName string, args *utils.CniCmdArgs, pr *current.Result) (*current.Interface, *current.Interface, error) {
-	nodeGw, err := getLinkIpAddrs(utils.GetIpVlanMaster(args.NetConf))
+// getNodeGateways return address of ipvlan master for each ip family of pod
+func getNodeGateways(master string, result *current.Result) ([]net.IP, error) {
+	var nodeGws []net.IP
+	for _, ipc := range result.IPs {
+		nodeGw, err := getLinkIpAddrs(master, ipc.Address.IP.To4() == nil)
+		if err != nil {
+			return nil, err
+		}
+		nodeGws = append(nodeGws, nodeGw.IP)
+	}
+	return nodeGws, nil
+}
+
+func hasIPv6(result *current.Result) bool {
+	for _, ipc := range result.IPs {
+		if ipc.Address.IP.To4() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setupVethIPv6 enable ipv6 on veth and add the fixed link local address, which is used as
+// next hop of ipv6 routes on the other side of veth pair
+func setupVethIPv6(link netlink.Link, linkLocal string) error {
+	name := link.Attrs().Name
+	_, err := sysctl.Sysctl(fmt.Sprintf(ipam.DisableIPv6SysctlTemplate, name), "0")
+	if err != nil {
+		return fmt.Errorf("failed to enable ipv6 on %s with error: %w", name, err)
+	}
+
+	addr := &netlink.Addr{
+		IPNet: &net.IPNet{
+			IP:   net.ParseIP(linkLocal),
+			Mask: net.CIDRMask(64, 128),
+		},
+		Flags: unix.IFA_F_NODAD,
+	}
+	if err = netlink.AddrAdd(link, addr); err != nil {
+		return fmt.Errorf("failed to add address %s to %s with error: %w", linkLocal, name, err)
+	}
+	return nil
+}
+
+func setupContainerVeth(logger *logrus.Entry, netns ns.NetNS, ifName string, args *utils.CniCmdArgs, pr *current.Result, rb *Rollback) (*current.Interface, *current.Interface, error) {
+	nodeGws, err := getNodeGateways(utils.GetIpVlanMaster(args.NetConf), pr)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get node gateway with error: %w", err)
 	}
-	logger.Infof("########### IP address for interface is :%s", nodeGw.IP.String())
+	logger.Debugf("node gateways %v", nodeGws)
 
+	vethName, err := hostVethName(args)
+	if err != nil {
+		return nil, nil, err
+	}
 	hostInterface := &current.Interface{}
 	containerInterface := &current.Interface{}
 
 	err = netns.Do(func(hostNS ns.NetNS) error {
-		hostVeth, contVeth0, err := ip.SetupVeth(ifName, args.MTU, "", hostNS)
+		hostVeth, contVeth0, err := ip.SetupVethWithName(ifName, vethName, args.MTU, "", hostNS)
 		if err != nil {
 			return err
 		}
+		// host side of veth goes with container side
+		nsPath := netns.Path()
+		rb.Add("veth pair", func() error {
+			return delLink(nsPath, ifName)
+		})
 		hostInterface.Name = hostVeth.Name
 		hostInterface.Mac = hostVeth.HardwareAddr.String()
 		containerInterface.Name = contVeth0.Name
@@ -128,30 +255,28 @@ func setupContainerVeth(logger *logrus.Entry, netns ns.NetNS, ifName string, arg
 			return fmt.Errorf("failed to set link %s up with error: %v", ifName, err)
 		}
 
-		svcCidr := utils.GetServiceCidr(args.K8sArgs)
-		dst, _ := netlink.ParseIPNet(svcCidr)
+		if hasIPv6(pr) {
+			if err = setupVethIPv6(contVeth, utils.DefaultContainerVethIPv6); err != nil {
+				return err
+			}
+		}
 
-		routes := []netlink.Route{
-			{
-				LinkIndex: contVeth.Attrs().Index,
-				Dst: &net.IPNet{
-					IP:   nodeGw.IP,
-					Mask: net.CIDRMask(32, 32),
-				},
-				Scope: netlink.SCOPE_LINK,
-			},
-			{
-				LinkIndex: contVeth.Attrs().Index,
-				Dst:       dst,
-				Gw:        nodeGw.IP,
-			},
+		routes, err := containerVethRoutes(contVeth.Attrs().Index, nodeGws, args)
+		if err != nil {
+			return err
 		}
 
-		for _, route := range routes {
-			logger.Infof("########### Route is :%+v", route)
+		for i := range routes {
+			route := routes[i]
+			logger.Debugf("add container route %+v", route)
 			if err := netlink.RouteAdd(&route); err != nil {
 				return fmt.Errorf("failed to add route %+v: %w", route, err)
 			}
+			rb.Add("container route", func() error {
+				return ns.WithNetNSPath(nsPath, func(_ ns.NetNS) error {
+					return delRoute(&route)
+				})
+			})
 		}
 		return nil
 	})
@@ -162,25 +287,127 @@ func setupContainerVeth(logger *logrus.Entry, netns ns.NetNS, ifName string, arg
 	return hostInterface, containerInterface, nil
 }
 
-func setupHostVeth(logger *logrus.Entry, vethName string, result *current.Result) error {
+func setupHostVeth(logger *logrus.Entry, vethName string, result *current.Result, rb *Rollback) error {
 	hostVeth, err := netlink.LinkByName(vethName)
 	if err != nil {
 		return fmt.Errorf("failed to get link %q: %v", vethName, err)
 	}
 
-	route := netlink.Route{
-		LinkIndex: hostVeth.Attrs().Index,
+	if hasIPv6(result) {
+		if err = setupVethIPv6(hostVeth, utils.DefaultHostVethIPv6); err != nil {
+			return err
+		}
+	}
+
+	for _, ipc := range result.IPs {
+		route := hostVethRoute(hostVeth.Attrs().Index, ipc.Address.IP)
+
+		logger.Debugf("replace host route %+v", route)
+		// route to pod ip may be left on veth of an old sandbox of pod, the new sandbox takes it over
+		if err = netlink.RouteReplace(&route); err != nil {
+			return fmt.Errorf("failed to add route %+v on host with error: %w", route, err)
+		}
+		rb.Add("host route", func() error {
+			return delRoute(&route)
+		})
+	}
+
+	return nil
+}
+
+// containerVethRoutes return routes to node and service cidr via veth in container. ipv6 routes use
+// link local address of host veth as next hop, because neighbor discovery only resolves addresses on the veth
+func containerVethRoutes(linkIndex int, nodeGws []net.IP, args *utils.CniCmdArgs) ([]netlink.Route, error) {
+	var svcDsts []*net.IPNet
+	for _, svcCidr := range strings.Split(utils.GetServiceCidr(args.K8sArgs), ",") {
+		dst, err := netlink.ParseIPNet(strings.TrimSpace(svcCidr))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse service cidr %q: %w", svcCidr, err)
+		}
+		svcDsts = append(svcDsts, dst)
+	}
+
+	var routes []netlink.Route
+	for _, nodeGw := range nodeGws {
+		ipv4 := nodeGw.To4() != nil
+		if ipv4 {
+			routes = append(routes, netlink.Route{
+				LinkIndex: linkIndex,
+				Dst: &net.IPNet{
+					IP:   nodeGw,
+					Mask: net.CIDRMask(32, 32),
+				},
+				Scope: netlink.SCOPE_LINK,
+			})
+		} else {
+			routes = append(routes, netlink.Route{
+				LinkIndex: linkIndex,
+				Dst: &net.IPNet{
+					IP:   nodeGw,
+					Mask: net.CIDRMask(128, 128),
+				},
+				Gw: net.ParseIP(utils.DefaultHostVethIPv6),
+			})
+		}
+
+		for _, dst := range svcDsts {
+			if (dst.IP.To4() != nil) != ipv4 {
+				continue
+			}
+			gw := nodeGw
+			if !ipv4 {
+				gw = net.ParseIP(utils.DefaultHostVethIPv6)
+			}
+			routes = append(routes, netlink.Route{
+				LinkIndex: linkIndex,
+				Dst:       dst,
+				Gw:        gw,
+			})
+		}
+	}
+	return routes, nil
+}
+
+// hostVethRoute return route to pod ip via veth on host
+func hostVethRoute(linkIndex int, podIP net.IP) netlink.Route {
+	if podIP.To4() == nil {
+		return netlink.Route{
+			LinkIndex: linkIndex,
+			Dst: &net.IPNet{
+				IP:   podIP,
+				Mask: net.CIDRMask(128, 128),
+			},
+			Gw: net.ParseIP(utils.DefaultContainerVethIPv6),
+		}
+	}
+	return netlink.Route{
+		LinkIndex: linkIndex,
 		Dst: &net.IPNet{
-			IP:   result.IPs[0].Address.IP,
+			IP:   podIP,
 			Mask: net.CIDRMask(32, 32),
 		},
 		Scope: netlink.SCOPE_LINK,
 	}
+}
 
-	logger.Infof("########### Route is :%+v", route)
-	if err = netlink.RouteAdd(&route); err != nil {
-		return fmt.Errorf("failed to add route %+v on host with error: %w", route, err)
+func routeFamily(route *netlink.Route) int {
+	if route.Dst != nil && route.Dst.IP.To4() == nil {
+		return netlink.FAMILY_V6
 	}
+	return netlink.FAMILY_V4
+}
 
+func checkRoute(route *netlink.Route) error {
+	filter := netlink.RT_FILTER_OIF | netlink.RT_FILTER_DST
+	if route.Gw != nil {
+		filter |= netlink.RT_FILTER_GW
+	}
+	found, err := netlink.RouteListFiltered(routeFamily(route), route, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list route %+v with error: %w", route, err)
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("route %+v not found", route)
+	}
 	return nil
 }
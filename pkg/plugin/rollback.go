@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"errors"
+	"syscall"
+
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// Rollback record undo of each step applied by ADD, undo runs in reverse order when a later step fails
+// so a failed ADD leaves no interface, route or allocation behind
+type Rollback struct {
+	steps []rollbackStep
+}
+
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+func NewRollback() *Rollback {
+	return &Rollback{}
+}
+
+// Add register undo of a step which has been applied
+func (r *Rollback) Add(name string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// Run undo steps in reverse order, a failed undo is logged and the rest still run
+func (r *Rollback) Run(logger *logrus.Entry) {
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			logger.Errorf("failed to undo %s with error: %v", step.name, err)
+			continue
+		}
+		logger.Infof("undo %s", step.name)
+	}
+	r.steps = nil
+}
+
+// delLink delete link in netns at nsPath, a link or netns already gone is not an error
+func delLink(nsPath, name string) error {
+	err := ns.WithNetNSPath(nsPath, func(_ ns.NetNS) error {
+		if err := ip.DelLinkByName(name); err != nil && err != ip.ErrLinkNotFound {
+			return err
+		}
+		return nil
+	})
+	if _, ok := err.(ns.NSPathNotExistErr); ok {
+		return nil
+	}
+	return err
+}
+
+// delRoute delete route in current netns, a route already gone is not an error
+func delRoute(route *netlink.Route) error {
+	if err := netlink.RouteDel(route); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRollback(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	var undone []string
+	step := func(name string, err error) func() error {
+		return func() error {
+			undone = append(undone, name)
+			return err
+		}
+	}
+
+	rb := NewRollback()
+	rb.Add("ip allocation", step("ip allocation", nil))
+	rb.Add("interface", step("interface", errors.New("link busy")))
+	rb.Add("route", step("route", nil))
+	rb.Run(logger)
+
+	// steps are undone in reverse order, a failed undo does not stop the rest
+	want := []string{"route", "interface", "ip allocation"}
+	if !reflect.DeepEqual(undone, want) {
+		t.Errorf("undone = %v, want %v", undone, want)
+	}
+
+	undone = nil
+	rb.Run(logger)
+	if len(undone) != 0 {
+		t.Errorf("steps undone again: %v", undone)
+	}
+
+	rb.Add("veth", step("veth", nil))
+	rb.Run(logger)
+	if !reflect.DeepEqual(undone, []string{"veth"}) {
+		t.Errorf("undone = %v, want only steps added after last run", undone)
+	}
+}
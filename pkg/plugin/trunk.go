@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"fmt"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/rubble/pkg/rpc"
+	"github.com/rubble/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// maxVlanID is max vlan id of subports
+const maxVlanID = 4094
+
+// TrunkDriver create vlan on master, which is parent port of trunk of node, with vlan id and mac of
+// subport of pod, so each pod has security groups and mac of its own port
+type TrunkDriver struct{}
+
+func NewTrunkDriver() *TrunkDriver {
+	return &TrunkDriver{}
+}
+
+// Setup create vlan interface in container with vlan id and mac of subport and addresses allocated,
+// undo of the interface is added to rb
+func (d *TrunkDriver) Setup(logger *logrus.Entry, reply *rpc.AllocateIPReply, args *utils.CniCmdArgs, rb *Rollback) (*current.Result, error) {
+	if !reply.EnableTrunking {
+		return nil, fmt.Errorf("trunking is not enabled, trunk datapath requires daemon with trunk datapath")
+	}
+	vid, err := portVlanID(reply.NetConfs)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := portMAC(reply.NetConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, reply.NetConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := netlink.LinkByName(utils.GetIpVlanMaster(args.NetConf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup master %q: %v", utils.GetIpVlanMaster(args.NetConf), err)
+	}
+	vlan := &netlink.Vlan{
+		LinkAttrs: netlink.LinkAttrs{
+			MTU:          args.MTU,
+			HardwareAddr: mac,
+			ParentIndex:  m.Attrs().Index,
+		},
+		VlanId: vid,
+	}
+	iface, err := addContainerLink(args, netNs, vlan, rb)
+	if err != nil {
+		return nil, err
+	}
+	result.Interfaces = []*current.Interface{iface}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		return ipam.ConfigureIface(args.RawArgs.IfName, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure ip address for vlan interface with error: %w", err)
+	}
+	return result, nil
+}
+
+// Check verify the vlan interface in container has vlan id and mac of subport, the address and routes
+// from net config
+func (d *TrunkDriver) Check(logger *logrus.Entry, netConfs []*rpc.NetConf, args *utils.CniCmdArgs) (*current.Result, error) {
+	vid, err := portVlanID(netConfs)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := portMAC(netConfs)
+	if err != nil {
+		return nil, err
+	}
+	netNs, err := ns.GetNS(args.NetNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", args.NetNS, err)
+	}
+	defer netNs.Close()
+
+	result, err := newPodResult(logger, netConfs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = netNs.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(args.RawArgs.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to find vlan interface %q: %w", args.RawArgs.IfName, err)
+		}
+		vlan, ok := link.(*netlink.Vlan)
+		if !ok {
+			return fmt.Errorf("interface %q is %s, not vlan", args.RawArgs.IfName, link.Type())
+		}
+		if vlan.VlanId != vid {
+			return fmt.Errorf("vlan id of interface %q is %d, not %d of subport", args.RawArgs.IfName, vlan.VlanId, vid)
+		}
+		if link.Attrs().HardwareAddr.String() != mac.String() {
+			return fmt.Errorf("mac of interface %q is %s, not %s of port", args.RawArgs.IfName, link.Attrs().HardwareAddr, mac)
+		}
+		result.Interfaces = []*current.Interface{
+			{
+				Name:    args.RawArgs.IfName,
+				Mac:     mac.String(),
+				Sandbox: netNs.Path(),
+			},
+		}
+
+		if err := ip.ValidateExpectedInterfaceIPs(args.RawArgs.IfName, result.IPs); err != nil {
+			return err
+		}
+		return ip.ValidateExpectedRoute(result.Routes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *TrunkDriver) TearDown(args *utils.CniCmdArgs) error {
+	return delLink(args.NetNS, args.RawArgs.IfName)
+}
+
+// portVlanID return vlan id of subport in net config
+func portVlanID(netConfs []*rpc.NetConf) (int, error) {
+	if len(netConfs) == 0 || netConfs[0].ENIInfo == nil || !netConfs[0].ENIInfo.Trunk {
+		return 0, fmt.Errorf("port in net config is not a subport of trunk")
+	}
+	vid := int(netConfs[0].ENIInfo.Vid)
+	if vid < 1 || vid > maxVlanID {
+		return 0, fmt.Errorf("invalid vlan id %d of subport", vid)
+	}
+	return vid, nil
+}
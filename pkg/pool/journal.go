@@ -0,0 +1,175 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rubble/pkg/storage"
+	types "github.com/rubble/pkg/utils"
+)
+
+// JournalState is state of resource recorded in journal
+type JournalState string
+
+const (
+	// JournalCreating is recorded by factory before resource is created, key of entry is name of resource
+	JournalCreating  JournalState = "creating"
+	JournalIdle      JournalState = "idle"
+	JournalInUse     JournalState = "inuse"
+	JournalDisposing JournalState = "disposing"
+)
+
+// JournalEntry is the last transition of a resource in pool
+type JournalEntry struct {
+	Pool string `json:"pool"`
+	// Key is id of resource, or name of resource being created
+	Key   string       `json:"key"`
+	State JournalState `json:"state"`
+	// ReservedUntil is reverse time of idle resource released by pod
+	ReservedUntil time.Time `json:"reservedUntil,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Journal is write-ahead log of pool transitions. it is replayed when pool is created after daemon restarted,
+// so resources being created or disposed when daemon crashed are neither duplicated nor orphaned.
+// a nil Journal records nothing
+type Journal struct {
+	store storage.Storage
+}
+
+// Recoverer is implemented by factories recording names of resources being created in journal,
+// resources created before daemon crashed are found by names when pool is replayed
+type Recoverer interface {
+	// Recover return existing resources of names, they are ready to be added to pool
+	Recover(names []string) ([]types.NetworkResource, error)
+}
+
+func NewJournal(store storage.Storage) *Journal {
+	return &Journal{store: store}
+}
+
+// DecodeJournalEntry is deserializer of journal storage
+func DecodeJournalEntry(data []byte) (interface{}, error) {
+	entry := JournalEntry{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("error unmarshal pool journal entry: %w", err)
+	}
+	return entry, nil
+}
+
+func journalKey(pool, key string) string {
+	return pool + "/" + key
+}
+
+// Record write state of resource in pool, errors are logged since journal only helps recovery
+func (j *Journal) Record(pool, key string, state JournalState, reservedUntil time.Time) {
+	if j == nil {
+		return
+	}
+	entry := JournalEntry{
+		Pool:          pool,
+		Key:           key,
+		State:         state,
+		ReservedUntil: reservedUntil,
+		Time:          time.Now(),
+	}
+	if err := j.store.Put(journalKey(pool, key), entry); err != nil {
+		logger.Warnf("failed to record %s of %s in journal of pool %s: %v", state, key, pool, err)
+	}
+}
+
+// Forget remove resource from journal after it left pool
+func (j *Journal) Forget(pool, key string) {
+	if j == nil {
+		return
+	}
+	if err := j.store.Delete(journalKey(pool, key)); err != nil {
+		logger.Warnf("failed to remove %s from journal of pool %s: %v", key, pool, err)
+	}
+}
+
+// Entries return entries of pool
+func (j *Journal) Entries(pool string) []JournalEntry {
+	if j == nil {
+		return nil
+	}
+	objs, err := j.store.List()
+	if err != nil {
+		logger.Warnf("failed to list journal of pool %s: %v", pool, err)
+		return nil
+	}
+	var ret []JournalEntry
+	for _, obj := range objs {
+		if entry := obj.(JournalEntry); entry.Pool == pool {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
+
+// replay finish operations interrupted by crash with entries read before Initializer restored resources:
+// resources being created are added to idle if they exist, resources being disposed are disposed again
+// unless they are used by pods, and reverse time of idle resources is restored
+func (p *SimpleObjectPool) replay(entries []JournalEntry) {
+	var creating []string
+	var disposing []types.NetworkResource
+	p.lock.Lock()
+	for _, e := range entries {
+		if e.State == JournalCreating {
+			creating = append(creating, e.Key)
+			continue
+		}
+		if _, ok := p.inuse[e.Key]; ok {
+			// resources used by pods are restored by Initializer
+			continue
+		}
+		item := p.idle.Find(e.Key)
+		if item == nil {
+			// resource is gone
+			p.journal.Forget(p.name, e.Key)
+			continue
+		}
+		switch e.State {
+		case JournalDisposing:
+			disposing = append(disposing, p.idle.Rob(e.Key).res)
+		case JournalIdle:
+			if e.ReservedUntil.After(item.reverse) {
+				p.idle.Rob(e.Key)
+				p.idle.Push(&poolItem{res: item.res, reverse: e.ReservedUntil, idleSince: item.idleSince})
+				p.journal.Record(p.name, e.Key, JournalIdle, e.ReservedUntil)
+			}
+		}
+	}
+	p.lock.Unlock()
+
+	if len(disposing) > 0 {
+		logger.Infof("finish disposing %d resources of pool %s in journal", len(disposing), p.name)
+		p.disposeAll(disposing)
+	}
+
+	if len(creating) == 0 {
+		return
+	}
+	recoverer, ok := p.factory.(Recoverer)
+	if !ok {
+		logger.Warnf("factory of pool %s can not recover %d resources being created", p.name, len(creating))
+		return
+	}
+	created, err := recoverer.Recover(creating)
+	if err != nil {
+		// entries are kept and replayed on next start
+		logger.Errorf("failed to recover resources being created in pool %s: %v", p.name, err)
+		return
+	}
+	for _, res := range created {
+		if p.Stat(res.GetResourceId()) == nil {
+			continue
+		}
+		logger.Infof("add resource %s created before restart to pool %s", res.GetResourceId(), p.name)
+		p.AddIdle(res)
+	}
+	for _, name := range creating {
+		p.journal.Forget(p.name, name)
+	}
+}
@@ -0,0 +1,174 @@
+package pool
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rubble/pkg/storage"
+	types "github.com/rubble/pkg/utils"
+)
+
+// recoverFactory recover resources of names in existing, ids are the values
+type recoverFactory struct {
+	fakeFactory
+	existing map[string]string
+	err      error
+}
+
+func (f *recoverFactory) Recover(names []string) ([]types.NetworkResource, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var ret []types.NetworkResource
+	for _, name := range names {
+		if id, ok := f.existing[name]; ok {
+			ret = append(ret, &fakeResource{id: id})
+		}
+	}
+	return ret, nil
+}
+
+func TestReplay(t *testing.T) {
+	reserved := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name        string
+		idle        []string
+		inuse       []string
+		entries     []JournalEntry
+		existing    map[string]string
+		recoverErr  error
+		noRecoverer bool
+
+		wantIdle     []string
+		wantInUse    []string
+		wantDisposed int
+		// wantKept are keys of entries left in journal with their states
+		wantKept  []string
+		wantUntil map[string]time.Time
+	}{
+		{
+			name:     "created before crash",
+			entries:  []JournalEntry{{Key: "port-a", State: JournalCreating}},
+			existing: map[string]string{"port-a": "res-a"},
+			wantIdle: []string{"res-a"},
+		},
+		{
+			name:    "not created before crash",
+			entries: []JournalEntry{{Key: "port-a", State: JournalCreating}},
+		},
+		{
+			name:       "recover fails",
+			entries:    []JournalEntry{{Key: "port-a", State: JournalCreating}},
+			recoverErr: errors.New("neutron down"),
+			wantKept:   []string{"port-a"},
+		},
+		{
+			name:        "factory can not recover",
+			entries:     []JournalEntry{{Key: "port-a", State: JournalCreating}},
+			noRecoverer: true,
+			wantKept:    []string{"port-a"},
+		},
+		{
+			name:         "disposing idle",
+			idle:         []string{"res-a", "res-b"},
+			entries:      []JournalEntry{{Key: "res-a", State: JournalDisposing}},
+			wantIdle:     []string{"res-b"},
+			wantDisposed: 1,
+		},
+		{
+			name:      "disposing but used by pod",
+			inuse:     []string{"res-a"},
+			entries:   []JournalEntry{{Key: "res-a", State: JournalDisposing}},
+			wantInUse: []string{"res-a"},
+		},
+		{
+			name:      "reserved idle",
+			idle:      []string{"res-a"},
+			entries:   []JournalEntry{{Key: "res-a", State: JournalIdle, ReservedUntil: reserved}},
+			wantIdle:  []string{"res-a"},
+			wantKept:  []string{"res-a"},
+			wantUntil: map[string]time.Time{"res-a": reserved},
+		},
+		{
+			name:    "gone",
+			entries: []JournalEntry{{Key: "res-a", State: JournalIdle}, {Key: "res-b", State: JournalDisposing}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journal := NewJournal(storage.NewMemoryStorage())
+			for _, e := range tt.entries {
+				journal.Record("replay", e.Key, e.State, e.ReservedUntil)
+			}
+			recoverer := &recoverFactory{existing: tt.existing, err: tt.recoverErr}
+			var factory ObjectFactory = recoverer
+			if tt.noRecoverer {
+				factory = &recoverer.fakeFactory
+			}
+
+			p, _ := newTestPool(t, PoolConfig{
+				Name:        "replay",
+				Factory:     factory,
+				MaxIdle:     5,
+				MaxPoolSize: 5,
+				Journal:     journal,
+				Initializer: func(holder ResourceHolder) error {
+					for _, id := range tt.idle {
+						holder.AddIdle(&fakeResource{id: id})
+					}
+					for _, id := range tt.inuse {
+						holder.AddInuse(&fakeResource{id: id})
+					}
+					return nil
+				},
+			})
+
+			var idle, inuse []string
+			until := make(map[string]time.Time)
+			for _, item := range p.GetIdle() {
+				idle = append(idle, item.res.GetResourceId())
+				if !item.reverse.IsZero() {
+					until[item.res.GetResourceId()] = item.reverse
+				}
+			}
+			for id := range p.GetInUse() {
+				inuse = append(inuse, id)
+			}
+			sort.Strings(idle)
+			sort.Strings(inuse)
+			if !equalIDs(idle, tt.wantIdle) || !equalIDs(inuse, tt.wantInUse) {
+				t.Errorf("idle %v inuse %v, want idle %v inuse %v", idle, inuse, tt.wantIdle, tt.wantInUse)
+			}
+			if recoverer.disposed != tt.wantDisposed {
+				t.Errorf("disposed = %d, want %d", recoverer.disposed, tt.wantDisposed)
+			}
+			if len(tt.wantUntil) > 0 && !reflect.DeepEqual(until, tt.wantUntil) {
+				t.Errorf("reserved until %v, want %v", until, tt.wantUntil)
+			}
+
+			states := make(map[string]JournalState)
+			for _, e := range journal.Entries("replay") {
+				states[e.Key] = e.State
+			}
+			var kept []string
+			for _, e := range tt.entries {
+				if states[e.Key] == e.State {
+					kept = append(kept, e.Key)
+				}
+			}
+			if !equalIDs(kept, tt.wantKept) {
+				t.Errorf("entries kept %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,326 @@
+package pool
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	types "github.com/rubble/pkg/utils"
+)
+
+const (
+	// PolicyFloor keep pool size at least MinPoolSize
+	PolicyFloor = "floor"
+	// PolicyHysteresis tolerate idle resources slightly over maxIdle and recently created ones
+	PolicyHysteresis = "hysteresis"
+	// PolicyTTL reclaim resources idle longer than ttl down to idle target
+	PolicyTTL = "ttl"
+	// PolicySchedule scale idle resources down in off-hours windows
+	PolicySchedule = "schedule"
+
+	DefaultIdleTTL          = 30 * time.Minute
+	DefaultHysteresisMargin = 2
+	DefaultCooldown         = 5 * time.Minute
+)
+
+// IdleResource is an idle resource seen by policies
+type IdleResource struct {
+	ID string
+	// IdleSince is the time resource was put to idle
+	IdleSince time.Time
+	// ReservedUntil is reverse time of resource released by pod, it is not reclaimed before
+	ReservedUntil time.Time
+}
+
+func (r IdleResource) reserved(now time.Time) bool {
+	return r.ReservedUntil.After(now)
+}
+
+// PoolState is snapshot of pool when policies are applied
+type PoolState struct {
+	Now time.Time
+	// Idle are idle resources in reclaim order, resources reserved the shortest and idle the longest first
+	Idle        []IdleResource
+	InUse       int
+	Capacity    int
+	MinIdle     int
+	MaxIdle     int
+	MinPoolSize int
+	Pending     int
+	// IdleTarget is idle size expected by minIdle, pending pods and recent allocations
+	IdleTarget int
+	// LastCreated is the last time pool created resources
+	LastCreated time.Time
+}
+
+// Policy decide how many idle resources pool refills to and which idle resources are reclaimed.
+// policies of pool are applied in order, each one gets the result of the previous one
+type Policy interface {
+	Name() string
+	// Target adjust idle size pool refills to
+	Target(state *PoolState, target int) int
+	// Reclaim adjust idle resources to dispose, candidates are in reclaim order
+	Reclaim(state *PoolState, candidates []IdleResource) []IdleResource
+}
+
+// NewPolicies create policies of config in order. floor is always applied last so that no policy
+// reclaims resources below MinPoolSize or lowers target under it
+func NewPolicies(cfg types.PoolPolicyConfig) ([]Policy, error) {
+	var ret []Policy
+	for _, name := range cfg.Policies {
+		switch name {
+		case PolicyFloor:
+		case PolicyHysteresis:
+			policy := hysteresisPolicy{margin: cfg.HysteresisMargin, cooldown: time.Duration(cfg.Cooldown) * time.Second}
+			if policy.margin <= 0 {
+				policy.margin = DefaultHysteresisMargin
+			}
+			if policy.cooldown <= 0 {
+				policy.cooldown = DefaultCooldown
+			}
+			ret = append(ret, policy)
+		case PolicyTTL:
+			policy := ttlPolicy{ttl: time.Duration(cfg.IdleTTL) * time.Second}
+			if policy.ttl <= 0 {
+				policy.ttl = DefaultIdleTTL
+			}
+			ret = append(ret, policy)
+		case PolicySchedule:
+			policy, err := newSchedulePolicy(cfg.OffHours)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, policy)
+		default:
+			return nil, fmt.Errorf("unknown pool policy: %q", name)
+		}
+	}
+	return append(ret, floorPolicy{}), nil
+}
+
+// PolicyNames return names of policies
+func PolicyNames(policies []Policy) []string {
+	ret := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		ret = append(ret, policy.Name())
+	}
+	return ret
+}
+
+// sortIdle sort idle resources in reclaim order
+func sortIdle(idle []IdleResource) {
+	sort.SliceStable(idle, func(i, j int) bool {
+		if !idle[i].ReservedUntil.Equal(idle[j].ReservedUntil) {
+			return idle[i].ReservedUntil.Before(idle[j].ReservedUntil)
+		}
+		return idle[i].IdleSince.Before(idle[j].IdleSince)
+	})
+}
+
+// excessCandidates return unreserved idle resources over keep in reclaim order, candidates are kept
+func excessCandidates(state *PoolState, candidates []IdleResource, keep int) []IdleResource {
+	chosen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		chosen[c.ID] = true
+	}
+	excess := len(state.Idle) - len(candidates) - keep
+	for _, r := range state.Idle {
+		if excess <= 0 || r.reserved(state.Now) {
+			break
+		}
+		if chosen[r.ID] {
+			continue
+		}
+		candidates = append(candidates, r)
+		excess--
+	}
+	return candidates
+}
+
+// defaultCandidates choose idle resources over maxIdle or capacity, resources kept for pending pods
+// and reserved for pods are not reclaimed
+func defaultCandidates(state *PoolState) []IdleResource {
+	keep := state.MaxIdle
+	if state.Pending > keep {
+		keep = state.Pending
+	}
+	if over := len(state.Idle) + state.InUse - state.Capacity; over > 0 && len(state.Idle)-over < keep {
+		keep = len(state.Idle) - over
+	}
+	return excessCandidates(state, nil, keep)
+}
+
+// floorPolicy keep pool size at least MinPoolSize, pool refills idle resources up to it
+type floorPolicy struct{}
+
+func (floorPolicy) Name() string {
+	return PolicyFloor
+}
+
+func (floorPolicy) Target(state *PoolState, target int) int {
+	if floor := state.MinPoolSize - state.InUse; floor > target {
+		return floor
+	}
+	return target
+}
+
+func (floorPolicy) Reclaim(state *PoolState, candidates []IdleResource) []IdleResource {
+	allowed := len(state.Idle) + state.InUse - state.MinPoolSize
+	if allowed < 0 {
+		allowed = 0
+	}
+	if len(candidates) > allowed {
+		return candidates[:allowed]
+	}
+	return candidates
+}
+
+// hysteresisPolicy keep idle resources over maxIdle until they exceed it by margin, and within cooldown
+// after pool created resources, so load around maxIdle does not create and dispose every check. resources
+// over capacity are reclaimed regardless, so shrinking capacity takes effect
+type hysteresisPolicy struct {
+	margin   int
+	cooldown time.Duration
+}
+
+func (hysteresisPolicy) Name() string {
+	return PolicyHysteresis
+}
+
+func (hysteresisPolicy) Target(state *PoolState, target int) int {
+	return target
+}
+
+func (h hysteresisPolicy) Reclaim(state *PoolState, candidates []IdleResource) []IdleResource {
+	if state.Now.Sub(state.LastCreated) >= h.cooldown && len(state.Idle) > state.MaxIdle+h.margin {
+		return candidates
+	}
+	over := len(state.Idle) + state.InUse - state.Capacity
+	if over <= 0 {
+		return nil
+	}
+	if over < len(candidates) {
+		return candidates[:over]
+	}
+	return candidates
+}
+
+// ttlPolicy reclaim resources idle longer than ttl, idle resources are kept up to idle target
+type ttlPolicy struct {
+	ttl time.Duration
+}
+
+func (ttlPolicy) Name() string {
+	return PolicyTTL
+}
+
+func (ttlPolicy) Target(state *PoolState, target int) int {
+	return target
+}
+
+func (t ttlPolicy) Reclaim(state *PoolState, candidates []IdleResource) []IdleResource {
+	chosen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		chosen[c.ID] = true
+	}
+	left := len(state.Idle) - len(candidates)
+	for _, r := range state.Idle {
+		if left <= state.IdleTarget {
+			break
+		}
+		if chosen[r.ID] || r.reserved(state.Now) || state.Now.Sub(r.IdleSince) < t.ttl {
+			continue
+		}
+		candidates = append(candidates, r)
+		left--
+	}
+	return candidates
+}
+
+// offHours is a daily window in minutes of day, it wraps midnight if end is before start
+type offHours struct {
+	start, end int
+	maxIdle    int
+}
+
+func (w offHours) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// schedulePolicy limit idle resources to maxIdle of off-hours window, pending pods are still served
+type schedulePolicy struct {
+	windows []offHours
+}
+
+func newSchedulePolicy(cfg []types.OffHoursWindow) (*schedulePolicy, error) {
+	if len(cfg) == 0 {
+		return nil, fmt.Errorf("schedule pool policy requires off_hours")
+	}
+	policy := &schedulePolicy{}
+	for _, w := range cfg {
+		start, err := minuteOfDay(w.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := minuteOfDay(w.End)
+		if err != nil {
+			return nil, err
+		}
+		if w.MaxIdle < 0 {
+			return nil, fmt.Errorf("invalid max idle %d of off hours %s-%s", w.MaxIdle, w.Start, w.End)
+		}
+		policy.windows = append(policy.windows, offHours{start: start, end: end, maxIdle: w.MaxIdle})
+	}
+	return policy, nil
+}
+
+func minuteOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q in off hours, expect HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// maxIdle return max idle of window now is in, false if not in off hours
+func (s *schedulePolicy) maxIdle(now time.Time) (int, bool) {
+	for _, w := range s.windows {
+		if w.contains(now) {
+			return w.maxIdle, true
+		}
+	}
+	return 0, false
+}
+
+func (s *schedulePolicy) Name() string {
+	return PolicySchedule
+}
+
+func (s *schedulePolicy) Target(state *PoolState, target int) int {
+	maxIdle, ok := s.maxIdle(state.Now)
+	if !ok || target <= maxIdle {
+		return target
+	}
+	if state.Pending > maxIdle {
+		maxIdle = state.Pending
+	}
+	if target > maxIdle {
+		return maxIdle
+	}
+	return target
+}
+
+func (s *schedulePolicy) Reclaim(state *PoolState, candidates []IdleResource) []IdleResource {
+	maxIdle, ok := s.maxIdle(state.Now)
+	if !ok {
+		return candidates
+	}
+	if state.Pending > maxIdle {
+		maxIdle = state.Pending
+	}
+	return excessCandidates(state, candidates, maxIdle)
+}
@@ -0,0 +1,238 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	types "github.com/rubble/pkg/utils"
+)
+
+func TestNewPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     types.PoolPolicyConfig
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", want: []string{PolicyFloor}},
+		{name: "floor is moved last", cfg: types.PoolPolicyConfig{Policies: []string{PolicyFloor, PolicyTTL}}, want: []string{PolicyTTL, PolicyFloor}},
+		{name: "floor is added", cfg: types.PoolPolicyConfig{Policies: []string{PolicyHysteresis, PolicyTTL}}, want: []string{PolicyHysteresis, PolicyTTL, PolicyFloor}},
+		{name: "unknown", cfg: types.PoolPolicyConfig{Policies: []string{"lru"}}, wantErr: true},
+		{name: "schedule without off hours", cfg: types.PoolPolicyConfig{Policies: []string{PolicySchedule}}, wantErr: true},
+		{
+			name:    "invalid off hours",
+			cfg:     types.PoolPolicyConfig{Policies: []string{PolicySchedule}, OffHours: []types.OffHoursWindow{{Start: "22", End: "06:00"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := NewPolicies(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPolicies() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(PolicyNames(policies), tt.want) {
+				t.Errorf("NewPolicies() = %v, want %v", PolicyNames(policies), tt.want)
+			}
+		})
+	}
+}
+
+// idleFor return n idle resources, the first idle the longest
+func idleFor(now time.Time, n int, idle time.Duration) []IdleResource {
+	var ret []IdleResource
+	for i := 0; i < n; i++ {
+		ret = append(ret, IdleResource{ID: fmt.Sprintf("res-%d", i), IdleSince: now.Add(-idle + time.Duration(i)*time.Second)})
+	}
+	return ret
+}
+
+func TestPolicyReclaim(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	night := time.Date(2026, 1, 1, 23, 0, 0, 0, time.Local)
+	cfg := types.PoolPolicyConfig{
+		IdleTTL:          600,
+		HysteresisMargin: 2,
+		Cooldown:         300,
+		OffHours:         []types.OffHoursWindow{{Start: "22:00", End: "06:00", MaxIdle: 1}},
+	}
+	tests := []struct {
+		name     string
+		policies []string
+		state    PoolState
+		want     int
+	}{
+		{
+			name:  "default over max idle",
+			state: PoolState{Now: now, Idle: idleFor(now, 6, time.Minute), MaxIdle: 4, Capacity: 10},
+			want:  2,
+		},
+		{
+			name:  "floor keeps min pool size",
+			state: PoolState{Now: now, Idle: idleFor(now, 6, time.Minute), InUse: 1, MaxIdle: 4, MinPoolSize: 6, Capacity: 10},
+			want:  1,
+		},
+		{
+			name:     "hysteresis within margin",
+			policies: []string{PolicyHysteresis},
+			state:    PoolState{Now: now, Idle: idleFor(now, 6, time.Minute), MaxIdle: 4, Capacity: 10},
+			want:     0,
+		},
+		{
+			name:     "hysteresis over margin",
+			policies: []string{PolicyHysteresis},
+			state:    PoolState{Now: now, Idle: idleFor(now, 7, time.Minute), MaxIdle: 4, Capacity: 10},
+			want:     3,
+		},
+		{
+			name:     "hysteresis in cooldown",
+			policies: []string{PolicyHysteresis},
+			state:    PoolState{Now: now, Idle: idleFor(now, 7, time.Minute), MaxIdle: 4, Capacity: 10, LastCreated: now.Add(-time.Minute)},
+			want:     0,
+		},
+		{
+			name:     "hysteresis within margin over capacity",
+			policies: []string{PolicyHysteresis},
+			state:    PoolState{Now: now, Idle: idleFor(now, 6, time.Minute), InUse: 2, MaxIdle: 4, Capacity: 5},
+			want:     3,
+		},
+		{
+			name:     "hysteresis in cooldown over capacity",
+			policies: []string{PolicyHysteresis},
+			state:    PoolState{Now: now, Idle: idleFor(now, 7, time.Minute), MaxIdle: 4, Capacity: 5, LastCreated: now.Add(-time.Minute)},
+			want:     2,
+		},
+		{
+			name:     "ttl down to idle target",
+			policies: []string{PolicyTTL},
+			state:    PoolState{Now: now, Idle: idleFor(now, 4, time.Hour), MaxIdle: 4, Capacity: 10, IdleTarget: 1},
+			want:     3,
+		},
+		{
+			name:     "ttl not expired",
+			policies: []string{PolicyTTL},
+			state:    PoolState{Now: now, Idle: idleFor(now, 4, time.Minute), MaxIdle: 4, Capacity: 10, IdleTarget: 1},
+			want:     0,
+		},
+		{
+			// floor is applied after ttl even if it is configured first
+			name:     "ttl does not go below floor",
+			policies: []string{PolicyFloor, PolicyTTL},
+			state:    PoolState{Now: now, Idle: idleFor(now, 4, time.Hour), InUse: 1, MaxIdle: 4, MinPoolSize: 4, Capacity: 10},
+			want:     1,
+		},
+		{
+			name:     "schedule in off hours",
+			policies: []string{PolicySchedule},
+			state:    PoolState{Now: night, Idle: idleFor(night, 4, time.Minute), MaxIdle: 4, Capacity: 10},
+			want:     3,
+		},
+		{
+			name:     "schedule keeps pending",
+			policies: []string{PolicySchedule},
+			state:    PoolState{Now: night, Idle: idleFor(night, 4, time.Minute), MaxIdle: 4, Capacity: 10, Pending: 3},
+			want:     1,
+		},
+		{
+			name:     "reserved resources are kept",
+			policies: []string{PolicySchedule},
+			state: PoolState{Now: night, MaxIdle: 4, Capacity: 10, Idle: []IdleResource{
+				{ID: "a", IdleSince: night, ReservedUntil: night.Add(time.Hour)},
+				{ID: "b", IdleSince: night, ReservedUntil: night.Add(time.Hour)},
+			}},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Policies = tt.policies
+			policies, err := NewPolicies(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := tt.state
+			candidates := defaultCandidates(&state)
+			for _, policy := range policies {
+				candidates = policy.Reclaim(&state, candidates)
+			}
+			if len(candidates) != tt.want {
+				t.Errorf("reclaim %d resources, want %d", len(candidates), tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyTarget(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	night := time.Date(2026, 1, 1, 23, 0, 0, 0, time.Local)
+	cfg := types.PoolPolicyConfig{OffHours: []types.OffHoursWindow{{Start: "22:00", End: "06:00", MaxIdle: 1}}}
+	tests := []struct {
+		name     string
+		policies []string
+		state    PoolState
+		target   int
+		want     int
+	}{
+		{name: "idle target", state: PoolState{Now: now}, target: 2, want: 2},
+		{name: "floor raises target", state: PoolState{Now: now, MinPoolSize: 5, InUse: 1}, target: 2, want: 4},
+		{name: "schedule lowers target", policies: []string{PolicySchedule}, state: PoolState{Now: night}, target: 3, want: 1},
+		{name: "schedule keeps pending", policies: []string{PolicySchedule}, state: PoolState{Now: night, Pending: 2}, target: 3, want: 2},
+		{
+			// floor is applied after schedule even if it is configured first
+			name:     "schedule does not go below floor",
+			policies: []string{PolicyFloor, PolicySchedule},
+			state:    PoolState{Now: night, MinPoolSize: 3},
+			target:   3,
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Policies = tt.policies
+			policies, err := NewPolicies(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := tt.target
+			for _, policy := range policies {
+				target = policy.Target(&tt.state, target)
+			}
+			if target != tt.want {
+				t.Errorf("target = %d, want %d", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastCreated(t *testing.T) {
+	tests := []struct {
+		name    string
+		minIdle int
+		acquire bool
+	}{
+		{name: "preload", minIdle: 1},
+		{name: "acquire creates", acquire: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			p, _ := newTestPool(t, PoolConfig{Name: "last-created", MinIdle: tt.minIdle, MaxIdle: 2, MaxPoolSize: 2})
+			if tt.acquire {
+				if _, err := p.Acquire(context.Background(), ""); err != nil {
+					t.Fatalf("acquire: %v", err)
+				}
+			}
+			p.lock.Lock()
+			lastCreated := p.lastCreated
+			p.lock.Unlock()
+			if lastCreated.Before(start) {
+				t.Errorf("last created = %s, want after %s", lastCreated, start)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/rubble/pkg/log"
+	"github.com/rubble/pkg/metrics"
 	types "github.com/rubble/pkg/utils"
 	"strings"
 	"sync"
@@ -24,8 +25,13 @@ var logger = log.DefaultLogger.WithField("component:", "rubble pool")
 const (
 	CheckIdleInterval  = 1 * time.Minute
 	defaultPoolBackoff = 1 * time.Minute
-	DefaultMaxIdle     = 20
-	DefaultCapacity    = 50
+	minPoolBackoff     = 1 * time.Second
+	// allocationWindow is period of recent allocations, pool keeps as many idle resources as
+	// allocated in last window for the next one
+	allocationWindow = 1 * time.Minute
+	DefaultMaxIdle   = 20
+	DefaultCapacity  = 50
+	DefaultPoolName  = "default"
 )
 
 type ObjectPool interface {
@@ -37,6 +43,29 @@ type ObjectPool interface {
 	GetInUse() map[string]types.NetworkResource
 	GetIdle() []*poolItem
 	AddIdle(res types.NetworkResource)
+	// Adopt add resource not created by pool into inuse, it takes a token like resources created by pool
+	Adopt(ctx context.Context, res types.NetworkResource) error
+	Remove(resId string) (types.NetworkResource, error)
+	// Warm set count of pods expected to acquire soon, pool creates idle resources for them ahead
+	Warm(pending int)
+	// Close stop refill and dispose, requests of running refill or dispose are canceled and waited until ctx is done
+	Close(ctx context.Context) error
+	// SetCapacity change capacity at runtime, it is limited by capacity the pool is created with
+	SetCapacity(capacity int)
+	// Usage return sizes of pool
+	Usage() Usage
+	// Drain dispose idle resources, resources reserved for pods are kept unless force
+	Drain(force bool) int
+}
+
+// Usage is sizes of pool, e.g. reported to cluster quota
+type Usage struct {
+	InUse int
+	Idle  int
+	// IdleTarget is idle size expected by pool
+	IdleTarget  int
+	Capacity    int
+	MaxCapacity int
 }
 
 type ResourceHolder interface {
@@ -45,12 +74,30 @@ type ResourceHolder interface {
 }
 
 type ObjectFactory interface {
-	//Create (TODO) gophercloud does not support creating multiple ports one API call
 	Create(ip string) (types.NetworkResource, error)
 	Dispose(types.NetworkResource) error
 }
 
+// ContextFactory is implemented by factories able to abort create and dispose when ctx is done,
+// pool uses it so that resources are not created for requests nobody is waiting on
+type ContextFactory interface {
+	ObjectFactory
+	CreateContext(ctx context.Context, ip string) (types.NetworkResource, error)
+	DisposeContext(ctx context.Context, res types.NetworkResource) error
+}
+
+// BatchFactory is implemented by factories able to create and dispose resources in batch,
+// pool uses it to preload, refill and shrink
+type BatchFactory interface {
+	ObjectFactory
+	// CreateBatch create count resources, resources created are returned with error if some of them failed
+	CreateBatch(ctx context.Context, count int) ([]types.NetworkResource, error)
+	// DisposeBatch dispose resources, resources failed to dispose are returned with error
+	DisposeBatch(ctx context.Context, res []types.NetworkResource) ([]types.NetworkResource, error)
+}
+
 type SimpleObjectPool struct {
+	name       string
 	inuse      map[string]types.NetworkResource
 	idle       *PriorityQueue
 	lock       sync.Mutex
@@ -59,12 +106,33 @@ type SimpleObjectPool struct {
 	minIdle    int
 	capacity   int
 	maxBackoff time.Duration
-	notifyCh   chan interface{}
+	// backoff delay refill after factory failed, only used by ticker goroutine
+	backoff     time.Duration
+	refillAfter time.Time
+	// pending is count of pods expected to acquire soon, allocated are times of recent acquires
+	pending   int
+	allocated []time.Time
+	// policies decide idle target and idle resources to reclaim, lastCreated is used by them
+	policies    []Policy
+	minPoolSize int
+	lastCreated time.Time
+	notifyCh    chan interface{}
+	journal     *Journal
+	// ctx is canceled when pool is closed and aborts requests of factory, done is closed after refill and
+	// dispose stopped
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 	// concurrency to create resource. tokenCh = capacity - (idle + inuse + dispose)
 	tokenCh chan struct{}
+	// maxCapacity is capacity pool created with, owed is tokens to drop after capacity shrunk below size
+	maxCapacity int
+	owed        int
 }
 
 type PoolConfig struct {
+	// Name of pool in metrics, default is default
+	Name        string
 	Factory     ObjectFactory
 	Initializer Initializer
 	MinIdle     int
@@ -72,11 +140,16 @@ type PoolConfig struct {
 	MaxPoolSize int
 	MinPoolSize int
 	Capacity    int
+	// Journal record transitions of resources, it is replayed when pool is created. optional
+	Journal *Journal
+	// Policies decide how many idle resources are kept, floor is used if empty
+	Policies []Policy
 }
 
 type poolItem struct {
-	res     types.NetworkResource
-	reverse time.Time
+	res       types.NetworkResource
+	reverse   time.Time
+	idleSince time.Time
 }
 
 func (i *poolItem) lessThan(other *poolItem) bool {
@@ -87,6 +160,16 @@ func (i *poolItem) GetResource() types.NetworkResource {
 	return i.res
 }
 
+// ReservedUntil return time resource is reserved for pod released it
+func (i *poolItem) ReservedUntil() time.Time {
+	return i.reverse
+}
+
+// IdleSince return time resource was put to idle
+func (i *poolItem) IdleSince() time.Time {
+	return i.idleSince
+}
+
 type Initializer func(holder ResourceHolder) error
 
 func NewSimpleObjectPool(cfg PoolConfig) (ObjectPool, error) {
@@ -94,6 +177,10 @@ func NewSimpleObjectPool(cfg PoolConfig) (ObjectPool, error) {
 		return nil, ErrInvalidArguments
 	}
 
+	if cfg.Capacity == 0 {
+		cfg.Capacity = cfg.MaxPoolSize
+	}
+
 	if cfg.MaxIdle > cfg.Capacity {
 		return nil, ErrInvalidArguments
 	}
@@ -104,45 +191,73 @@ func NewSimpleObjectPool(cfg PoolConfig) (ObjectPool, error) {
 	if cfg.Capacity == 0 {
 		cfg.Capacity = DefaultCapacity
 	}
+	if cfg.MinPoolSize > cfg.Capacity {
+		return nil, ErrInvalidArguments
+	}
+	if len(cfg.Policies) == 0 {
+		cfg.Policies = []Policy{floorPolicy{}}
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = DefaultPoolName
+	}
 
 	pool := &SimpleObjectPool{
-		factory:  cfg.Factory,
-		inuse:    make(map[string]types.NetworkResource),
-		idle:     NewPriorityQueue(),
-		maxIdle:  cfg.MaxIdle,
-		minIdle:  cfg.MinIdle,
-		capacity: cfg.Capacity,
-		notifyCh: make(chan interface{}),
-		tokenCh:  make(chan struct{}, cfg.Capacity),
+		name:        cfg.Name,
+		factory:     cfg.Factory,
+		inuse:       make(map[string]types.NetworkResource),
+		idle:        NewPriorityQueue(),
+		maxIdle:     cfg.MaxIdle,
+		minIdle:     cfg.MinIdle,
+		capacity:    cfg.Capacity,
+		minPoolSize: cfg.MinPoolSize,
+		policies:    cfg.Policies,
+		maxBackoff:  defaultPoolBackoff,
+		notifyCh:    make(chan interface{}, 1),
+		tokenCh:     make(chan struct{}, cfg.Capacity),
+		maxCapacity: cfg.Capacity,
+		journal:     cfg.Journal,
+		done:        make(chan struct{}),
 	}
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
 
+	// read journal before Initializer records restored resources
+	entries := pool.journal.Entries(pool.name)
 	if cfg.Initializer != nil {
 		if err := cfg.Initializer(pool); err != nil {
 			return nil, err
 		}
 	}
+	pool.replay(entries)
 
 	if err := pool.preload(); err != nil {
 		return nil, err
 	}
 
-	logger.Infof("pool initial state, capacity %d, maxIdle: %d, minIdle %d, idle: %s, inuse: %s",
+	logger.Infof("pool initial state, capacity %d, maxIdle: %d, minIdle %d, minPoolSize %d, policies %v, idle: %s, inuse: %s",
 		pool.capacity,
 		pool.maxIdle,
 		pool.minIdle,
+		pool.minPoolSize,
+		PolicyNames(pool.policies),
 		queueKeys(pool.idle),
 		mapKeys(pool.inuse))
 
+	metrics.RegisterPool(pool.name, pool.stats)
 	go pool.startCheckIdleTicker()
 
 	return pool, nil
 }
 
 func (p *SimpleObjectPool) startCheckIdleTicker() {
+	defer close(p.done)
 	p.checkIdle()
 	ticker := time.NewTicker(CheckIdleInterval)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-p.ctx.Done():
+			return
 		case <-ticker.C:
 			p.checkIdle()
 			p.checkInsufficient()
@@ -169,55 +284,294 @@ func queueKeys(q *PriorityQueue) string {
 	return strings.Join(keys, ", ")
 }
 
+func (p *SimpleObjectPool) createResource(ctx context.Context, ip string) (res types.NetworkResource, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveFactory(p.name, "create", start, err) }()
+	if cf, ok := p.factory.(ContextFactory); ok {
+		return cf.CreateContext(ctx, ip)
+	}
+	return p.factory.Create(ip)
+}
+
+func (p *SimpleObjectPool) disposeResource(ctx context.Context, res types.NetworkResource) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveFactory(p.name, "dispose", start, err) }()
+	if cf, ok := p.factory.(ContextFactory); ok {
+		return cf.DisposeContext(ctx, res)
+	}
+	return p.factory.Dispose(res)
+}
+
+// createResources create count resources in batch if factory supports, resources created
+// are returned with error if some of them failed
+func (p *SimpleObjectPool) createResources(ctx context.Context, count int) ([]types.NetworkResource, error) {
+	bf, ok := p.factory.(BatchFactory)
+	if !ok {
+		var ret []types.NetworkResource
+		for i := 0; i < count; i++ {
+			res, err := p.createResource(ctx, "")
+			if err != nil {
+				return ret, err
+			}
+			ret = append(ret, res)
+		}
+		return ret, nil
+	}
+
+	start := time.Now()
+	ret, err := bf.CreateBatch(ctx, count)
+	metrics.ObserveFactory(p.name, "create_batch", start, err)
+	return ret, err
+}
+
+// disposeResources dispose resources in batch if factory supports, resources failed to dispose are returned
+func (p *SimpleObjectPool) disposeResources(ctx context.Context, res []types.NetworkResource) ([]types.NetworkResource, error) {
+	bf, ok := p.factory.(BatchFactory)
+	if !ok {
+		var failed []types.NetworkResource
+		var lastErr error
+		for _, r := range res {
+			if err := p.disposeResource(ctx, r); err != nil {
+				failed = append(failed, r)
+				lastErr = err
+			}
+		}
+		return failed, lastErr
+	}
+
+	start := time.Now()
+	failed, err := bf.DisposeBatch(ctx, res)
+	metrics.ObserveFactory(p.name, "dispose_batch", start, err)
+	return failed, err
+}
+
+// putTokens return n tokens after resources are disposed or failed to create, tokens owed since
+// capacity shrunk are dropped
+func (p *SimpleObjectPool) putTokens(n int) {
+	p.lock.Lock()
+	drop := n
+	if drop > p.owed {
+		drop = p.owed
+	}
+	p.owed -= drop
+	p.lock.Unlock()
+	for i := 0; i < n-drop; i++ {
+		select {
+		case p.tokenCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SetCapacity change capacity of pool, e.g. to budget granted by cluster quota. capacity is limited to
+// [0, capacity pool created with], idle resources over a shrunk capacity are disposed by checkIdle
+func (p *SimpleObjectPool) SetCapacity(capacity int) {
+	if capacity > p.maxCapacity {
+		capacity = p.maxCapacity
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	p.lock.Lock()
+	delta := capacity - p.capacity
+	if delta == 0 {
+		p.lock.Unlock()
+		return
+	}
+	logger.Infof("capacity of pool %s changed from %d to %d", p.name, p.capacity, capacity)
+	p.capacity = capacity
+	if delta < 0 {
+		// take free tokens, tokens held by resources over capacity are owed
+		shrink, taken := -delta, 0
+		for taken < shrink {
+			select {
+			case <-p.tokenCh:
+				taken++
+				continue
+			default:
+			}
+			break
+		}
+		p.owed += shrink - taken
+		delta = 0
+	} else if p.owed > 0 {
+		paid := delta
+		if paid > p.owed {
+			paid = p.owed
+		}
+		p.owed -= paid
+		delta -= paid
+	}
+	p.lock.Unlock()
+	for i := 0; i < delta; i++ {
+		select {
+		case p.tokenCh <- struct{}{}:
+		default:
+		}
+	}
+	p.notify()
+}
+
+// Usage return sizes of pool
+func (p *SimpleObjectPool) Usage() Usage {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return Usage{
+		InUse:       len(p.inuse),
+		Idle:        p.idle.Size(),
+		IdleTarget:  p.refillTargetLocked(),
+		Capacity:    p.capacity,
+		MaxCapacity: p.maxCapacity,
+	}
+}
+
+// stats return sizes of pool for metrics
+func (p *SimpleObjectPool) stats() metrics.PoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return metrics.PoolStats{
+		Idle:     p.idle.Size(),
+		InUse:    len(p.inuse),
+		Capacity: p.capacity,
+		Tokens:   len(p.tokenCh),
+	}
+}
+
 func (p *SimpleObjectPool) dispose(res types.NetworkResource) {
 	logger.Infof("try dispose res %+v", res)
-	if err := p.factory.Dispose(res); err != nil {
+	if err := p.disposeResource(p.ctx, res); err != nil {
 		//put it back on dispose fail
 		logger.Warnf("failed dispose %s: %v, put it back to idle", res.GetResourceId(), err)
 	} else {
-		p.tokenCh <- struct{}{}
+		p.putTokens(1)
 	}
 }
 
-func (p *SimpleObjectPool) tooManyIdle() bool {
-	logger.Infof("Check Idle, idle size:%d, maxIdel:%d, pool size: %d, capacity:%d", p.idle.Size(), p.maxIdle, p.size(), p.capacity)
-	return p.idle.Size() > p.maxIdle || (p.idle.Size() > 0 && p.size() > p.capacity)
+// stateLocked return snapshot of pool for policies
+func (p *SimpleObjectPool) stateLocked(now time.Time) *PoolState {
+	state := &PoolState{
+		Now:         now,
+		InUse:       len(p.inuse),
+		Capacity:    p.capacity,
+		MinIdle:     p.minIdle,
+		MaxIdle:     p.maxIdle,
+		MinPoolSize: p.minPoolSize,
+		Pending:     p.pending,
+		LastCreated: p.lastCreated,
+	}
+	for _, item := range p.idle.List() {
+		state.Idle = append(state.Idle, IdleResource{
+			ID:            item.res.GetResourceId(),
+			IdleSince:     item.idleSince,
+			ReservedUntil: item.reverse,
+		})
+	}
+	sortIdle(state.Idle)
+	state.IdleTarget = p.idleTargetLocked(now)
+	return state
 }
 
-//found resources that can be disposed, put them into dispose channel
-//must in lock
+// reclaimLocked return idle resources to dispose chosen by policies
+func (p *SimpleObjectPool) reclaimLocked() []IdleResource {
+	state := p.stateLocked(time.Now())
+	candidates := defaultCandidates(state)
+	for _, policy := range p.policies {
+		candidates = policy.Reclaim(state, candidates)
+	}
+	logger.Infof("Check Idle, idle size:%d, maxIdel:%d, pending: %d, pool size: %d, capacity:%d, reclaim: %d",
+		len(state.Idle), p.maxIdle, p.pending, p.sizeLocked(), p.capacity, len(candidates))
+	return candidates
+}
+
+// checkIdle dispose idle resources chosen by policies in batch, resources failed to dispose are put back to idle
 func (p *SimpleObjectPool) checkIdle() {
-	for p.tooManyIdle() {
-		p.lock.Lock()
-		item := p.idle.Peek()
-		if item == nil {
-			//impossible
-			break
+	if p.ctx.Err() != nil {
+		return
+	}
+	var disposing []types.NetworkResource
+	p.lock.Lock()
+	for _, c := range p.reclaimLocked() {
+		if item := p.idle.Rob(c.ID); item != nil {
+			disposing = append(disposing, item.res)
 		}
-		if item.reverse.After(time.Now()) {
-			logger.Infof("NONONONO, will never after now")
-			break
+	}
+	p.lock.Unlock()
+	if len(disposing) == 0 {
+		return
+	}
+
+	logger.Infof("try dispose %d resources", len(disposing))
+	p.putTokens(p.disposeAll(disposing))
+}
+
+// disposeAll dispose resources in batch with disposing recorded in journal, resources failed to dispose
+// are put back to idle. count of resources disposed is returned
+func (p *SimpleObjectPool) disposeAll(res []types.NetworkResource) int {
+	for _, r := range res {
+		p.journal.Record(p.name, r.GetResourceId(), JournalDisposing, time.Time{})
+	}
+	failed, err := p.disposeResources(p.ctx, res)
+	if err != nil {
+		logger.Warnf("error dispose %d of %d resources: %+v", len(failed), len(res), err)
+	}
+	kept := make(map[string]bool)
+	for _, r := range failed {
+		kept[r.GetResourceId()] = true
+		p.AddIdle(r)
+	}
+	for _, r := range res {
+		if !kept[r.GetResourceId()] {
+			p.journal.Forget(p.name, r.GetResourceId())
 		}
-		item = p.idle.Pop()
-		p.lock.Unlock()
-		res := item.res
-		logger.Infof("try dispose res %+v", res)
-		err := p.factory.Dispose(res)
-		if err == nil {
-			p.tokenCh <- struct{}{}
-		} else {
-			logger.Warnf("error dispose res: %+v", err)
-			p.AddIdle(res)
+	}
+	return len(res) - len(failed)
+}
+
+// Drain dispose idle resources at once, e.g. before node maintenance. resources reserved for pods are
+// kept unless force. pool refills up to its idle target afterwards, count of resources disposed is returned
+func (p *SimpleObjectPool) Drain(force bool) int {
+	if p.ctx.Err() != nil {
+		return 0
+	}
+	now := time.Now()
+	var disposing []types.NetworkResource
+	p.lock.Lock()
+	// list aliases slots of queue which are moved by rob
+	var ids []string
+	for _, item := range p.idle.List() {
+		if force || !item.reverse.After(now) {
+			ids = append(ids, item.res.GetResourceId())
+		}
+	}
+	for _, id := range ids {
+		if item := p.idle.Rob(id); item != nil {
+			disposing = append(disposing, item.res)
 		}
 	}
+	p.lock.Unlock()
+	if len(disposing) == 0 {
+		return 0
+	}
+
+	logger.Infof("drain %d resources of pool %s", len(disposing), p.name)
+	disposed := p.disposeAll(disposing)
+	p.putTokens(disposed)
+	return disposed
 }
 
 func (p *SimpleObjectPool) checkInsufficient() {
+	if p.ctx.Err() != nil {
+		return
+	}
 	addition := p.needAddition()
 	logger.Infof("Insufficient check...... addition is %d, idle size is %d, min idle is %d, in use is %d", addition, p.idle.Size(), p.minIdle, len(p.inuse))
 	if addition <= 0 {
 		return
 	}
+	if time.Now().Before(p.refillAfter) {
+		logger.Infof("refill of pool %s is backed off until %s", p.name, p.refillAfter.Format(time.RFC3339))
+		return
+	}
 	var tokenAcquired int
 	for i := 0; i < addition; i++ {
 		// pending resources
@@ -233,55 +587,79 @@ func (p *SimpleObjectPool) checkInsufficient() {
 		return
 	}
 
-	var err error
-	leftCount := 0
-	for i := 0; i < tokenAcquired; i++ {
-		logger.Infof("@@@@@@@@@@@@@@@@@@@@  Insufficient to create port")
-		res, err := p.factory.Create("")
-		if err != nil {
-			logger.Errorf("error add idle network resources: %v", err)
-			// release token
-			p.tokenCh <- struct{}{}
-			leftCount++
-		} else {
-			logger.Infof("add resource %s to pool idle", res.GetResourceId())
-			p.AddIdle(res)
-		}
+	created, err := p.createResources(p.ctx, tokenAcquired)
+	if len(created) > 0 {
+		p.lock.Lock()
+		p.lastCreated = time.Now()
+		p.lock.Unlock()
+	}
+	for _, res := range created {
+		logger.Infof("add resource %s to pool idle", res.GetResourceId())
+		p.AddIdle(res)
 	}
 
-	if leftCount > 0 {
-		logger.Infof("token acquired left: %d, err: %v", leftCount, err)
-		p.notify()
+	if leftCount := tokenAcquired - len(created); leftCount > 0 {
+		logger.Errorf("error add idle network resources, %d of %d not created: %v", leftCount, tokenAcquired, err)
+		// release tokens
+		p.putTokens(leftCount)
+		p.backoffRefill()
+		return
 	}
+	p.backoff = 0
+}
+
+// backoffRefill delay next refill after factory failed, backoff doubles up to maxBackoff so that
+// a throttled or exhausted neutron is not flooded by refill
+func (p *SimpleObjectPool) backoffRefill() {
+	p.backoff *= 2
+	if p.backoff < minPoolBackoff {
+		p.backoff = minPoolBackoff
+	}
+	if p.backoff > p.maxBackoff {
+		p.backoff = p.maxBackoff
+	}
+	p.refillAfter = time.Now().Add(p.backoff)
+	logger.Warnf("refill pool %s again after %s", p.name, p.backoff)
+	time.AfterFunc(p.backoff, p.notify)
 }
 
 func (p *SimpleObjectPool) preload() error {
-	for {
-		// init resource sequential to avoid huge creating request on startup
-		if p.idle.Size() >= p.minIdle {
-			logger.Infof("@@@@@@@@@@@@ pool idle size > min Idle, %d > %d", p.idle.Size(), p.minIdle)
-			break
+	p.lock.Lock()
+	count := p.refillTargetLocked() - p.idle.Size()
+	p.lock.Unlock()
+	if left := p.capacity - p.size(); count > left {
+		count = left
+	}
+	if count > 0 {
+		logger.Infof("create %d resources in preload", count)
+		created, err := p.createResources(p.ctx, count)
+		if len(created) > 0 {
+			p.lock.Lock()
+			p.lastCreated = time.Now()
+			p.lock.Unlock()
 		}
-
-		if p.size() >= p.capacity {
-			logger.Infof("@@@@@@@@@@@@ pool  size > capacity, %d > %d", p.size(), p.capacity)
-			break
+		for _, res := range created {
+			logger.Debugf("add %s into idle in preload", res.GetResourceId())
+			p.AddIdle(res)
 		}
-
-		logger.Infof("@@@@@@@@@@@@ create port in preload")
-		res, err := p.factory.Create("")
 		if err != nil {
-			return err
+			if len(created) == 0 {
+				return err
+			}
+			// pool is refilled by checkInsufficient later
+			logger.Warnf("only %d of %d resources created in preload: %v", len(created), count, err)
 		}
-		logger.Infof("@@@@@@@@@@@@ add %s into idle", res.GetResourceId())
-		p.AddIdle(res)
 	}
 
 	tokenCount := p.capacity - p.size()
-	logger.Infof("@@@@@@@@@@@@ token count is %d", tokenCount)
+	logger.Debugf("token count is %d after preload", tokenCount)
 	for i := 0; i < tokenCount; i++ {
 		p.tokenCh <- struct{}{}
 	}
+	if tokenCount < 0 {
+		// restored more resources than capacity, tokens of them are dropped when they are disposed
+		p.owed = -tokenCount
+	}
 
 	return nil
 }
@@ -290,10 +668,46 @@ func (p *SimpleObjectPool) sizeLocked() int {
 	return p.idle.Size() + len(p.inuse)
 }
 
+// idleTargetLocked return idle size expected, which is the largest of minIdle, count of pending pods
+// and allocations in last window limited by maxIdle
+func (p *SimpleObjectPool) idleTargetLocked(now time.Time) int {
+	target := p.minIdle
+	if recent := p.recentAllocationsLocked(now); recent > target {
+		target = recent
+		if target > p.maxIdle {
+			target = p.maxIdle
+		}
+	}
+	if p.pending > target {
+		target = p.pending
+	}
+	return target
+}
+
+// refillTargetLocked return idle size pool refills to, idle target adjusted by policies
+func (p *SimpleObjectPool) refillTargetLocked() int {
+	state := p.stateLocked(time.Now())
+	target := state.IdleTarget
+	for _, policy := range p.policies {
+		target = policy.Target(state, target)
+	}
+	return target
+}
+
+// recentAllocationsLocked return count of acquires in last allocation window, older records are dropped
+func (p *SimpleObjectPool) recentAllocationsLocked(now time.Time) int {
+	i := 0
+	for i < len(p.allocated) && now.Sub(p.allocated[i]) > allocationWindow {
+		i++
+	}
+	p.allocated = p.allocated[i:]
+	return len(p.allocated)
+}
+
 func (p *SimpleObjectPool) needAddition() int {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	addition := p.minIdle - p.idle.Size()
+	addition := p.refillTargetLocked() - p.idle.Size()
 	if addition > (p.capacity - p.sizeLocked()) {
 		return p.capacity - p.sizeLocked()
 	}
@@ -315,13 +729,26 @@ func (p *SimpleObjectPool) getOneLocked(resId string) *poolItem {
 }
 
 func (p *SimpleObjectPool) Acquire(ctx context.Context, resId string) (types.NetworkResource, error) {
+	if ctx.Err() != nil {
+		// caller is gone, e.g. cni call timed out
+		logger.Infof("acquire (expect %s): return err %v", resId, ErrContextDone)
+		return nil, ErrContextDone
+	}
 	p.lock.Lock()
 	//defer p.lock.Unlock()
+	p.allocated = append(p.allocated, time.Now())
+	if p.pending > 0 {
+		// pending pod is acquiring, count is corrected by next Warm
+		p.pending--
+	}
 	if p.idle.Size() > 0 {
 		res := p.getOneLocked(resId).res
 		p.inuse[res.GetResourceId()] = res
 		p.lock.Unlock()
+		p.journal.Record(p.name, res.GetResourceId(), JournalInUse, time.Time{})
 		logger.Infof("acquire (expect %s): return idle %s", resId, res.GetResourceId())
+		// refill idle taken by pod
+		p.notify()
 		return res, nil
 	}
 	size := p.size()
@@ -335,11 +762,23 @@ func (p *SimpleObjectPool) Acquire(ctx context.Context, resId string) (types.Net
 
 	select {
 	case <-p.tokenCh:
-		//should we pass ctx into factory.Create?
-		res, err := p.factory.Create("")
+		res, err := p.createResource(ctx, "")
 		if err != nil {
-			p.tokenCh <- struct{}{}
-			return nil, fmt.Errorf("error create from factory: %v", err)
+			p.putTokens(1)
+			if ctx.Err() != nil {
+				logger.Infof("acquire (expect %s): create aborted: %v", resId, err)
+				return nil, ErrContextDone
+			}
+			return nil, fmt.Errorf("error create from factory: %w", err)
+		}
+		p.lock.Lock()
+		p.lastCreated = time.Now()
+		p.lock.Unlock()
+		if ctx.Err() != nil {
+			// nobody is waiting for the resource, keep it for next acquire instead of leaking it in inuse
+			logger.Infof("acquire (expect %s): context done, put newly %s to idle", resId, res.GetResourceId())
+			p.AddIdle(res)
+			return nil, ErrContextDone
 		}
 		logger.Infof("acquire (expect %s): return newly %s", resId, res.GetResourceId())
 		p.AddInuse(res)
@@ -350,6 +789,34 @@ func (p *SimpleObjectPool) Acquire(ctx context.Context, resId string) (types.Net
 	}
 }
 
+// Close stop refill and dispose of pool, requests of a running refill or dispose are canceled and it is
+// waited until ctx is done. resources created by canceled requests are recovered from journal on next start,
+// resources in pool are kept
+func (p *SimpleObjectPool) Close(ctx context.Context) error {
+	p.cancel()
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return fmt.Errorf("pool %s is still refilling or disposing: %w", p.name, ctx.Err())
+	}
+	s := p.stats()
+	logger.Infof("pool %s closed, idle %d, inuse %d, capacity %d", p.name, s.Idle, s.InUse, s.Capacity)
+	return nil
+}
+
+// Warm set count of pods scheduled to node but not started, idle resources are created for them
+// before they acquire
+func (p *SimpleObjectPool) Warm(pending int) {
+	p.lock.Lock()
+	changed := p.pending != pending
+	p.pending = pending
+	p.lock.Unlock()
+	if changed {
+		logger.Infof("pool %s has %d pending pods", p.name, pending)
+		p.notify()
+	}
+}
+
 func (p *SimpleObjectPool) AcquireAny(ctx context.Context) (types.NetworkResource, error) {
 	return p.Acquire(ctx, "")
 }
@@ -391,7 +858,8 @@ func (p *SimpleObjectPool) ReleaseWithReverse(resId string, reverse time.Duratio
 	if reverse > 0 {
 		reverseTo = reverseTo.Add(reverse)
 	}
-	p.idle.Push(&poolItem{res: res, reverse: reverseTo})
+	p.idle.Push(&poolItem{res: res, reverse: reverseTo, idleSince: time.Now()})
+	p.journal.Record(p.name, resId, JournalIdle, reverseTo)
 	p.notify()
 	return nil
 }
@@ -399,22 +867,83 @@ func (p *SimpleObjectPool) Release(resId string) error {
 	return p.ReleaseWithReverse(resId, time.Duration(0))
 }
 
+// Remove drop resource from pool without disposing it, e.g. port is taken over by another node
+func (p *SimpleObjectPool) Remove(resId string) (types.NetworkResource, error) {
+	p.lock.Lock()
+	res, ok := p.inuse[resId]
+	if ok {
+		delete(p.inuse, resId)
+	} else if item := p.idle.Rob(resId); item != nil {
+		res = item.res
+	} else {
+		p.lock.Unlock()
+		return nil, ErrNotFound
+	}
+	p.lock.Unlock()
+	p.journal.Forget(p.name, resId)
+
+	logger.Infof("remove %s from pool", resId)
+	// resources added by AddIdle directly hold no token
+	p.putTokens(1)
+	p.notify()
+	return res, nil
+}
+
 func (p *SimpleObjectPool) AddIdle(resource types.NetworkResource) {
 	p.lock.Lock()
-	defer p.lock.Unlock()
-	p.idle.Push(&poolItem{res: resource, reverse: time.Now()})
+	now := time.Now()
+	p.idle.Push(&poolItem{res: resource, reverse: now, idleSince: now})
+	p.lock.Unlock()
+	p.journal.Record(p.name, resource.GetResourceId(), JournalIdle, time.Time{})
+}
+
+// Adopt add resource created out of pool, e.g. port with static ip, into inuse. it waits for a token
+// until ctx is done so that pool does not grow over capacity
+func (p *SimpleObjectPool) Adopt(ctx context.Context, res types.NetworkResource) error {
+	select {
+	case <-p.tokenCh:
+	default:
+		p.lock.Lock()
+		size := p.sizeLocked()
+		p.lock.Unlock()
+		if size >= p.capacity {
+			logger.Infof("adopt %s, size %d, capacity %d: return err %v", res.GetResourceId(), size, p.capacity, ErrNoAvailableResource)
+			return ErrNoAvailableResource
+		}
+		select {
+		case <-p.tokenCh:
+		case <-ctx.Done():
+			return ErrContextDone
+		}
+	}
+	p.lock.Lock()
+	p.allocated = append(p.allocated, time.Now())
+	p.lock.Unlock()
+	logger.Infof("adopt %s into inuse", res.GetResourceId())
+	p.AddInuse(res)
+	return nil
 }
 
 func (p *SimpleObjectPool) AddInuse(res types.NetworkResource) {
 	p.lock.Lock()
-	defer p.lock.Unlock()
 	p.inuse[res.GetResourceId()] = res
+	p.lock.Unlock()
+	p.journal.Record(p.name, res.GetResourceId(), JournalInUse, time.Time{})
 }
 
 func (p *SimpleObjectPool) GetInUse() map[string]types.NetworkResource {
-	return p.inuse
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	inuse := make(map[string]types.NetworkResource, len(p.inuse))
+	for k, v := range p.inuse {
+		inuse[k] = v
+	}
+	return inuse
 }
 
 func (p *SimpleObjectPool) GetIdle() []*poolItem {
-	return p.idle.List()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	idle := make([]*poolItem, 0, p.idle.Size())
+	return append(idle, p.idle.List()...)
 }
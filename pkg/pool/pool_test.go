@@ -0,0 +1,129 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/rubble/pkg/utils"
+)
+
+type fakeResource struct {
+	id string
+}
+
+func (r *fakeResource) GetResourceId() string { return r.id }
+func (r *fakeResource) GetType() string       { return "fake" }
+func (r *fakeResource) GetIPAddress() string  { return "" }
+
+// fakeFactory create resources with increasing ids, create fails while err is set
+type fakeFactory struct {
+	lock     sync.Mutex
+	next     int
+	created  int
+	disposed int
+	err      error
+}
+
+func (f *fakeFactory) Create(ip string) (types.NetworkResource, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.next++
+	f.created++
+	return &fakeResource{id: fmt.Sprintf("res-%d", f.next)}, nil
+}
+
+func (f *fakeFactory) Dispose(res types.NetworkResource) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.disposed++
+	return nil
+}
+
+func (f *fakeFactory) setErr(err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.err = err
+}
+
+func newTestPool(t *testing.T, cfg PoolConfig) (*SimpleObjectPool, *fakeFactory) {
+	t.Helper()
+	factory := &fakeFactory{}
+	if cfg.Factory == nil {
+		cfg.Factory = factory
+	}
+	p, err := NewSimpleObjectPool(cfg)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Close(context.Background()) })
+	return p.(*SimpleObjectPool), factory
+}
+
+func TestAdopt(t *testing.T) {
+	p, _ := newTestPool(t, PoolConfig{Name: "adopt", MaxIdle: 1, MaxPoolSize: 2, Capacity: 2})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := p.Adopt(ctx, &fakeResource{id: fmt.Sprintf("static-%d", i)}); err != nil {
+			t.Fatalf("adopt %d: %v", i, err)
+		}
+	}
+	if err := p.Adopt(ctx, &fakeResource{id: "static-2"}); !errors.Is(err, ErrNoAvailableResource) {
+		t.Fatalf("adopt over capacity: err = %v, want %v", err, ErrNoAvailableResource)
+	}
+	if _, err := p.Acquire(ctx, ""); !errors.Is(err, ErrNoAvailableResource) {
+		t.Fatalf("acquire over capacity: err = %v, want %v", err, ErrNoAvailableResource)
+	}
+	if u := p.Usage(); u.InUse != 2 {
+		t.Errorf("in use = %d, want 2", u.InUse)
+	}
+
+	// token of adopted resource is returned when it leaves pool
+	if _, err := p.Remove("static-0"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := p.Adopt(ctx, &fakeResource{id: "static-3"}); err != nil {
+		t.Fatalf("adopt after remove: %v", err)
+	}
+}
+
+// blockingFactory create and dispose in batch until ctx is done
+type blockingFactory struct {
+	fakeFactory
+	started chan struct{}
+}
+
+func (f *blockingFactory) CreateBatch(ctx context.Context, count int) ([]types.NetworkResource, error) {
+	f.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *blockingFactory) DisposeBatch(ctx context.Context, res []types.NetworkResource) ([]types.NetworkResource, error) {
+	f.started <- struct{}{}
+	<-ctx.Done()
+	return res, ctx.Err()
+}
+
+func TestCloseCancelRefill(t *testing.T) {
+	factory := &blockingFactory{started: make(chan struct{}, 1)}
+	p, _ := newTestPool(t, PoolConfig{Name: "close", Factory: factory, MaxIdle: 2, MaxPoolSize: 2})
+	p.Warm(1)
+	select {
+	case <-factory.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool is not refilled")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
@@ -175,3 +175,233 @@ var RubbleBackend_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "rpc.proto",
 }
+
+// RubbleAdminClient is the client API for RubbleAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RubbleAdminClient interface {
+	ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsReply, error)
+	ReleasePort(ctx context.Context, in *ReleasePortRequest, opts ...grpc.CallOption) (*ReleasePortReply, error)
+	DrainPool(ctx context.Context, in *DrainPoolRequest, opts ...grpc.CallOption) (*DrainPoolReply, error)
+	Resync(ctx context.Context, in *ResyncRequest, opts ...grpc.CallOption) (*ResyncReply, error)
+	DumpPodPorts(ctx context.Context, in *DumpPodPortsRequest, opts ...grpc.CallOption) (*DumpPodPortsReply, error)
+}
+
+type rubbleAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRubbleAdminClient(cc grpc.ClientConnInterface) RubbleAdminClient {
+	return &rubbleAdminClient{cc}
+}
+
+func (c *rubbleAdminClient) ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsReply, error) {
+	out := new(ListPortsReply)
+	err := c.cc.Invoke(ctx, "/rpc.RubbleAdmin/ListPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rubbleAdminClient) ReleasePort(ctx context.Context, in *ReleasePortRequest, opts ...grpc.CallOption) (*ReleasePortReply, error) {
+	out := new(ReleasePortReply)
+	err := c.cc.Invoke(ctx, "/rpc.RubbleAdmin/ReleasePort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rubbleAdminClient) DrainPool(ctx context.Context, in *DrainPoolRequest, opts ...grpc.CallOption) (*DrainPoolReply, error) {
+	out := new(DrainPoolReply)
+	err := c.cc.Invoke(ctx, "/rpc.RubbleAdmin/DrainPool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rubbleAdminClient) Resync(ctx context.Context, in *ResyncRequest, opts ...grpc.CallOption) (*ResyncReply, error) {
+	out := new(ResyncReply)
+	err := c.cc.Invoke(ctx, "/rpc.RubbleAdmin/Resync", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rubbleAdminClient) DumpPodPorts(ctx context.Context, in *DumpPodPortsRequest, opts ...grpc.CallOption) (*DumpPodPortsReply, error) {
+	out := new(DumpPodPortsReply)
+	err := c.cc.Invoke(ctx, "/rpc.RubbleAdmin/DumpPodPorts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RubbleAdminServer is the server API for RubbleAdmin service.
+// All implementations must embed UnimplementedRubbleAdminServer
+// for forward compatibility
+type RubbleAdminServer interface {
+	ListPorts(context.Context, *ListPortsRequest) (*ListPortsReply, error)
+	ReleasePort(context.Context, *ReleasePortRequest) (*ReleasePortReply, error)
+	DrainPool(context.Context, *DrainPoolRequest) (*DrainPoolReply, error)
+	Resync(context.Context, *ResyncRequest) (*ResyncReply, error)
+	DumpPodPorts(context.Context, *DumpPodPortsRequest) (*DumpPodPortsReply, error)
+	mustEmbedUnimplementedRubbleAdminServer()
+}
+
+// UnimplementedRubbleAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedRubbleAdminServer struct {
+}
+
+func (UnimplementedRubbleAdminServer) ListPorts(context.Context, *ListPortsRequest) (*ListPortsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPorts not implemented")
+}
+func (UnimplementedRubbleAdminServer) ReleasePort(context.Context, *ReleasePortRequest) (*ReleasePortReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleasePort not implemented")
+}
+func (UnimplementedRubbleAdminServer) DrainPool(context.Context, *DrainPoolRequest) (*DrainPoolReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainPool not implemented")
+}
+func (UnimplementedRubbleAdminServer) Resync(context.Context, *ResyncRequest) (*ResyncReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resync not implemented")
+}
+func (UnimplementedRubbleAdminServer) DumpPodPorts(context.Context, *DumpPodPortsRequest) (*DumpPodPortsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpPodPorts not implemented")
+}
+func (UnimplementedRubbleAdminServer) mustEmbedUnimplementedRubbleAdminServer() {}
+
+// UnsafeRubbleAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RubbleAdminServer will
+// result in compilation errors.
+type UnsafeRubbleAdminServer interface {
+	mustEmbedUnimplementedRubbleAdminServer()
+}
+
+func RegisterRubbleAdminServer(s grpc.ServiceRegistrar, srv RubbleAdminServer) {
+	s.RegisterService(&RubbleAdmin_ServiceDesc, srv)
+}
+
+func _RubbleAdmin_ListPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPortsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RubbleAdminServer).ListPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.RubbleAdmin/ListPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RubbleAdminServer).ListPorts(ctx, req.(*ListPortsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RubbleAdmin_ReleasePort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleasePortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RubbleAdminServer).ReleasePort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.RubbleAdmin/ReleasePort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RubbleAdminServer).ReleasePort(ctx, req.(*ReleasePortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RubbleAdmin_DrainPool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainPoolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RubbleAdminServer).DrainPool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.RubbleAdmin/DrainPool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RubbleAdminServer).DrainPool(ctx, req.(*DrainPoolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RubbleAdmin_Resync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RubbleAdminServer).Resync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.RubbleAdmin/Resync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RubbleAdminServer).Resync(ctx, req.(*ResyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RubbleAdmin_DumpPodPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpPodPortsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RubbleAdminServer).DumpPodPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.RubbleAdmin/DumpPodPorts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RubbleAdminServer).DumpPodPorts(ctx, req.(*DumpPodPortsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RubbleAdmin_ServiceDesc is the grpc.ServiceDesc for RubbleAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RubbleAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.RubbleAdmin",
+	HandlerType: (*RubbleAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPorts",
+			Handler:    _RubbleAdmin_ListPorts_Handler,
+		},
+		{
+			MethodName: "ReleasePort",
+			Handler:    _RubbleAdmin_ReleasePort_Handler,
+		},
+		{
+			MethodName: "DrainPool",
+			Handler:    _RubbleAdmin_DrainPool_Handler,
+		},
+		{
+			MethodName: "Resync",
+			Handler:    _RubbleAdmin_Resync_Handler,
+		},
+		{
+			MethodName: "DumpPodPorts",
+			Handler:    _RubbleAdmin_DumpPodPorts_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rpc.proto",
+}
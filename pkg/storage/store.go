@@ -104,6 +104,21 @@ func NewDiskStorage(name string, path string, serializer Serializer, deserialize
 	return diskstorage, nil
 }
 
+// Bucket return storage of another bucket in the same db, bolt db can not be opened twice
+func (d *DiskStorage) Bucket(name string, serializer Serializer, deserializer Deserializer) (Storage, error) {
+	bucket := &DiskStorage{
+		db:           d.db,
+		name:         name,
+		memory:       NewMemoryStorage(),
+		serializer:   serializer,
+		deserializer: deserializer,
+	}
+	if err := bucket.load(); err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
 func (d *DiskStorage) Put(key string, value interface{}) error {
 	data, err := d.serializer(value)
 	if err != nil {
@@ -146,6 +161,14 @@ func (d *DiskStorage) load() error {
 	return err
 }
 
+// Close sync and close db, buckets sharing the db can not be used after
+func (d *DiskStorage) Close() error {
+	if err := d.db.Sync(); err != nil {
+		return err
+	}
+	return d.db.Close()
+}
+
 func (d *DiskStorage) Get(key string) (interface{}, error) {
 	return d.memory.Get(key)
 }
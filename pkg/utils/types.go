@@ -7,10 +7,20 @@ import (
 
 type NetConf struct {
 	types.NetConf
-	Master       string `json:"master"`
-	Mode         string `json:"mode"`
-	MTU          int    `json:"mtu"`
-	DefaultRoute bool   `json:"default_route"`
+	Master string `json:"master"`
+	Mode   string `json:"mode"`
+	// MTU of pod interfaces, mtu of neutron network less MTUOverhead is used if it is 0
+	MTU          int  `json:"mtu"`
+	MTUOverhead  int  `json:"mtu_overhead"`
+	DefaultRoute bool `json:"default_route"`
+	// VethName is name of veth in container, HostVethPrefix is name prefix of veth on host,
+	// veth on host is named randomly if it is empty
+	VethName       string `json:"veth_name"`
+	HostVethPrefix string `json:"host_veth_prefix"`
+	// ExtraRoutes are destinations routed via gateway of pod subnet besides routes from daemon
+	ExtraRoutes []string `json:"extra_routes"`
+	// Datapath is one of ipvlan, macvlan, eni and trunk, it must match datapath of daemon
+	Datapath string `json:"datapath"`
 }
 
 type K8sArgs struct {
@@ -32,20 +42,79 @@ type NodeInfo struct {
 	Hostname         string `json:"hostname"`
 	ProjectID        string `json:"project_id"`
 	Name             string `json:"name"`
-	AvailabilityZone string `json:availability_zone`
+	AvailabilityZone string `json:"availability_zone"`
+}
+
+// RateLimitConfig limit requests of daemon to neutron, zero values use defaults
+type RateLimitConfig struct {
+	QPS   float64 `yaml:"qps" json:"qps"`
+	Burst int     `yaml:"burst" json:"burst"`
+	// MaxConcurrency is max requests in flight
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
+	// MaxBackoff is max seconds requests are paused after neutron returns 429 or 503
+	MaxBackoff int `yaml:"max_backoff" json:"max_backoff"`
+}
+
+// PoolPolicyConfig select policies reclaiming idle ports of pools
+type PoolPolicyConfig struct {
+	// Policies are applied in order, each is one of floor, hysteresis, ttl and schedule. floor is always applied last
+	Policies []string `yaml:"policies" json:"policies"`
+	// IdleTTL is seconds a port stays idle before ttl policy reclaims it
+	IdleTTL int `yaml:"idle_ttl" json:"idle_ttl"`
+	// HysteresisMargin is idle ports over max idle size tolerated by hysteresis policy
+	HysteresisMargin int `yaml:"hysteresis_margin" json:"hysteresis_margin"`
+	// Cooldown is seconds after pool created ports in which hysteresis policy reclaims nothing
+	Cooldown int `yaml:"cooldown" json:"cooldown"`
+	// OffHours are daily windows in which schedule policy scales idle ports down
+	OffHours []OffHoursWindow `yaml:"off_hours" json:"off_hours"`
+}
+
+// OffHoursWindow is a daily window in local time, e.g. 22:00-06:00
+type OffHoursWindow struct {
+	Start   string `yaml:"start" json:"start"`
+	End     string `yaml:"end" json:"end"`
+	MaxIdle int    `yaml:"max_idle" json:"max_idle"`
 }
 
 type DaemonConfigure struct {
 	ServiceCIDR string `yaml:"service_cidr" json:"service_cidr"`
 	NetID       string `yaml:"net_id" json:"net_id"`
 	SubnetID    string `yaml:"subnet_id" json:"subnet_id"`
-	MaxPoolSize int    `yaml:"max_pool_size" json:"max_pool_size"`
-	MinPoolSize int    `yaml:"min_pool_size" json:"min_pool_size"`
-	MaxIdleSize int    `yaml:"max_idle_size" json:"max_idle_size"`
-	MinIdleSize int    `yaml:"min_idle_size" json:"min_idle_size"`
-	Period      int    `yaml:"period" json:"period"`
-	NodeName    string `yaml:"node_name" json:"node_name"`
-	Node        *NodeInfo
+	// Subnets are extra subnets in the network, ports are created from them when SubnetID is exhausted
+	Subnets []string `yaml:"subnets" json:"subnets"`
+	// SubnetPolicy is one of ordered, least-used and zone, default is ordered
+	SubnetPolicy string `yaml:"subnet_policy" json:"subnet_policy"`
+	// ZoneSubnets map availability zone of node to subnets, used with zone policy
+	ZoneSubnets map[string][]string `yaml:"zone_subnets" json:"zone_subnets"`
+	// AnnotationSubnets are names or ids of subnets pods can select by subnet annotation besides subnets
+	// above, each of them has a pool of at most SubnetPoolSize ports
+	AnnotationSubnets []string `yaml:"annotation_subnets" json:"annotation_subnets"`
+	SubnetPoolSize    int      `yaml:"subnet_pool_size" json:"subnet_pool_size"`
+	// IPv6SubnetID enable dual stack pod with an ipv6 address from this subnet
+	IPv6SubnetID string `yaml:"ipv6_subnet_id" json:"ipv6_subnet_id"`
+	// SecurityGroups are names or ids of security groups of pod ports, pods can override them by annotation
+	SecurityGroups []string `yaml:"security_groups" json:"security_groups"`
+	MaxPoolSize    int      `yaml:"max_pool_size" json:"max_pool_size"`
+	MinPoolSize    int      `yaml:"min_pool_size" json:"min_pool_size"`
+	MaxIdleSize    int      `yaml:"max_idle_size" json:"max_idle_size"`
+	MinIdleSize    int      `yaml:"min_idle_size" json:"min_idle_size"`
+	Period         int      `yaml:"period" json:"period"`
+	NodeName       string   `yaml:"node_name" json:"node_name"`
+	// AllowedSecurityGroups are names or ids of security groups pods can select by annotation besides
+	// SecurityGroups
+	AllowedSecurityGroups []string `yaml:"allowed_security_groups" json:"allowed_security_groups"`
+	// RateLimit of requests to neutron, pod allocations are sent before pool refill and dispose
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit"`
+	// PoolPolicy decide how idle ports of pools are reclaimed
+	PoolPolicy PoolPolicyConfig `yaml:"pool_policy" json:"pool_policy"`
+	// ClusterQuota report usage of default pool and limit its capacity to budget granted by controller
+	ClusterQuota bool `yaml:"cluster_quota" json:"cluster_quota"`
+	// Datapath of pods on node, ports are attached to vm as interfaces of pods for eni datapath, added
+	// into trunk of node as subports for trunk datapath
+	Datapath string `yaml:"datapath" json:"datapath"`
+	// TrunkPort is name or id of parent port of trunk for trunk datapath, port of node in NetID if empty
+	TrunkPort string `yaml:"trunk_port" json:"trunk_port"`
+	Node      *NodeInfo
 }
 
 type NetworkResource interface {
@@ -16,16 +16,36 @@ const (
 	DefaultIpVlanMaster = "eth0"
 	DefaultIpVlanRoute  = true
 	DefaultDst          = "0.0.0.0/0"
+	DefaultDstV6        = "::/0"
 
 	DefaultContainerVethName = "veth0"
+	// link local addresses of veth pair, used as next hop of ipv6 routes
+	DefaultHostVethIPv6      = "fe80::1"
+	DefaultContainerVethIPv6 = "fe80::2"
 	DefaultServiceCidr       = "10.222.0.0/16"
 
 	DefaultDeamonConfigPath = "/etc/cni/rubble/rubble.json"
 
 	ResourceTypeMultipleIP = "PortMultipleIp"
 
+	// DatapathIPVlan attach pod by ipvlan on master with a veth pair to host, DatapathMacvlan by macvlan
+	// with mac of neutron port, DatapathENI by moving interface of port hot-plugged into vm into pod,
+	// DatapathTrunk by vlan on master with vlan id of port as subport of trunk of node
+	DatapathIPVlan  = "ipvlan"
+	DatapathMacvlan = "macvlan"
+	DatapathENI     = "eni"
+	DatapathTrunk   = "trunk"
+	// DefaultENIWaitTimeout is time to wait for hot-plugged interface of port to appear on node
+	DefaultENIWaitTimeout = 10 * time.Second
+
+	// ReleaseReasonDelete is reason of ReleaseIP by CNI DEL, ReleaseReasonRollback by a failed CNI ADD
+	ReleaseReasonDelete   = "cni-del"
+	ReleaseReasonRollback = "cni-add-rollback"
+
 	DaemonDBPath = "/var/lib/cni/rubble/daemon.db"
 	ResDBName    = "PodPorts"
+	// PoolJournalName is bucket of pool journal in daemon db
+	PoolJournalName = "PoolJournal"
 
 	charset = "abcdefghijklmnopqrstuvwxyz0123456789"
 
@@ -57,6 +77,21 @@ func GetIpVlanDefaultRoute(conf *NetConf) bool {
 	return conf.DefaultRoute || DefaultIpVlanRoute
 }
 
+func GetVethName(conf *NetConf) string {
+	if len(conf.VethName) > 0 {
+		return conf.VethName
+	}
+	return DefaultContainerVethName
+}
+
+// GetDatapath return datapath of pod interfaces, ipvlan by default
+func GetDatapath(datapath string) string {
+	if len(datapath) > 0 {
+		return datapath
+	}
+	return DatapathIPVlan
+}
+
 func GetServiceCidr(args *K8sArgs) string {
 	if len(args.K8sServiceCidr) > 0 {
 		return args.K8sServiceCidr